@@ -3,8 +3,7 @@ package main
 import (
 	"log"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
+	"mysql-demo/database"
 )
 
 type User struct {
@@ -20,12 +19,12 @@ func (User) TableName() string {
 }
 
 func main() {
-	// 数据库连接信息
-	// mysql 8.0 版本需要添加 allowNativePasswords=true 参数
-	dsn := "root:123457@tcp(127.0.0.1:3306)/test_gorm?charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true"
-
-	// 打开数据库连接
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// 数据库连接信息来自环境变量或 DB_CONFIG 指定的配置文件，参考 config.example.yaml
+	// mysql 8.0 版本需要添加 allowNativePasswords=true 参数（默认 params 里已经带上）
+	// 例：DB_CONFIG=config.example.yaml go run 02_连接到数据库.go
+	// 例：DB_DRIVER=sqlite DB_DSN=test.db go run 02_连接到数据库.go
+	// 打开数据库连接，数据库没启动时会按退避重试几次再失败
+	db, err := database.Open(database.MustLoad())
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
@@ -1,18 +1,22 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"log"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/migrations"
+	"mysql-demo/repository"
+	"mysql-demo/validate"
 )
 
 type User3 struct {
-	gorm.Model // 包含 ID, CreatedAt, UpdatedAt, DeletedAt 字段
-	Name       string
-	Age        int
+	gorm.Model        // 包含 ID, CreatedAt, UpdatedAt, DeletedAt 字段
+	Name       string `validate:"notblank,max=50"`
+	Age        int    `validate:"min=18,max=150"`
 	// Birthday   time.Time
 	Birthday *time.Time
 }
@@ -25,35 +29,50 @@ func (User3) TableName() string {
 }
 
 // 勾子
-func (u *User3) BeforeCreate(tx *gorm.DB) (err error) {
-	if u.Age < 18 {
-		return errors.New("age must be greater than 18")
-	}
-	return nil
-}
+// 以前在这里写死 Age < 18 的检查：
+//
+//	func (u *User3) BeforeCreate(tx *gorm.DB) (err error) {
+//		if u.Age < 18 {
+//			return errors.New("age must be greater than 18")
+//		}
+//		return nil
+//	}
+//
+// 钩子只在 struct 创建时触发，map 创建和更新都会绕过去。现在改成字段上的 validate 标签，
+// 由 validate.Plugin 在创建和更新前统一校验，失败时返回 validate.Errors（每个字段一条）
 
 func main3() {
-	dsn := "root:123457@tcp(172.17.0.2:3306)/test_gorm?charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		// Logger: logger.Default.LogMode(logger.Info),
-	})
+	// 连接配置见 02_连接到数据库.go，打印 SQL 设置 DB_LOG_LEVEL=info 即可
+	db, err := database.Open(database.MustLoad())
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	// 自动迁移（如果表结构已存在，可以跳过，如果不存在则在数据库中生成）
-	db.AutoMigrate(&User3{})
+	// 注册校验插件，User3 上的 validate 标签对 struct、map、批量的创建和更新都生效
+	if err := db.Use(validate.Plugin{}); err != nil {
+		log.Fatalf("failed to register validate plugin: %v", err)
+	}
+
+	// 建表交给版本化迁移（migrations 目录），不再用 db.AutoMigrate(&User3{})
+	// AutoMigrate 只会加列、不会删列改列，也没有记录和回滚；命令行见 go run ./cmd/migrate status
+	if err := migrations.Up(db); err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
 
-	// createSingleUser(db)
-	// createMultiUsers(db)
-	// createWithSpecified(db)
-	// ignoreWithSpecified(db)
-	// createInBatches(db)
-	// createWithMap(db)
-	createMapBatches(db)
+	// 下面的例子都通过泛型仓储操作，每个函数里注释了等价的 gorm 写法
+	users := repository.New[User3](db)
+	ctx := context.Background()
+
+	// createSingleUser(ctx, users)
+	// createMultiUsers(ctx, users)
+	// createWithSpecified(ctx, users)
+	// ignoreWithSpecified(ctx, users)
+	// createInBatches(ctx, users)
+	// createWithMap(ctx, users)
+	createMapBatches(ctx, users)
 }
 
-func createSingleUser(db *gorm.DB) {
+func createSingleUser(ctx context.Context, users *repository.Repository[User3]) {
 	// random name & random age
 	// go run 03_CRUD_创建.go common.go 同时指定多个文件。common里面有工具函数。
 	// go run . 不合适。这是运行整个包，还在练习阶段。推荐上面的方式。
@@ -64,10 +83,9 @@ func createSingleUser(db *gorm.DB) {
 	now := time.Now()
 	user := User3{Name: name, Age: age, Birthday: &now}
 
-	result := db.Create(&user) // 通过数据的指针来创建
-
-	if result.Error != nil {
-		log.Fatalf("failed to create user: %v", result.Error)
+	// 通过数据的指针来创建，等价于 db.Create(&user)
+	if err := users.Create(ctx, &user); err != nil {
+		log.Fatalf("failed to create user: %v", err)
 	}
 
 	// log.Printf("user created: %v", user)
@@ -80,49 +98,54 @@ func createSingleUser(db *gorm.DB) {
 	// 输出：main.User3{Model:gorm.Model{ID:0x1, CreatedAt:time.Time{...}, Name:"张三", Age:20, Birthday:time.Time{...}}
 }
 
-func createMultiUsers(db *gorm.DB) {
+func createMultiUsers(ctx context.Context, users *repository.Repository[User3]) {
 	now := time.Now()
-	users := []*User3{
+	list := []*User3{
 		{Name: randomName(), Age: randomAge(), Birthday: &now},
 		{Name: randomName(), Age: randomAge(), Birthday: &now},
 		{Name: randomName(), Age: randomAge(), Birthday: &now},
 	}
-	result := db.Create(users)
-	if result.Error != nil {
-		log.Fatalf("failed to create users: %v", result.Error)
+	// 等价于 db.Create(list)，batchSize 为 0 表示一条 SQL 插入全部
+	if err := users.CreateBatch(ctx, list, 0); err != nil {
+		log.Fatalf("failed to create users: %v", err)
 	}
-	log.Printf("users created: %+v", users)
+	log.Printf("users created: %+v", list)
 }
 
 // 用指定字段创建记录
-func createWithSpecified(db *gorm.DB) {
+func createWithSpecified(ctx context.Context, users *repository.Repository[User3]) {
 	now := time.Now()
 	// select 非SQL的select ，而是选择器
-	db.Select("Name", "Age").Create(&User3{Name: "张三", Age: 20, Birthday: &now})
+	// 等价于 db.Select("Name", "Age").Create(&User3{...})
+	users.Select("Name", "Age").Create(ctx, &User3{Name: "张三", Age: 20, Birthday: &now})
 }
 
 // 用指定字段创建记录 注：select可以和omit组合使用
-func ignoreWithSpecified(db *gorm.DB) {
+func ignoreWithSpecified(ctx context.Context, users *repository.Repository[User3]) {
 	now := time.Now()
-	// omit 忽略字段
-	db.Omit("Age").Create(&User3{Name: "张三", Age: 20, Birthday: &now})
+	// omit 忽略字段，等价于 db.Omit("Age").Create(&User3{...})
+	users.Omit("Age").Create(ctx, &User3{Name: "张三", Age: 20, Birthday: &now})
 }
 
 // 批量插入 大于1000条的情况再使用。其他可以直接使用Create即可。
 // 这些记录可以被分割成多个批次时，GORM会开启一个事务来处理它们。
-func createInBatches(db *gorm.DB) {
-	users := []User3{
-		{Name: randomName()},
-		{Name: randomName()},
-		{Name: randomName()},
+func createInBatches(ctx context.Context, users *repository.Repository[User3]) {
+	// 注意 Age 要求 18 到 150 岁，不设置 Age 整批都会失败（randomAge 已经保证范围）
+	list := []*User3{
+		{Name: randomName(), Age: randomAge()},
+		{Name: randomName(), Age: randomAge()},
+		{Name: randomName(), Age: randomAge()},
+	}
+	// 等价于 db.CreateInBatches(list, 2)
+	if err := users.CreateBatch(ctx, list, 2); err != nil {
+		log.Printf("failed to create users: %v", err)
 	}
-	db.CreateInBatches(users, 2)
 }
 
 // 使用map
-// 勾子函数不会被调用，而使用结构体的db.Create()会调用。
+// 勾子函数不会被调用，而使用结构体的db.Create()会调用。（validate 标签的校验两种方式都会执行）
 // 更灵活，如果是API接口处理，推荐使用map形式，因为使用结构体需要先创建结构体，再赋值。
-func createWithMap(db *gorm.DB) {
+func createWithMap(ctx context.Context, users *repository.Repository[User3]) {
 	now := time.Now()
 	user := map[string]interface{}{
 		"Name":      "张三啊啊",
@@ -131,18 +154,20 @@ func createWithMap(db *gorm.DB) {
 		"CreatedAt": now, // 显式添加 当使用 map[string]interface{} 创建记录时，GORM 无法识别 map 中的字段对应关系
 		"UpdatedAt": now, // 显式添加
 	}
-	db.Model(&User3{}).Create(&user)
+	// 等价于 db.Model(&User3{}).Create(&user)，map 的 key 会按模型校验，拼错字段名直接报错
+	users.CreateMap(ctx, user)
 }
 
 // map[string]interface{}  这是映射
 // []map[string]interface{}  这是(映射)切片
-func createMapBatches(db *gorm.DB) {
+func createMapBatches(ctx context.Context, users *repository.Repository[User3]) {
 	now := time.Now()
-	users := []map[string]interface{}{
+	list := []map[string]interface{}{
 		{"Name": "jinzhu_11", "Age": 18, "Birthday": &now},
 		{"Name": "jinzhu_22", "Age": 20, "Birthday": &now},
 	}
-	db.Model(&User3{}).Create(users)
+	// 等价于 db.Model(&User3{}).Create(list)
+	users.CreateMap(ctx, list...)
 }
 
 // 关联创建
@@ -0,0 +1,561 @@
+// Package audit 记录模型的变更历史
+//
+// 注册插件时列出需要审计的模型，之后对这些模型的创建、更新、删除都会在同一个事务里
+// 写一条审计记录：谁（从 context 取）、什么时候、改之前和改之后的整行 JSON、改了哪些字段
+//
+//	auditor := audit.New(&model.User3{})
+//	db.Use(auditor) // 审计表由 migrations 里的 0007_create_audit_logs 创建
+//
+//	ctx := audit.WithActor(ctx, "admin")
+//	db.WithContext(ctx).Model(&u).Update("Age", 30)
+//
+//	history, _ := auditor.History(ctx, db, &model.User3{}, u.ID)
+//	auditor.Restore(ctx, db, &model.User3{}, u.ID, 1) // 把（软删除的）记录恢复到第 1 版
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 变更类型
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// DefaultTable 审计表名
+const DefaultTable = "audit_logs"
+
+// ErrVersionNotFound 要恢复的版本不存在
+var ErrVersionNotFound = errors.New("audit: version not found")
+
+// maxVersionRetries 并发写同一条记录时，版本号冲突后最多重试的次数
+const maxVersionRetries = 5
+
+// Entry 一条审计记录
+type Entry struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	TableName string    `gorm:"column:table_name;size:64;not null;uniqueIndex:idx_audit_record,priority:1" json:"table"`
+	RecordID  string    `gorm:"size:64;not null;uniqueIndex:idx_audit_record,priority:2" json:"record_id"`
+	Version   int       `gorm:"not null;uniqueIndex:idx_audit_record,priority:3" json:"version"` // 同一条记录从 1 开始递增
+	Action    string    `gorm:"size:16;not null" json:"action"`
+	Actor     string    `gorm:"size:128" json:"actor"`
+	Before    string    `gorm:"type:text" json:"before,omitempty"` // 变更前的整行，创建时为空
+	After     string    `gorm:"type:text" json:"after,omitempty"`  // 变更后的整行，物理删除时为空
+	Diff      string    `gorm:"type:text" json:"diff"`             // {"age": {"old": 20, "new": 30}}
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Change Diff 里一个字段的变化
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Changes 解析 Diff
+func (e Entry) Changes() (map[string]Change, error) {
+	m := map[string]Change{}
+	err := json.Unmarshal([]byte(e.Diff), &m)
+	return m, err
+}
+
+type actorKey struct{}
+
+// WithActor 把操作人放进 context，审计记录里的 Actor 从这里取
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 取出操作人，没有时返回 "system"
+func ActorFrom(ctx context.Context) string {
+	if ctx != nil {
+		if a, ok := ctx.Value(actorKey{}).(string); ok && a != "" {
+			return a
+		}
+	}
+	return "system"
+}
+
+// Auditor 审计插件
+type Auditor struct {
+	Table string // 审计表名，默认 audit_logs
+
+	types map[reflect.Type]bool
+}
+
+// New 创建插件，models 是需要审计的模型（指针或值都可以）
+func New(models ...any) *Auditor {
+	a := &Auditor{Table: DefaultTable, types: map[reflect.Type]bool{}}
+	for _, m := range models {
+		a.types[indirectType(m)] = true
+	}
+	return a
+}
+
+func (a *Auditor) Name() string { return "audit" }
+
+// Initialize 检查审计表并注册回调；表由迁移创建，自定义表名时照着 0007_create_audit_logs 建表
+func (a *Auditor) Initialize(db *gorm.DB) error {
+	if !db.Migrator().HasTable(a.Table) {
+		return fmt.Errorf("audit: table %s does not exist, run migrations first", a.Table)
+	}
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("audit:after_create", a.afterCreate),
+		cb.Update().Before("gorm:update").Register("audit:before_update", a.before),
+		cb.Update().After("gorm:update").Register("audit:after_update", a.after(ActionUpdate)),
+		cb.Delete().Before("gorm:delete").Register("audit:before_delete", a.before),
+		cb.Delete().After("gorm:delete").Register("audit:after_delete", a.after(ActionDelete)),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History 按版本从旧到新返回一条记录的全部审计记录，model 只用来确定表名
+func (a *Auditor) History(ctx context.Context, db *gorm.DB, model any, id any) ([]Entry, error) {
+	s, err := parse(db, model)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	err = db.WithContext(ctx).Table(a.Table).
+		Where("table_name = ? AND record_id = ?", s.Table, recordID([]any{id})).
+		Order("version").Find(&entries).Error
+	return entries, err
+}
+
+// Restore 把记录恢复到某个版本之后的状态，包括软删除的和已经物理删除的记录
+//
+// 恢复本身也是一次变更，会写一条 Action 为 restore 的审计记录，版本号继续递增
+func (a *Auditor) Restore(ctx context.Context, db *gorm.DB, model any, id any, version int) error {
+	s, err := parse(db, model)
+	if err != nil {
+		return err
+	}
+	var e Entry
+	err = db.WithContext(ctx).Table(a.Table).
+		Where("table_name = ? AND record_id = ? AND version = ?", s.Table, recordID([]any{id}), version).
+		Take(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && e.After == "") {
+		// 物理删除那一版之后没有数据，同样视为不存在
+		return fmt.Errorf("%w: %s %v version %d", ErrVersionNotFound, s.Table, id, version)
+	}
+	if err != nil {
+		return err
+	}
+	values, err := decode(s.Schema, e.After)
+	if err != nil {
+		return err
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Set 返回的实例会被后续链式调用修改，开一个 Session 让每次查询互不影响
+		tx = tx.Set(actionKey, ActionRestore).Session(&gorm.Session{})
+		pk := clause.Eq{Column: clause.Column{Table: s.Table, Name: s.Schema.PrioritizedPrimaryField.DBName}, Value: id}
+		var n int64
+		if err := tx.Model(reflect.New(s.Schema.ModelType).Interface()).Unscoped().Where(pk).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return tx.Model(reflect.New(s.Schema.ModelType).Interface()).Create(values).Error
+		}
+		for _, f := range s.Schema.PrimaryFields {
+			delete(values, f.DBName)
+		}
+		// 恢复也是一次更新，updated_at 用现在的时间
+		for _, f := range s.Schema.Fields {
+			if f.AutoUpdateTime > 0 {
+				delete(values, f.DBName)
+			}
+		}
+		return tx.Model(reflect.New(s.Schema.ModelType).Interface()).Unscoped().Where(pk).Updates(values).Error
+	})
+}
+
+// parse 解析模型得到表名和字段
+func parse(db *gorm.DB, model any) (*gorm.Statement, error) {
+	s := &gorm.Statement{DB: db}
+	if err := s.Parse(model); err != nil {
+		return nil, fmt.Errorf("audit: parse %T: %w", model, err)
+	}
+	if s.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("audit: %s has no single primary key", s.Table)
+	}
+	return s, nil
+}
+
+// decode 把审计记录里的整行 JSON 按字段类型还原成列名 -> 值
+func decode(s *schema.Schema, data string) (map[string]any, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return nil, fmt.Errorf("audit: decode snapshot: %w", err)
+	}
+	values := make(map[string]any, len(raw))
+	for col, msg := range raw {
+		f := s.LookUpField(col)
+		if f == nil || f.DBName == "" {
+			continue // 快照之后删掉的列
+		}
+		v := reflect.New(f.FieldType)
+		if err := json.Unmarshal(msg, v.Interface()); err != nil {
+			return nil, fmt.Errorf("audit: decode %s: %w", col, err)
+		}
+		values[col] = v.Elem().Interface()
+	}
+	return values, nil
+}
+
+// enabled 当前语句的模型是否需要审计
+func (a *Auditor) enabled(db *gorm.DB) bool {
+	s := db.Statement
+	return db.Error == nil && !db.DryRun && s.Schema != nil && a.types[s.Schema.ModelType]
+}
+
+const (
+	beforeKey = "audit:before"
+	actionKey = "audit:action"
+)
+
+func (a *Auditor) afterCreate(db *gorm.DB) {
+	if !a.enabled(db) {
+		return
+	}
+	s := db.Statement
+	// 新建的记录直接从 Dest 取主键，再从库里读回整行（包含数据库生成的默认值）
+	var pks [][]any
+	switch rv := reflect.Indirect(reflect.ValueOf(s.Dest)); rv.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array:
+		if elemType(rv.Type()) == s.Schema.ModelType {
+			_, pks = schema.GetIdentityFieldValuesMap(s.Context, rv, s.Schema.PrimaryFields)
+		}
+	case reflect.Map:
+		// map 创建时 gorm 会把自增主键写回 map
+		pks = mapPrimaryKeys(s.Schema, s.Dest)
+	}
+	if len(pks) == 0 {
+		return
+	}
+	rows, err := a.load(db, pks)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	a.write(db, action(db, ActionCreate), nil, rows)
+}
+
+// before 在更新/删除之前按同样的条件把会受影响的行读出来
+func (a *Auditor) before(db *gorm.DB) {
+	if !a.enabled(db) {
+		return
+	}
+	q := a.query(db, !db.Statement.Unscoped)
+	rows, err := find(q, db.Statement.Schema)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: load rows before change: %w", err))
+		return
+	}
+	db.Statement.Settings.Store(beforeKey, rows)
+}
+
+func (a *Auditor) after(act string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if !a.enabled(db) {
+			return
+		}
+		v, ok := db.Statement.Settings.LoadAndDelete(beforeKey)
+		if !ok {
+			return
+		}
+		before := v.([]row)
+		if len(before) == 0 {
+			return
+		}
+		pks := make([][]any, len(before))
+		for i, r := range before {
+			pks[i] = r.pk
+		}
+		// 软删除之后行还在（deleted_at 有值），物理删除之后读不到
+		after, err := a.load(db, pks)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		a.write(db, action(db, act), before, after)
+	}
+}
+
+// action Restore 通过 Settings 把变更类型改成 restore
+func action(db *gorm.DB, def string) string {
+	if v, ok := db.Statement.Settings.Load(actionKey); ok {
+		return v.(string)
+	}
+	return def
+}
+
+// row 一行数据：主键值和按列名展开的字段
+type row struct {
+	pk     []any
+	values map[string]any
+}
+
+// query 构造和当前语句条件相同的查询
+func (a *Auditor) query(db *gorm.DB, scoped bool) *gorm.DB {
+	s := db.Statement
+	q := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped().Table(s.Table)
+	if c, ok := s.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			q = q.Clauses(clause.Where{Exprs: where.Exprs})
+		}
+	}
+	// db.Model(&u).Update(...) 和 db.Delete(&u) 的主键条件是 gorm 在执行时才加上的，这里照样加一遍
+	if s.ReflectValue.IsValid() && elemType(s.ReflectValue.Type()) == s.Schema.ModelType {
+		_, values := schema.GetIdentityFieldValuesMap(s.Context, s.ReflectValue, s.Schema.PrimaryFields)
+		if col, vals := schema.ToQueryValues(s.Table, s.Schema.PrimaryFieldDBNames, values); len(vals) > 0 {
+			q = q.Where(clause.IN{Column: col, Values: vals})
+		}
+	}
+	if f := softDelete(s.Schema); scoped && f != nil {
+		q = q.Where(clause.Eq{Column: clause.Column{Table: s.Table, Name: f.DBName}, Value: nil})
+	}
+	return q
+}
+
+// load 按主键读取（包含软删除的行）
+func (a *Auditor) load(db *gorm.DB, pks [][]any) ([]row, error) {
+	s := db.Statement
+	col, vals := schema.ToQueryValues(s.Table, s.Schema.PrimaryFieldDBNames, pks)
+	q := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped().Table(s.Table).Where(clause.IN{Column: col, Values: vals})
+	rows, err := find(q, s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("audit: load rows: %w", err)
+	}
+	return rows, nil
+}
+
+// find 查询到模型结构体里，再按列名展开，保证时间等字段类型正确
+func find(q *gorm.DB, s *schema.Schema) ([]row, error) {
+	slice := reflect.New(reflect.SliceOf(s.ModelType))
+	if err := q.Find(slice.Interface()).Error; err != nil {
+		return nil, err
+	}
+	ctx := q.Statement.Context
+	rows := make([]row, slice.Elem().Len())
+	for i := range rows {
+		rv := slice.Elem().Index(i)
+		r := row{values: map[string]any{}}
+		for _, f := range s.Fields {
+			if f.DBName == "" {
+				continue
+			}
+			v, _ := f.ValueOf(ctx, rv)
+			r.values[f.DBName] = v
+		}
+		for _, f := range s.PrimaryFields {
+			v, _ := f.ValueOf(ctx, rv)
+			r.pk = append(r.pk, v)
+		}
+		rows[i] = r
+	}
+	return rows, nil
+}
+
+// write 对比前后两组行，每行写一条审计记录
+func (a *Auditor) write(db *gorm.DB, action string, before, after []row) {
+	byKey := map[string]row{}
+	for _, r := range after {
+		byKey[recordID(r.pk)] = r
+	}
+	var keys []string
+	olds := map[string]row{}
+	for _, r := range before {
+		k := recordID(r.pk)
+		olds[k] = r
+		keys = append(keys, k)
+	}
+	if before == nil {
+		for _, r := range after {
+			keys = append(keys, recordID(r.pk))
+		}
+	}
+
+	s := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	actor := ActorFrom(s.Context)
+	now := time.Now()
+	for _, k := range keys {
+		old, hasOld := olds[k]
+		cur, hasCur := byKey[k]
+		var oldVals, newVals map[string]any
+		if hasOld {
+			oldVals = old.values
+		}
+		if hasCur {
+			newVals = cur.values
+		}
+		diff, changed := diffJSON(s.Schema, oldVals, newVals)
+		if !changed {
+			continue
+		}
+		e := Entry{
+			TableName: s.Table,
+			RecordID:  k,
+			Action:    action,
+			Actor:     actor,
+			Before:    marshal(oldVals),
+			After:     marshal(newVals),
+			Diff:      diff,
+			CreatedAt: now,
+		}
+		if err := a.insert(tx, &e); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+// insert 以当前最大版本加 1 写入；另一个事务抢先写了同一个版本时唯一索引冲突，换下一个版本重试
+// 冲突后不重新查 MAX：MySQL 可重复读的事务里再查还是旧的快照
+func (a *Auditor) insert(tx *gorm.DB, e *Entry) error {
+	var last int
+	if err := tx.Table(a.Table).Where("table_name = ? AND record_id = ?", e.TableName, e.RecordID).
+		Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+		return fmt.Errorf("audit: next version: %w", err)
+	}
+	for i := 1; ; i++ {
+		e.ID, e.Version = 0, last+i
+		err := tx.Table(a.Table).Create(e).Error
+		if err == nil {
+			return nil
+		}
+		if i == maxVersionRetries || !duplicated(tx, err) {
+			return fmt.Errorf("audit: write entry: %w", err)
+		}
+	}
+}
+
+// duplicated 是否违反唯一索引；没有开 TranslateError 时借用驱动的翻译
+func duplicated(db *gorm.DB, err error) bool {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = t.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// diffJSON 按 JSON 表示比较每一列，返回变化的列
+// 只有 updated_at 这类自动更新时间变化时视为没有变更
+func diffJSON(s *schema.Schema, old, cur map[string]any) (string, bool) {
+	changes := map[string]Change{}
+	seen := map[string]bool{}
+	for k := range old {
+		seen[k] = true
+	}
+	for k := range cur {
+		seen[k] = true
+	}
+	for k := range seen {
+		o, n := old[k], cur[k]
+		if marshal(o) != marshal(n) {
+			changes[k] = Change{Old: o, New: n}
+		}
+	}
+	changed := false
+	for k := range changes {
+		if f := s.LookUpField(k); f == nil || f.AutoUpdateTime == 0 || old == nil || cur == nil {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	b, _ := json.Marshal(changes)
+	return string(b), true
+}
+
+func marshal(v any) string {
+	if m, ok := v.(map[string]any); ok && m == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func recordID(pk []any) string {
+	parts := make([]string, len(pk))
+	for i, v := range pk {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func mapPrimaryKeys(s *schema.Schema, dest any) [][]any {
+	var maps []map[string]any
+	switch d := dest.(type) {
+	case map[string]any:
+		maps = append(maps, d)
+	case *map[string]any:
+		maps = append(maps, *d)
+	case []map[string]any:
+		maps = d
+	case *[]map[string]any:
+		maps = *d
+	}
+	var pks [][]any
+	for _, m := range maps {
+		var pk []any
+		for _, f := range s.PrimaryFields {
+			v, ok := m[f.DBName]
+			if !ok {
+				v, ok = m[f.Name]
+			}
+			if !ok {
+				return nil
+			}
+			pk = append(pk, v)
+		}
+		pks = append(pks, pk)
+	}
+	return pks
+}
+
+func softDelete(s *schema.Schema) *schema.Field {
+	for _, f := range s.Fields {
+		if _, ok := f.FieldType.MethodByName("DeleteClauses"); ok && f.DBName != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+func indirectType(v any) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/migrations"
+)
+
+// user 与课程里的 User3 一致
+type user struct {
+	gorm.Model
+	Name     string
+	Age      int
+	Birthday *time.Time
+}
+
+func (user) TableName() string { return "user3" }
+
+// plain 没有注册审计
+type plain struct {
+	ID   uint
+	Name string
+}
+
+func openDB(t *testing.T) (*gorm.DB, *Auditor) {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}, &plain{}); err != nil {
+		t.Fatal(err)
+	}
+	a := New(&user{})
+	if err := db.Use(a); err != nil {
+		t.Fatal(err)
+	}
+	return db, a
+}
+
+func history(t *testing.T, db *gorm.DB, a *Auditor, id uint) []Entry {
+	t.Helper()
+	h, err := a.History(context.Background(), db, &user{}, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestCreateUpdateDelete(t *testing.T) {
+	db, a := openDB(t)
+	ctx := WithActor(context.Background(), "admin")
+
+	u := user{Name: "张三", Age: 20}
+	if err := db.WithContext(ctx).Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WithContext(ctx).Model(&u).Update("age", 30).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 值没有变化的更新不记录
+	if err := db.Model(&user{}).Where("name = ?", "张三").Update("name", "张三").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := history(t, db, a, u.ID)
+	if len(h) != 3 {
+		t.Fatalf("len(history) = %d, want 3: %+v", len(h), h)
+	}
+	for i, want := range []struct {
+		action, actor string
+	}{{ActionCreate, "admin"}, {ActionUpdate, "admin"}, {ActionDelete, "system"}} {
+		if h[i].Version != i+1 || h[i].Action != want.action || h[i].Actor != want.actor {
+			t.Errorf("history[%d] = v%d %s by %s, want v%d %s by %s",
+				i, h[i].Version, h[i].Action, h[i].Actor, i+1, want.action, want.actor)
+		}
+	}
+	if h[0].Before != "" || h[0].After == "" {
+		t.Errorf("create entry before = %q, after = %q", h[0].Before, h[0].After)
+	}
+
+	changes, err := h[1].Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := changes["age"]; !ok || c.Old != float64(20) || c.New != float64(30) {
+		t.Errorf("update diff age = %+v, want 20 -> 30", c)
+	}
+	if _, ok := changes["name"]; ok {
+		t.Errorf("update diff should not contain name: %s", h[1].Diff)
+	}
+
+	// 软删除只改了 deleted_at
+	changes, _ = h[2].Changes()
+	if _, ok := changes["deleted_at"]; !ok || h[2].After == "" {
+		t.Errorf("soft delete diff = %s, after = %q", h[2].Diff, h[2].After)
+	}
+}
+
+func TestBatchAndHardDelete(t *testing.T) {
+	db, a := openDB(t)
+
+	us := []user{{Name: "a", Age: 20}, {Name: "b", Age: 20}, {Name: "c", Age: 40}}
+	if err := db.Create(&us).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&user{}).Where("age = ?", 20).Update("age", 21).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Unscoped().Delete(&us[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 没有注册的模型不记录
+	if err := db.Create(&plain{Name: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if h := history(t, db, a, us[0].ID); len(h) != 3 || h[2].Action != ActionDelete || h[2].After != "" {
+		t.Errorf("history of a = %+v", h)
+	}
+	if h := history(t, db, a, us[1].ID); len(h) != 2 {
+		t.Errorf("len(history of b) = %d, want 2", len(h))
+	}
+	if h := history(t, db, a, us[2].ID); len(h) != 1 {
+		t.Errorf("len(history of c) = %d, want 1", len(h))
+	}
+	var n int64
+	db.Table(a.Table).Count(&n)
+	if n != 6 {
+		t.Errorf("audit entries = %d, want 6", n)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	db, a := openDB(t)
+	ctx := WithActor(context.Background(), "admin")
+
+	birthday := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	u := user{Name: "张三", Age: 20, Birthday: &birthday}
+	db.Create(&u)
+	db.Model(&u).Updates(map[string]any{"name": "李四", "age": 30})
+	db.Delete(&u)
+
+	// 恢复到第 1 版：软删除的行回来，字段回到创建时的值
+	if err := a.Restore(ctx, db, &user{}, u.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	var got user
+	if err := db.First(&got, u.ID).Error; err != nil {
+		t.Fatalf("restored row not visible: %v", err)
+	}
+	if got.Name != "张三" || got.Age != 20 || got.Birthday == nil || !got.Birthday.Equal(birthday) {
+		t.Errorf("restored = %+v", got)
+	}
+
+	h := history(t, db, a, u.ID)
+	last := h[len(h)-1]
+	if last.Version != 4 || last.Action != ActionRestore || last.Actor != "admin" {
+		t.Errorf("restore entry = v%d %s by %s", last.Version, last.Action, last.Actor)
+	}
+
+	// 物理删除后也能恢复
+	db.Unscoped().Delete(&got)
+	if err := a.Restore(ctx, db, &user{}, u.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	got = user{}
+	if err := db.First(&got, u.ID).Error; err != nil || got.Name != "李四" || got.Age != 30 {
+		t.Errorf("restored after hard delete = %+v, %v", got, err)
+	}
+
+	// 物理删除那一版和不存在的版本都不能恢复
+	for _, v := range []int{5, 99} {
+		if err := a.Restore(ctx, db, &user{}, u.ID, v); !errors.Is(err, ErrVersionNotFound) {
+			t.Errorf("Restore(version %d) = %v, want ErrVersionNotFound", v, err)
+		}
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	db, a := openDB(t)
+	u := user{Name: "张三", Age: 20}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟另一个事务在查完 MAX(version) 之后抢先写入了同一个版本；
+	// 要在 Create 的保存点之前写，否则冲突回滚时会连这条一起回滚
+	raced := false
+	err := db.Callback().Create().Before("gorm:begin_transaction").Register("test:race", func(tx *gorm.DB) {
+		e, ok := tx.Statement.Dest.(*Entry)
+		if !ok || raced {
+			return
+		}
+		raced = true
+		tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO audit_logs (table_name, record_id, version, action) VALUES (?, ?, ?, ?)",
+			e.TableName, e.RecordID, e.Version, ActionUpdate)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&u).Update("age", 21).Error; err != nil {
+		t.Fatal(err)
+	}
+	h := history(t, db, a, u.ID)
+	if !raced || len(h) != 3 || h[1].Actor != "" || h[2].Version != 3 || h[2].Actor != "system" {
+		t.Errorf("history = %+v", h)
+	}
+
+	// 没有执行迁移时不会偷偷建表
+	bare, _ := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err := bare.Use(New(&user{})); err == nil {
+		t.Error("Use without audit table: nil error")
+	}
+}
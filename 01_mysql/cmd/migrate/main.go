@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+
+	"mysql-demo/database"
+	"mysql-demo/migrate"
+	"mysql-demo/migrations"
+)
+
+const usage = `用法: go run ./cmd/migrate [flags] <command>
+
+命令:
+  up          执行所有未执行的迁移
+  down [n]    回滚最近 n 个迁移，默认 1
+  status      查看每个迁移是否已执行
+  to N        迁移到版本 N（升级或回滚），0 表示全部回滚
+
+flags:
+`
+
+// 例：DB_CONFIG=config.example.yaml go run ./cmd/migrate status
+// 例：DB_DRIVER=sqlite DB_DSN=test.db go run ./cmd/migrate -dry-run up
+func main() {
+	configPath := flag.String("config", "", "配置文件路径，默认读取环境变量 DB_CONFIG")
+	dryRun := flag.Bool("dry-run", false, "只打印将要执行的 SQL")
+	allowDrift := flag.Bool("allow-drift", false, "已执行的迁移被修改时只警告")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := database.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m, err := migrations.New(db, migrate.Options{
+		Out:        os.Stdout,
+		DryRun:     *dryRun,
+		AllowDrift: *allowDrift,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, m, flag.Args()); err != nil {
+		if errors.Is(err, migrate.ErrDrift) {
+			log.Print("hint: 使用 -allow-drift 跳过检查")
+		}
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, m *migrate.Migrator, args []string) error {
+	switch cmd := args[0]; cmd {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("down: bad step count %q", args[1])
+			}
+			steps = n
+		}
+		return m.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return errors.New("to: missing version")
+		}
+		v, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("to: bad version %q", args[1])
+		}
+		return m.To(ctx, v)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range list {
+			state, at := "pending", ""
+			if s.Applied {
+				state, at = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			switch {
+			case s.Drift:
+				state += " (modified)"
+			case s.Orphan:
+				state += " (missing in code)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
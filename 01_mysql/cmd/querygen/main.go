@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"mysql-demo/query/gen"
+)
+
+const usage = `用法: go run ./cmd/querygen [flags]
+
+按 query/gen.Models 里的模型生成 query 包的字段描述，一般通过 go generate ./query 调用
+
+flags:
+`
+
+func main() {
+	out := flag.String("out", "", "输出文件，默认打印到标准输出")
+	check := flag.Bool("check", false, "只检查 -out 是否是最新的，不是时以状态码 1 退出（用于 CI）")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	src, err := gen.Generate(gen.Models...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case *out == "":
+		os.Stdout.Write(src)
+	case *check:
+		old, _ := os.ReadFile(*out)
+		if !bytes.Equal(old, src) {
+			fmt.Fprintf(os.Stderr, "%s is out of date, run go generate ./query\n", *out)
+			os.Exit(1)
+		}
+	default:
+		if err := os.WriteFile(*out, src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
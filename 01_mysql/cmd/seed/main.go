@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mysql-demo/database"
+	"mysql-demo/fixtures"
+	"mysql-demo/migrations"
+	"mysql-demo/validate"
+)
+
+const usage = `用法: go run ./cmd/seed [flags] [表名=条数 ...]
+
+例：
+  go run ./cmd/seed user3=100000               造 10 万个 user3
+  go run ./cmd/seed -set users,edge_ages       加载命名夹具
+  go run ./cmd/seed -seed 7 user3=10 02_user=5 换一个种子
+
+flags:
+`
+
+func main() {
+	configPath := flag.String("config", "", "配置文件路径，默认读取环境变量 DB_CONFIG")
+	seed := flag.Uint64("seed", fixtures.DefaultSeed, "随机种子，相同种子生成相同数据")
+	batch := flag.Int("batch", 500, "每批写入的条数，按批生成和写入，内存占用和总条数无关")
+	setNames := flag.String("set", "", "逗号分隔的命名夹具")
+	migrate := flag.Bool("migrate", true, "造数前先执行迁移")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n表: %s\n夹具: %s\n", strings.Join(tables(), ", "), strings.Join(fixtures.Names(), ", "))
+	}
+	flag.Parse()
+
+	counts, err := parseCounts(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(counts) == 0 && *setNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := database.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.Use(validate.Plugin{}); err != nil {
+		log.Fatal(err)
+	}
+	if *migrate {
+		if err := migrations.Up(db); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := fixtures.NewSeeder(db, *seed)
+	s.BatchSize = *batch
+	if *setNames != "" {
+		names := strings.Split(*setNames, ",")
+		if err := fixtures.Load(ctx, s, names...); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("loaded sets %v", names)
+	}
+	for _, c := range counts {
+		start := time.Now()
+		if err := fixtures.Counts[c.table](ctx, s, c.n); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("seeded %d %s in %v", c.n, c.table, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+type count struct {
+	table string
+	n     int
+}
+
+// parseCounts 解析 user3=100 这样的参数，保持命令行里的顺序（有关联的表要先造被引用的）
+func parseCounts(args []string) ([]count, error) {
+	var out []count
+	for _, arg := range args {
+		table, num, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad argument %q, want table=count", arg)
+		}
+		if _, ok := fixtures.Counts[table]; !ok {
+			return nil, fmt.Errorf("unknown table %q, have %v", table, tables())
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad count in %q", arg)
+		}
+		out = append(out, count{table, n})
+	}
+	return out, nil
+}
+
+func tables() []string {
+	var names []string
+	for name := range fixtures.Counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
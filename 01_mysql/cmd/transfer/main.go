@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/query/gen"
+	"mysql-demo/transfer"
+	"mysql-demo/validate"
+)
+
+const usage = `用法: go run ./cmd/transfer <export|import> [flags] [文件]
+
+例：
+  go run ./cmd/transfer export -model user3 -format csv -where "age > 18" -o adults.csv
+  go run ./cmd/transfer export -model user3_orders -format jsonl > orders.jsonl
+  go run ./cmd/transfer import -model user3 adults.csv                 格式按扩展名判断
+  go run ./cmd/transfer import -model user3_orders -on-conflict update -conflict no < orders.jsonl
+
+导入时出错的行打印到标准错误，不影响其他行；有失败的行时以状态码 1 退出
+
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	configPath := fs.String("config", "", "配置文件路径，默认读取环境变量 DB_CONFIG")
+	modelName := fs.String("model", "", "表名或模型名，例如 user3、User3")
+	format := fs.String("format", "", "csv 或 jsonl，默认按文件扩展名判断")
+	batch := fs.Int("batch", transfer.DefaultBatch, "每批读写的行数")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n表: %s\n", strings.Join(tables(), ", "))
+	}
+
+	var run func(ctx context.Context, db *gorm.DB, m any, f transfer.Format) error
+	switch cmd {
+	case "export":
+		where := fs.String("where", "", "导出条件（原生 SQL），例如 \"age > 18\"")
+		unscoped := fs.Bool("unscoped", false, "包含软删除的记录")
+		out := fs.String("o", "", "输出文件，默认标准输出")
+		run = func(ctx context.Context, db *gorm.DB, m any, f transfer.Format) error {
+			w := io.Writer(os.Stdout)
+			if *out != "" {
+				file, err := os.Create(*out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			n, err := transfer.Export(ctx, db, m, w, transfer.ExportOptions{
+				Format:   f,
+				Where:    *where,
+				Unscoped: *unscoped,
+				Batch:    *batch,
+				Progress: func(n int64) { log.Printf("exported %d rows", n) },
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("done, %d rows", n)
+			return nil
+		}
+	case "import":
+		onConflict := fs.String("on-conflict", "error", "主键或唯一键冲突时：error 记为失败，ignore 跳过，update 覆盖")
+		conflict := fs.String("conflict", "", "逗号分隔的唯一键列，配合 -on-conflict update，默认主键")
+		run = func(ctx context.Context, db *gorm.DB, m any, f transfer.Format) error {
+			mode, err := transfer.ParseConflict(*onConflict)
+			if err != nil {
+				return err
+			}
+			r := io.Reader(os.Stdin)
+			if fs.NArg() > 0 {
+				file, err := os.Open(fs.Arg(0))
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				r = file
+			}
+			o := transfer.ImportOptions{
+				Format:     f,
+				Batch:      *batch,
+				OnConflict: mode,
+				Progress: func(r transfer.Report) {
+					log.Printf("read %d rows, written %d, failed %d", r.Read, r.Written, len(r.Failed))
+				},
+			}
+			if *conflict != "" {
+				o.ConflictColumns = strings.Split(*conflict, ",")
+			}
+			report, err := transfer.Import(ctx, db, m, r, o)
+			for _, fe := range report.Failed {
+				log.Print(fe)
+			}
+			if err != nil {
+				return err
+			}
+			log.Printf("done, read %d rows, written %d, failed %d", report.Read, report.Written, len(report.Failed))
+			if len(report.Failed) > 0 {
+				os.Exit(1)
+			}
+			return nil
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	fs.Parse(args)
+
+	if *modelName == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format == "" && cmd == "import" && fs.NArg() > 0 {
+		*format = filepath.Ext(fs.Arg(0))
+	}
+	if *format == "" && cmd == "export" {
+		*format = string(transfer.CSV)
+	}
+	f, err := transfer.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := database.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.Use(validate.Plugin{}); err != nil {
+		log.Fatal(err)
+	}
+	m, err := lookup(db, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, db, m, f); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// lookup 在 gen.Models 里按表名或结构体名找模型
+func lookup(db *gorm.DB, name string) (any, error) {
+	for _, m := range gen.Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		if stmt.Schema.Table == name || strings.EqualFold(stmt.Schema.Name, name) {
+			return reflect.New(reflect.TypeOf(m)).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown model %q, have %v", name, tables())
+}
+
+func tables() []string {
+	var names []string
+	for _, m := range gen.Models {
+		if t, ok := m.(interface{ TableName() string }); ok {
+			names = append(names, t.TableName())
+		}
+	}
+	return names
+}
@@ -1,20 +1,19 @@
 package main
 
 import (
-	"fmt"
-	"math/rand"
+	"mysql-demo/fixtures"
 )
 
-func randomName() string {
-	firstName := []string{"张", "李", "王", "赵", "孙", "周", "吴", "郑", "冯", "陈"}
-	lastName := []string{"三", "四", "五", "六", "七", "八", "九", "十"}
+// 随机数据统一由 fixtures 生成，需要可复现的数据（测试、造数）时用 fixtures.NewSeeder 固定种子
+// 例：go run ./cmd/seed user3=1000
+var faker = fixtures.Random()
 
-	// %s%s是格式化模板，表示两个字符串占位符
-	name := fmt.Sprintf("%s%s", firstName[rand.Intn(len(firstName))], lastName[rand.Intn(len(lastName))])
-	return name
+func randomName() string {
+	// 姓 + 名，例如 张三
+	return faker.Name()
 }
 
 func randomAge() int {
-	age := rand.Intn(100)
-	return age
+	// 18 到 80 岁，满足 User3 的 validate 标签
+	return faker.Age()
 }
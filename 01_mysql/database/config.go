@@ -0,0 +1,234 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// 支持的驱动
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// ConfigEnv 指定配置文件路径的环境变量
+const ConfigEnv = "DB_CONFIG"
+
+// Duration 让配置文件里可以写 "30s"、"5m" 这样的时长
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config 数据库连接配置
+// 优先级：环境变量 > 配置文件 > 默认值
+type Config struct {
+	Driver string `yaml:"driver" toml:"driver"` // mysql | sqlite，默认 mysql
+	// DSN 完整的连接串，设置后忽略下面的 Host/Port/User/Password/Name/Params
+	// sqlite 为文件路径，默认 :memory:
+	DSN string `yaml:"dsn" toml:"dsn"`
+
+	Host     string `yaml:"host" toml:"host"`         // 默认 127.0.0.1
+	Port     int    `yaml:"port" toml:"port"`         // 默认 3306
+	User     string `yaml:"user" toml:"user"`         // 默认 root
+	Password string `yaml:"password" toml:"password"` // 默认空
+	Name     string `yaml:"name" toml:"name"`         // 数据库名，默认 test_gorm
+	// Params mysql 连接参数，默认 charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true
+	Params string `yaml:"params" toml:"params"`
+
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`         // 最大打开连接数，默认 20
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`         // 最大空闲连接数，默认 10
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`   // 连接最长存活时间，默认 1h
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"` // 连接最长空闲时间，默认 10m
+
+	PingAttempts int      `yaml:"ping_attempts" toml:"ping_attempts"` // 启动时 ping 的次数，默认 5
+	PingBackoff  Duration `yaml:"ping_backoff" toml:"ping_backoff"`   // 第一次重试的等待，之后翻倍，默认 500ms
+	PingTimeout  Duration `yaml:"ping_timeout" toml:"ping_timeout"`   // 单次 ping 超时，默认 3s
+
+	// LogLevel GORM 日志级别：silent | error | warn | info，默认 warn
+	// 以前在代码里注释/取消注释 logger.Default.LogMode(logger.Info)，现在改配置即可
+	LogLevel      string   `yaml:"log_level" toml:"log_level"`
+	SlowThreshold Duration `yaml:"slow_threshold" toml:"slow_threshold"` // 慢查询阈值，默认 200ms
+}
+
+// Default 返回默认配置
+func Default() Config {
+	return Config{
+		Driver:          DriverMySQL,
+		Host:            "127.0.0.1",
+		Port:            3306,
+		User:            "root",
+		Name:            "test_gorm",
+		Params:          "charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true",
+		MaxOpenConns:    20,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: Duration(time.Hour),
+		ConnMaxIdleTime: Duration(10 * time.Minute),
+		PingAttempts:    5,
+		PingBackoff:     Duration(500 * time.Millisecond),
+		PingTimeout:     Duration(3 * time.Second),
+		LogLevel:        "warn",
+		SlowThreshold:   Duration(200 * time.Millisecond),
+	}
+}
+
+// Load 加载配置：默认值 -> 配置文件 -> 环境变量
+// path 为空时读取环境变量 DB_CONFIG，仍为空则不读文件
+// 文件格式按扩展名判断：.yaml/.yml/.toml
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// MustLoad 同 Load(""), 失败时 panic，给课程里的 main 函数用
+func MustLoad() Config {
+	cfg, err := Load("")
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("database: read config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("database: unsupported config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("database: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 用环境变量覆盖配置，变量名为 DB_ 加上大写的 yaml 字段名，例如 DB_MAX_OPEN_CONNS
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"DB_DRIVER":    &cfg.Driver,
+		"DB_DSN":       &cfg.DSN,
+		"DB_HOST":      &cfg.Host,
+		"DB_USER":      &cfg.User,
+		"DB_PASSWORD":  &cfg.Password,
+		"DB_NAME":      &cfg.Name,
+		"DB_PARAMS":    &cfg.Params,
+		"DB_LOG_LEVEL": &cfg.LogLevel,
+	}
+	for key, p := range strs {
+		if v, ok := lookup(key); ok {
+			*p = v
+		}
+	}
+
+	ints := map[string]*int{
+		"DB_PORT":           &cfg.Port,
+		"DB_MAX_OPEN_CONNS": &cfg.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &cfg.MaxIdleConns,
+		"DB_PING_ATTEMPTS":  &cfg.PingAttempts,
+	}
+	for key, p := range ints {
+		if v, ok := lookup(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("database: %s: %w", key, err)
+			}
+			*p = n
+		}
+	}
+
+	durs := map[string]*Duration{
+		"DB_CONN_MAX_LIFETIME":  &cfg.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &cfg.ConnMaxIdleTime,
+		"DB_PING_BACKOFF":       &cfg.PingBackoff,
+		"DB_PING_TIMEOUT":       &cfg.PingTimeout,
+		"DB_SLOW_THRESHOLD":     &cfg.SlowThreshold,
+	}
+	for key, p := range durs {
+		if v, ok := lookup(key); ok {
+			if err := p.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("database: %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 检查配置
+func (c Config) Validate() error {
+	switch c.Driver {
+	case DriverMySQL, DriverSQLite:
+	default:
+		return fmt.Errorf("database: unknown driver %q, want mysql or sqlite", c.Driver)
+	}
+	if _, err := c.gormLogLevel(); err != nil {
+		return err
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		return fmt.Errorf("database: pool sizes must not be negative")
+	}
+	return nil
+}
+
+// DataSource 返回最终使用的连接串
+func (c Config) DataSource() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	if c.Driver == DriverSQLite {
+		return ":memory:"
+	}
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.User, c.Password, c.Host, c.Port, c.Name)
+	if c.Params != "" {
+		dsn += "?" + c.Params
+	}
+	return dsn
+}
+
+// Redacted 隐藏密码的连接串，用于日志
+func (c Config) Redacted() string {
+	dsn := c.DataSource()
+	if c.Driver != DriverMySQL {
+		return dsn
+	}
+	// user:password@tcp(...) -> user:***@tcp(...)
+	at := strings.LastIndex(dsn, "@")
+	colon := strings.Index(dsn, ":")
+	if at < 0 || colon < 0 || colon > at {
+		return dsn
+	}
+	return dsn[:colon+1] + "***" + dsn[at:]
+}
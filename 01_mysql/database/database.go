@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 按配置打开数据库：选择驱动、设置连接池、启动时带退避重试地 ping
+// 课程里的写法统一为：
+//
+//	db, err := database.Open(database.MustLoad())
+//
+// 测试里用 DB_DRIVER=sqlite 或者直接传 Config{Driver: "sqlite"}，不需要改代码
+func Open(cfg Config) (*gorm.DB, error) {
+	return OpenContext(context.Background(), cfg)
+}
+
+// OpenContext 同 Open，ctx 用于控制启动时重试的总时长
+func OpenContext(ctx context.Context, cfg Config) (*gorm.DB, error) {
+	cfg = withDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 数据库还没起来（例如 docker compose 同时启动）时按指数退避重试
+	// gorm.Open 初始化时就会查询版本，所以整个打开过程都放在重试里
+	wait := time.Duration(cfg.PingBackoff)
+	for attempt := 1; ; attempt++ {
+		db, err := connect(ctx, cfg)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.PingAttempts {
+			return nil, fmt.Errorf("database: connect %s failed after %d attempts: %w", cfg.Redacted(), attempt, err)
+		}
+		log.Printf("database: connect %s failed (attempt %d/%d), retry in %v: %v", cfg.Redacted(), attempt, cfg.PingAttempts, wait, err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("database: connect %s: %w", cfg.Redacted(), ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// connect 打开一次连接：创建 gorm.DB、设置连接池、ping
+func connect(ctx context.Context, cfg Config) (*gorm.DB, error) {
+	level, _ := cfg.gormLogLevel()
+
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverMySQL:
+		dialector = mysql.Open(cfg.DataSource())
+	case DriverSQLite:
+		dialector = sqlite.Open(sqliteDSN(cfg.DataSource()))
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             time.Duration(cfg.SlowThreshold),
+			LogLevel:                  level,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  false,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	maxOpen, maxIdle := cfg.MaxOpenConns, cfg.MaxIdleConns
+	lifetime, idleTime := time.Duration(cfg.ConnMaxLifetime), time.Duration(cfg.ConnMaxIdleTime)
+	// 每个 :memory: 连接都是一个独立的空库，只能用一个连接，并且连接不能被回收
+	if cfg.Driver == DriverSQLite && isMemory(cfg.DataSource()) {
+		maxOpen, maxIdle = 1, 1
+		lifetime, idleTime = 0, 0
+	}
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(lifetime)
+	sqlDB.SetConnMaxIdleTime(idleTime)
+
+	pctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.PingTimeout))
+	defer cancel()
+	if err := sqlDB.PingContext(pctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// withDefaults 直接构造的 Config 可能缺字段，用默认值补齐
+func withDefaults(cfg Config) Config {
+	def := Default()
+	if cfg.Driver == "" {
+		cfg.Driver = def.Driver
+	}
+	if cfg.Driver == DriverMySQL && cfg.DSN == "" {
+		if cfg.Host == "" {
+			cfg.Host = def.Host
+		}
+		if cfg.Port == 0 {
+			cfg.Port = def.Port
+		}
+		if cfg.User == "" {
+			cfg.User = def.User
+		}
+		if cfg.Name == "" {
+			cfg.Name = def.Name
+		}
+		if cfg.Params == "" {
+			cfg.Params = def.Params
+		}
+	}
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = def.MaxOpenConns
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = def.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = def.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime == 0 {
+		cfg.ConnMaxIdleTime = def.ConnMaxIdleTime
+	}
+	if cfg.PingAttempts <= 0 {
+		cfg.PingAttempts = def.PingAttempts
+	}
+	if cfg.PingBackoff == 0 {
+		cfg.PingBackoff = def.PingBackoff
+	}
+	if cfg.PingTimeout == 0 {
+		cfg.PingTimeout = def.PingTimeout
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = def.LogLevel
+	}
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = def.SlowThreshold
+	}
+	return cfg
+}
+
+func (c Config) gormLogLevel() (logger.LogLevel, error) {
+	switch strings.ToLower(c.LogLevel) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "", "warn":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	}
+	return 0, fmt.Errorf("database: unknown log level %q", c.LogLevel)
+}
+
+func isMemory(dsn string) bool {
+	return dsn == ":memory:" || strings.Contains(dsn, "mode=memory") || strings.HasPrefix(dsn, "file::memory:")
+}
+
+// sqliteDSN 给 sqlite 文件库默认打开外键约束，并设置忙等待，避免并发写时直接报 database is locked
+func sqliteDSN(dsn string) string {
+	params := []string{}
+	if !strings.Contains(dsn, "_foreign_keys") && !strings.Contains(dsn, "_fk") {
+		params = append(params, "_foreign_keys=1")
+	}
+	if !strings.Contains(dsn, "_busy_timeout") && !strings.Contains(dsn, "_timeout") {
+		params = append(params, "_busy_timeout=5000")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	if dsn == ":memory:" {
+		dsn = "file::memory:"
+	}
+	return dsn + sep + strings.Join(params, "&")
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "db.yaml")
+	os.WriteFile(yamlPath, []byte("driver: sqlite\ndsn: a.db\nmax_open_conns: 7\nconn_max_lifetime: 2m\nlog_level: info\n"), 0o644)
+	tomlPath := filepath.Join(dir, "db.toml")
+	os.WriteFile(tomlPath, []byte("driver = \"mysql\"\nhost = \"db\"\npassword = \"secret\"\nping_backoff = \"1s\"\n"), 0o644)
+
+	cfg, err := Load(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Driver != DriverSQLite || cfg.DSN != "a.db" || cfg.MaxOpenConns != 7 ||
+		time.Duration(cfg.ConnMaxLifetime) != 2*time.Minute || cfg.MaxIdleConns != 10 {
+		t.Errorf("yaml config = %+v", cfg)
+	}
+
+	// 环境变量覆盖文件
+	t.Setenv(ConfigEnv, tomlPath)
+	t.Setenv("DB_PORT", "3307")
+	t.Setenv("DB_PING_TIMEOUT", "10s")
+	cfg, err = Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "db" || cfg.Port != 3307 || time.Duration(cfg.PingBackoff) != time.Second || time.Duration(cfg.PingTimeout) != 10*time.Second {
+		t.Errorf("toml+env config = %+v", cfg)
+	}
+	if got := cfg.DataSource(); got != "root:secret@tcp(db:3307)/test_gorm?"+Default().Params {
+		t.Errorf("DataSource() = %q", got)
+	}
+	if got := cfg.Redacted(); strings.Contains(got, "secret") || !strings.HasPrefix(got, "root:***@tcp(db:3307)") {
+		t.Errorf("Redacted() = %q", got)
+	}
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	if _, err := Load(""); err == nil {
+		t.Error("Load() with bad DB_MAX_OPEN_CONNS should fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{Driver: "postgres", LogLevel: "warn"},
+		{Driver: DriverSQLite, LogLevel: "verbose"},
+		{Driver: DriverSQLite, MaxOpenConns: -1},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", cfg)
+		}
+	}
+}
+
+func TestOpenSQLiteMemory(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_LOG_LEVEL", "silent")
+	db, err := Open(MustLoad())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	type Item struct {
+		ID   uint
+		Name string
+	}
+	if err := db.AutoMigrate(&Item{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&Item{Name: "张三"})
+	var n int64
+	db.Model(&Item{}).Count(&n)
+	if n != 1 {
+		t.Errorf("count = %d, want 1", n)
+	}
+	// 内存库固定一个连接，保证所有查询看到同一个库
+	if got := sqlDB.Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("MaxOpenConnections = %d, want 1", got)
+	}
+
+	var fk int
+	db.Raw("PRAGMA foreign_keys").Scan(&fk)
+	if fk != 1 {
+		t.Errorf("foreign_keys = %d, want 1", fk)
+	}
+}
+
+func TestOpenPingRetry(t *testing.T) {
+	// 目录不存在，sqlite 每次连接都会失败
+	cfg := Config{
+		Driver:       DriverSQLite,
+		DSN:          filepath.Join(t.TempDir(), "missing", "x.db"),
+		PingAttempts: 3,
+		PingBackoff:  Duration(10 * time.Millisecond),
+		LogLevel:     "silent",
+	}
+	start := time.Now()
+	_, err := OpenContext(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("Open() = %v, want connect failure after 3 attempts", err)
+	}
+	// 10ms + 20ms 的退避
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("elapsed = %v, want backoff between attempts", elapsed)
+	}
+}
@@ -0,0 +1,184 @@
+// Package fixtures 造数和测试夹具
+//
+// 每个模型声明一个工厂，描述一条"典型"数据长什么样：
+//
+//	var Users3 = fixtures.Define("user3", func(c *fixtures.Ctx, n int) model.User3 {
+//		age := c.Age()
+//		birthday := c.Birthday(age)
+//		return model.User3{Name: c.Name(), Age: age, Birthday: &birthday}
+//	})
+//
+// 然后在 Seeder 里批量生成，需要特殊数据时用 override 覆盖字段：
+//
+//	s := fixtures.NewSeeder(db, 42)
+//	users, err := Users3.Create(ctx, s, 1000)
+//	kids, err := Users3.Create(ctx, s, 3, func(u *model.User3, i int) { u.Name = fmt.Sprintf("kid%d", i) })
+//
+// 同一个种子、同样的调用顺序，生成的数据完全一样
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// Ctx 工厂函数的上下文：随机数据 + 当前 Seeder 已创建的记录
+type Ctx struct {
+	*Faker
+	s *Seeder
+}
+
+// Pick 从当前 Seeder 已经创建的 T 里随机选一条，用于建立关联（例如订单属于某个用户）
+// 还没有创建过 T 时返回 nil
+func Pick[T any](c *Ctx) *T {
+	list := c.s.created[reflect.TypeFor[T]()]
+	if len(list) == 0 {
+		return nil
+	}
+	return list[c.IntN(len(list))].(*T)
+}
+
+// Nth 当前 Seeder 创建的第 i 条 T（从 1 开始），没有时返回 nil
+// 一对一关联（每个用户一份资料）用工厂的序号取，不会像 Pick 那样重复
+func Nth[T any](c *Ctx, i int) *T {
+	list := c.s.created[reflect.TypeFor[T]()]
+	if i < 1 || i > len(list) {
+		return nil
+	}
+	return list[i-1].(*T)
+}
+
+// All 返回当前 Seeder 已经创建的所有 T
+func All[T any](s *Seeder) []*T {
+	list := s.created[reflect.TypeFor[T]()]
+	out := make([]*T, len(list))
+	for i, v := range list {
+		out[i] = v.(*T)
+	}
+	return out
+}
+
+// Factory 模型 T 的工厂
+type Factory[T any] struct {
+	name string
+	gen  func(c *Ctx, n int) T
+}
+
+// Define 声明工厂，build 的 n 是从 1 开始的序号，每个 Seeder 里每个工厂单独计数
+// 需要唯一值（用户名、邮箱）时用序号拼出来
+func Define[T any](name string, build func(c *Ctx, n int) T) *Factory[T] {
+	return &Factory[T]{name: name, gen: build}
+}
+
+// Name 工厂名
+func (f *Factory[T]) Name() string { return f.name }
+
+// Build 只在内存里生成 n 条，不写数据库
+// overrides 按顺序作用在每条数据上，第二个参数是本批里从 0 开始的下标
+func (f *Factory[T]) Build(s *Seeder, n int, overrides ...func(v *T, i int)) []*T {
+	return f.build(s, 0, n, overrides)
+}
+
+// build 生成下标 [from, to) 的数据，序号接着上一次
+func (f *Factory[T]) build(s *Seeder, from, to int, overrides []func(v *T, i int)) []*T {
+	c := &Ctx{Faker: s.faker, s: s}
+	out := make([]*T, 0, to-from)
+	for i := from; i < to; i++ {
+		s.seq[f.name]++
+		v := f.gen(c, s.seq[f.name])
+		for _, o := range overrides {
+			o(&v, i)
+		}
+		out = append(out, &v)
+	}
+	return out
+}
+
+// Create 生成 n 条并在一个事务里分批写入数据库，所有记录都返回，并留给后面的 Pick/All 使用
+// 校验插件和钩子照常生效，失败时全部回滚
+func (f *Factory[T]) Create(ctx context.Context, s *Seeder, n int, overrides ...func(v *T, i int)) ([]*T, error) {
+	vs := make([]*T, 0, n)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return f.insert(tx, s, n, overrides, func(batch []*T) { vs = append(vs, batch...) })
+	})
+	if err != nil {
+		return nil, err
+	}
+	remember(s, vs, len(vs))
+	return vs, nil
+}
+
+// Insert 和 Create 一样生成 n 条写入数据库，但是一批一批地生成和写入，不返回记录，
+// 内存占用和 n 无关；只保留前 Seeder.Keep 条给后面的 Pick/All 建立关联。seed 命令造大量数据时用
+// 每批单独提交，失败时之前的批已经写入
+func (f *Factory[T]) Insert(ctx context.Context, s *Seeder, n int, overrides ...func(v *T, i int)) error {
+	return f.insert(s.db.WithContext(ctx), s, n, overrides, func(batch []*T) {
+		remember(s, batch, s.Keep-len(s.created[reflect.TypeFor[T]()]))
+	})
+}
+
+// insert 每次生成 BatchSize 条写入，成功后交给 done
+func (f *Factory[T]) insert(db *gorm.DB, s *Seeder, n int, overrides []func(v *T, i int), done func(batch []*T)) error {
+	size := s.BatchSize
+	if size <= 0 {
+		size = n
+	}
+	for from := 0; from < n; from += size {
+		batch := f.build(s, from, min(from+size, n), overrides)
+		if err := db.Create(batch).Error; err != nil {
+			return fmt.Errorf("fixtures: create %d %s: %w", n, f.name, err)
+		}
+		done(batch)
+	}
+	return nil
+}
+
+// One 创建一条，测试里最常用
+func (f *Factory[T]) One(ctx context.Context, s *Seeder, overrides ...func(v *T)) (*T, error) {
+	vs, err := f.Create(ctx, s, 1, func(v *T, _ int) {
+		for _, o := range overrides {
+			o(v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vs[0], nil
+}
+
+// Seeder 一次造数过程：固定种子的随机源、各工厂的序号、已创建的记录
+type Seeder struct {
+	BatchSize int // 每批写入的条数，默认 500
+	Keep      int // Insert 为 Pick/All 保留的每种记录的条数上限，默认 1000
+
+	db      *gorm.DB
+	faker   *Faker
+	seq     map[string]int
+	created map[reflect.Type][]any
+}
+
+// NewSeeder 创建 Seeder
+func NewSeeder(db *gorm.DB, seed uint64) *Seeder {
+	return &Seeder{
+		BatchSize: 500,
+		Keep:      1000,
+		db:        db,
+		faker:     NewFaker(seed),
+		seq:       map[string]int{},
+		created:   map[reflect.Type][]any{},
+	}
+}
+
+// DB 返回 Seeder 使用的数据库
+func (s *Seeder) DB() *gorm.DB { return s.db }
+
+// remember 记下前 n 条已创建的记录给 Pick/All 用
+func remember[T any](s *Seeder, vs []*T, n int) {
+	t := reflect.TypeFor[T]()
+	for _, v := range vs[:max(0, min(n, len(vs)))] {
+		s.created[t] = append(s.created[t], v)
+	}
+}
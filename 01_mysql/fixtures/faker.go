@@ -0,0 +1,67 @@
+package fixtures
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	firstNames = []string{"张", "李", "王", "赵", "孙", "周", "吴", "郑", "冯", "陈"}
+	lastNames  = []string{"三", "四", "五", "六", "七", "八", "九", "十"}
+)
+
+// Faker 生成随机数据，同一个种子总是生成同样的序列
+type Faker struct {
+	r *rand.Rand
+}
+
+// NewFaker 用固定种子创建 Faker，测试和造数都用它保证结果可复现
+func NewFaker(seed uint64) *Faker {
+	return &Faker{r: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+// Random 不固定种子的 Faker，给课程里的 randomName/randomAge 用
+func Random() *Faker {
+	return &Faker{r: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// Name 随机中文名，例如 张三
+func (f *Faker) Name() string {
+	return firstNames[f.r.IntN(len(firstNames))] + lastNames[f.r.IntN(len(lastNames))]
+}
+
+// Age 随机年龄，范围 [18, 80]，满足 User3 的校验规则
+func (f *Faker) Age() int {
+	return f.IntRange(18, 80)
+}
+
+// IntN 返回 [0, n)
+func (f *Faker) IntN(n int) int { return f.r.IntN(n) }
+
+// IntRange 返回 [min, max]
+func (f *Faker) IntRange(min, max int) int { return min + f.r.IntN(max-min+1) }
+
+// Bool 随机布尔值，p 为 true 的概率
+func (f *Faker) Bool(p float64) bool { return f.r.Float64() < p }
+
+// Choice 从列表里随机选一个
+func Choice[E any](f *Faker, list []E) E { return list[f.r.IntN(len(list))] }
+
+// Email 按序号生成不重复的邮箱
+func (f *Faker) Email(seq int) string { return fmt.Sprintf("user%d@example.com", seq) }
+
+// Date 在 [from, to) 之间随机取一个时间，精确到秒
+func (f *Faker) Date(from, to time.Time) time.Time {
+	span := to.Unix() - from.Unix()
+	if span <= 0 {
+		return from
+	}
+	return time.Unix(from.Unix()+f.r.Int64N(span), 0).In(from.Location())
+}
+
+// Birthday 按年龄生成生日，以固定日期为基准而不是 time.Now()，用 UTC 保证不同时区下也可复现
+func (f *Faker) Birthday(age int) time.Time {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(-age-1, 0, 0)
+	return f.Date(base, base.AddDate(1, 0, 0))
+}
@@ -0,0 +1,188 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/migrations"
+	"mysql-demo/model"
+	"mysql-demo/validate"
+)
+
+// openDB 迁移过的内存 SQLite，没有夹具；fixturestest 依赖这个包，这里不能用它
+func openDB(t *testing.T) (*gorm.DB, *Seeder) {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.Use(validate.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db, NewSeeder(db, DefaultSeed)
+}
+
+// 测试关联用的模型
+type author struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Posts []post
+}
+
+type post struct {
+	ID       uint
+	AuthorID uint
+	Title    string
+}
+
+var (
+	authors = Define("author", func(c *Ctx, n int) author {
+		return author{Email: c.Email(n)}
+	})
+	posts = Define("post", func(c *Ctx, n int) post {
+		a := Pick[author](c)
+		return post{AuthorID: a.ID, Title: fmt.Sprintf("%s 的第 %d 篇", c.Name(), n)}
+	})
+)
+
+func TestDeterministic(t *testing.T) {
+	build := func(seed uint64) []*model.User3 {
+		return Users3.Build(NewSeeder(nil, seed), 20)
+	}
+	a, b, c := build(1), build(1), build(2)
+	same := true
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Age != b[i].Age || !a[i].Birthday.Equal(*b[i].Birthday) {
+			t.Fatalf("row %d differs with same seed: %+v vs %+v", i, a[i], b[i])
+		}
+		same = same && a[i].Name == c[i].Name && a[i].Age == c[i].Age
+	}
+	if same {
+		t.Error("different seeds produced the same data")
+	}
+	for _, u := range a {
+		if err := validate.Struct(u); err != nil {
+			t.Errorf("factory produced invalid user: %v", err)
+		}
+	}
+}
+
+func TestSequencesOverridesRelations(t *testing.T) {
+	ctx := context.Background()
+	db, s := openDB(t)
+	if err := db.AutoMigrate(&author{}, &post{}); err != nil {
+		t.Fatal(err)
+	}
+	s.BatchSize = 4
+
+	// 序号在同一个 Seeder 里连续，邮箱不会重复
+	if _, err := authors.Create(ctx, s, 3); err != nil {
+		t.Fatal(err)
+	}
+	more, err := authors.Create(ctx, s, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more[1].Email != "user5@example.com" {
+		t.Errorf("sequence email = %s", more[1].Email)
+	}
+
+	// 关联：每篇文章随机属于一个已创建的作者
+	ps, err := posts.Create(ctx, s, 10, func(p *post, i int) {
+		if i == 0 {
+			p.Title = "置顶"
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps[0].Title != "置顶" {
+		t.Errorf("override = %q", ps[0].Title)
+	}
+	var orphans int64
+	db.Model(&post{}).Where("author_id NOT IN (?)", db.Model(&author{}).Select("id")).Count(&orphans)
+	if orphans != 0 || len(All[author](s)) != 5 {
+		t.Errorf("orphans = %d, authors = %d", orphans, len(All[author](s)))
+	}
+
+	// 分 3 批（4+4+2）写入
+	var n int64
+	db.Model(&post{}).Count(&n)
+	if n != 10 {
+		t.Errorf("posts = %d", n)
+	}
+
+	// 校验插件照常生效，失败时整批回滚
+	_, err = Users3.Create(ctx, s, 6, func(u *model.User3, i int) {
+		if i == 5 {
+			u.Age = 5
+		}
+	})
+	if _, ok := validate.As(err); !ok {
+		t.Errorf("invalid override = %v", err)
+	}
+	db.Model(&model.User3{}).Count(&n)
+	if n != 0 {
+		t.Errorf("user3 after failed batch = %d", n)
+	}
+
+	u, err := Users3.One(ctx, s, func(u *model.User3) { u.Name = "指定" })
+	if err != nil || u.ID == 0 || u.Name != "指定" {
+		t.Errorf("One() = %+v, %v", u, err)
+	}
+
+	// 一对一：每份资料挂在不同的用户上，不会撞上 user_id 的唯一索引
+	if _, err := Users3.Create(ctx, s, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Profiles.Create(ctx, s, 5); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&model.Profile{}).Distinct("user_id").Count(&n)
+	if n != 5 {
+		t.Errorf("profiles belong to %d users, want 5", n)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, s := openDB(t)
+	s.BatchSize, s.Keep = 7, 10
+
+	// 分批写入，只保留前 Keep 条给关联用
+	if err := Users3.Insert(ctx, s, 50); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	db.Model(&model.User3{}).Count(&n)
+	kept := All[model.User3](s)
+	if n != 50 || len(kept) != 10 || kept[9].ID != 10 {
+		t.Errorf("user3 = %d, kept = %d", n, len(kept))
+	}
+	if _, err := Orders.Create(ctx, s, 5); err != nil {
+		t.Fatal(err)
+	}
+	var linked int64
+	db.Model(&model.Order{}).Where("user_id BETWEEN 1 AND 10").Count(&linked)
+	if linked != 5 {
+		t.Errorf("orders on kept users = %d", linked)
+	}
+
+	// 和 Create 生成同样的数据
+	_, s2 := openDB(t)
+	vs, _ := Users3.Create(ctx, s2, 50)
+	var last model.User3
+	db.Last(&last)
+	if last.Name != vs[49].Name || last.Age != vs[49].Age {
+		t.Errorf("Insert row 50 = %+v, Create = %+v", last, vs[49])
+	}
+}
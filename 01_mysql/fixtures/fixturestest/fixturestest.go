@@ -0,0 +1,40 @@
+// Package fixturestest 测试用的数据库，单独成包，seed 命令等非测试代码不会链接 testing
+package fixturestest
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/fixtures"
+	"mysql-demo/migrations"
+	"mysql-demo/validate"
+)
+
+// Open 给测试用的全新内存 SQLite：执行全部迁移、注册校验插件、按 fixtures.DefaultSeed 加载夹具
+// 测试结束时自动关闭
+//
+//	db, s := fixturestest.Open(t, "users", "edge_ages")
+func Open(tb testing.TB, names ...string) (*gorm.DB, *fixtures.Seeder) {
+	tb.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Use(validate.Plugin{}); err != nil {
+		tb.Fatal(err)
+	}
+	if err := migrations.Up(db); err != nil {
+		tb.Fatal(err)
+	}
+	s := fixtures.NewSeeder(db, fixtures.DefaultSeed)
+	if err := fixtures.Load(context.Background(), s, names...); err != nil {
+		tb.Fatal(err)
+	}
+	return db, s
+}
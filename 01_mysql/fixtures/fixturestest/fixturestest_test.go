@@ -0,0 +1,45 @@
+package fixturestest
+
+import (
+	"context"
+	"testing"
+
+	"mysql-demo/fixtures"
+	"mysql-demo/model"
+)
+
+func TestOpen(t *testing.T) {
+	db, _ := Open(t, "users", "edge_ages", "02_user")
+	var total, active, users int64
+	db.Unscoped().Model(&model.User3{}).Count(&total)
+	db.Model(&model.User3{}).Count(&active)
+	db.Model(&model.User{}).Count(&users)
+	if total != 53 || active != 52 || users != 5 {
+		t.Errorf("user3 total = %d, active = %d, 02_user = %d", total, active, users)
+	}
+
+	// 同样的夹具在另一个库里生成同样的数据
+	db2, _ := Open(t, "users")
+	var a, b []model.User3
+	db.Order("id").Limit(50).Find(&a)
+	db2.Order("id").Find(&b)
+	for i := range b {
+		if a[i].Name != b[i].Name || a[i].Age != b[i].Age {
+			t.Fatalf("row %d differs between databases", i)
+		}
+	}
+
+	// 关联夹具：订单挂在已创建的用户上，每个用户一份资料
+	db3, _ := Open(t, "relations")
+	var profiles, orders, links int64
+	db3.Model(&model.Profile{}).Count(&profiles)
+	db3.Model(&model.Order{}).Where("user_id IN (SELECT id FROM user3)").Count(&orders)
+	db3.Table("user3_roles").Count(&links)
+	if profiles != 10 || orders != 30 || links != 10 {
+		t.Errorf("profiles = %d, orders = %d, role links = %d", profiles, orders, links)
+	}
+
+	if err := fixtures.Load(context.Background(), fixtures.NewSeeder(db, 1), "nope"); err == nil {
+		t.Error("Load() with unknown set should fail")
+	}
+}
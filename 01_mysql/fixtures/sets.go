@@ -0,0 +1,145 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"mysql-demo/model"
+)
+
+// DefaultSeed 测试和 seed 命令默认的随机种子
+const DefaultSeed = 42
+
+// 课程模型的工厂
+var (
+	Users = Define("02_user", func(c *Ctx, n int) model.User {
+		return model.User{Name: c.Name()}
+	})
+
+	Users3 = Define("user3", func(c *Ctx, n int) model.User3 {
+		age := c.Age()
+		birthday := c.Birthday(age)
+		return model.User3{Name: c.Name(), Age: age, Birthday: &birthday}
+	})
+
+	// Profiles 一个用户只能有一份，没有指定用户时第 n 份挂在第 n 个已创建的 User3 上
+	Profiles = Define("user3_profiles", func(c *Ctx, n int) model.Profile {
+		p := model.Profile{Bio: fmt.Sprintf("第 %d 位用户的简介", n), Avatar: fmt.Sprintf("https://example.com/avatar/%d.png", n)}
+		if u := Nth[model.User3](c, n); u != nil {
+			p.UserID = u.ID
+		}
+		return p
+	})
+
+	Orders = Define("user3_orders", func(c *Ctx, n int) model.Order {
+		o := model.Order{
+			No:     fmt.Sprintf("NO%08d", n),
+			Amount: int64(c.IntRange(100, 100000)),
+			Status: Choice(c.Faker, []string{model.OrderPending, model.OrderPaid, model.OrderCanceled}),
+		}
+		if u := Pick[model.User3](c); u != nil {
+			o.UserID = u.ID
+		}
+		return o
+	})
+
+	Roles = Define("roles", func(c *Ctx, n int) model.Role {
+		return model.Role{Name: fmt.Sprintf("role%d", n)}
+	})
+)
+
+// Counts 按表名造指定数量的数据，给 seed 命令用
+var Counts = map[string]func(ctx context.Context, s *Seeder, n int) error{
+	Users.Name(): func(ctx context.Context, s *Seeder, n int) error {
+		return Users.Insert(ctx, s, n)
+	},
+	Users3.Name(): func(ctx context.Context, s *Seeder, n int) error {
+		return Users3.Insert(ctx, s, n)
+	},
+}
+
+// Set 一组命名的夹具
+type Set func(ctx context.Context, s *Seeder) error
+
+var sets = map[string]Set{
+	// users 查询课程用的一批用户，年龄分布随机
+	"users": func(ctx context.Context, s *Seeder) error {
+		_, err := Users3.Create(ctx, s, 50)
+		return err
+	},
+	// edge_ages 校验规则的边界：18 岁和 150 岁各一个，另有一个软删除的用户
+	"edge_ages": func(ctx context.Context, s *Seeder) error {
+		ages := []int{18, 150, 30}
+		vs, err := Users3.Create(ctx, s, len(ages), func(u *model.User3, i int) {
+			u.Age = ages[i]
+			u.Name = fmt.Sprintf("边界%d", ages[i])
+		})
+		if err != nil {
+			return err
+		}
+		return s.DB().WithContext(ctx).Delete(vs[2]).Error
+	},
+	// relations 关联的例子：每个用户一份资料、随机几个订单和角色
+	"relations": func(ctx context.Context, s *Seeder) error {
+		users, err := Users3.Create(ctx, s, 10)
+		if err != nil {
+			return err
+		}
+		if _, err := Profiles.Create(ctx, s, len(users), func(p *model.Profile, i int) { p.UserID = users[i].ID }); err != nil {
+			return err
+		}
+		if _, err := Orders.Create(ctx, s, 30); err != nil {
+			return err
+		}
+		names := []string{"admin", "editor", "viewer"}
+		roles, err := Roles.Create(ctx, s, len(names), func(r *model.Role, i int) { r.Name = names[i] })
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			picked := []*model.Role{Choice(s.faker, roles)}
+			if err := s.DB().WithContext(ctx).Model(u).Association("Roles").Append(picked); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 02_user 连接数据库那一课的用户表
+	"02_user": func(ctx context.Context, s *Seeder) error {
+		_, err := Users.Create(ctx, s, 5)
+		return err
+	},
+}
+
+// Register 注册命名夹具，重名会 panic，一般在 init 里调用
+func Register(name string, set Set) {
+	if _, ok := sets[name]; ok {
+		panic("fixtures: duplicate set " + name)
+	}
+	sets[name] = set
+}
+
+// Names 所有已注册的夹具名
+func Names() []string {
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load 按顺序加载命名夹具
+func Load(ctx context.Context, s *Seeder, names ...string) error {
+	for _, name := range names {
+		set, ok := sets[name]
+		if !ok {
+			return fmt.Errorf("fixtures: unknown set %q, have %v", name, Names())
+		}
+		if err := set(ctx, s); err != nil {
+			return fmt.Errorf("fixtures: load %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+)
+
+// user 与课程里的 User3 一致，多了版本号
+type user struct {
+	gorm.Model
+	Name     string
+	Age      int
+	Birthday *time.Time
+	Version  Version `gorm:"not null;default:1"`
+}
+
+func (user) TableName() string { return "user3" }
+
+// job Claim 用的任务表
+type job struct {
+	ID     uint
+	Done   bool
+	Worker int
+}
+
+const workers = 8
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return setup(t, database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+}
+
+// openShared 并发测试用的库，多个连接之间真的会互相等待：
+// 设置了 DB_DRIVER=mysql 时连 MySQL，行锁由 FOR UPDATE / SKIP LOCKED 提供；
+// 否则用临时目录里的 sqlite 文件库，sqlite 没有行锁，_txlock=immediate 让事务一开始就拿到写锁，
+// 效果相当于锁住整个库，能检查读和写是不是在同一个事务里
+func openShared(t *testing.T) *gorm.DB {
+	t.Helper()
+	if os.Getenv("DB_DRIVER") == database.DriverMySQL {
+		cfg, err := database.Load("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.LogLevel = "silent"
+		db := setup(t, cfg)
+		if err := db.Exec("DELETE FROM jobs").Error; err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	dsn := filepath.Join(t.TempDir(), "lock.db") + "?_txlock=immediate"
+	return setup(t, database.Config{Driver: database.DriverSQLite, DSN: dsn, MaxOpenConns: workers, LogLevel: "silent"})
+}
+
+func setup(t *testing.T, cfg database.Config) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}, &job{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func create(t *testing.T, db *gorm.DB) user {
+	t.Helper()
+	u := user{Name: "张三", Age: 20}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Version != 1 {
+		t.Fatalf("version after create = %d, want 1", u.Version)
+	}
+	return u
+}
+
+func load(t *testing.T, db *gorm.DB, id uint) user {
+	t.Helper()
+	var u user
+	if err := db.First(&u, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// 所有 goroutine 先读到同一个版本再同时更新，只有一个能成功
+func TestOptimisticRace(t *testing.T) {
+	db := openDB(t)
+	u := create(t, db)
+
+	var (
+		loaded, wg sync.WaitGroup
+		start      = make(chan struct{})
+		ok, stale  atomic.Int32
+	)
+	loaded.Add(workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var mine user
+			err := db.First(&mine, u.ID).Error
+			loaded.Done()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			<-start
+			switch err := db.Model(&mine).Update("age", 100+i).Error; {
+			case err == nil:
+				ok.Add(1)
+			case errors.Is(err, ErrStaleObject):
+				// 失败时内存里的版本号不变
+				if mine.Version != 1 {
+					t.Errorf("version after stale update = %d, want 1", mine.Version)
+				}
+				stale.Add(1)
+			default:
+				t.Error(err)
+			}
+		}(i)
+	}
+	loaded.Wait()
+	close(start)
+	wg.Wait()
+
+	if ok.Load() != 1 || stale.Load() != workers-1 {
+		t.Errorf("ok = %d, stale = %d, want 1 and %d", ok.Load(), stale.Load(), workers-1)
+	}
+	if got := load(t, db, u.ID); got.Version != 2 || got.Age < 100 {
+		t.Errorf("after race = age %d version %d", got.Age, got.Version)
+	}
+}
+
+// 冲突后重新读取再更新，所有的自增都不会丢
+func TestOptimisticRetry(t *testing.T) {
+	db := openDB(t)
+	u := create(t, db)
+
+	const rounds = 5
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for {
+					var mine user
+					if err := db.First(&mine, u.ID).Error; err != nil {
+						t.Error(err)
+						return
+					}
+					mine.Age++
+					err := db.Save(&mine).Error
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrStaleObject) {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := load(t, db, u.ID)
+	if got.Age != 20+workers*rounds || got.Version != Version(1+workers*rounds) {
+		t.Errorf("age = %d, version = %d, want %d and %d", got.Age, got.Version, 20+workers*rounds, 1+workers*rounds)
+	}
+}
+
+func TestStaleWrites(t *testing.T) {
+	db := openDB(t)
+	u := create(t, db)
+	old := u
+
+	if err := db.Model(&u).Updates(user{Name: "李四"}).Error; err != nil || u.Version != 2 {
+		t.Fatalf("Updates() = %v, version = %d", err, u.Version)
+	}
+	// Select 了别的字段，版本号照样写入
+	if err := db.Model(&u).Select("Age").Updates(user{Age: 0}).Error; err != nil || u.Version != 3 {
+		t.Fatalf("Select().Updates() = %v, version = %d", err, u.Version)
+	}
+
+	// 旧版本的 Save 不能退化成 upsert 覆盖新数据
+	old.Name = "王五"
+	if err := db.Save(&old).Error; !errors.Is(err, ErrStaleObject) {
+		t.Errorf("stale Save() = %v, want ErrStaleObject", err)
+	}
+	if err := db.Model(&old).Updates(map[string]any{"age": 1}).Error; !errors.Is(err, ErrStaleObject) {
+		t.Errorf("stale Updates() = %v, want ErrStaleObject", err)
+	}
+	if got := load(t, db, u.ID); got.Name != "李四" || got.Age != 0 || got.Version != 3 {
+		t.Errorf("row = %s age %d version %d", got.Name, got.Age, got.Version)
+	}
+
+	// 没有当前版本号的批量更新让版本号自增，之前读到的都会失效
+	if err := db.Model(&user{}).Where("name = ?", "李四").Update("age", 40).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&u).Update("age", 50).Error; !errors.Is(err, ErrStaleObject) {
+		t.Errorf("update after batch bump = %v, want ErrStaleObject", err)
+	}
+	if got := load(t, db, u.ID); got.Age != 40 || got.Version != 4 {
+		t.Errorf("after batch update age = %d, version = %d", got.Age, got.Version)
+	}
+
+	// 已经删除的行同样视为过期
+	cur := load(t, db, u.ID)
+	db.Delete(&user{}, u.ID)
+	if err := db.Model(&cur).Update("age", 60).Error; !errors.Is(err, ErrStaleObject) {
+		t.Errorf("update deleted row = %v, want ErrStaleObject", err)
+	}
+}
+
+// 加行锁后在事务里读-改-写，不会丢失更新
+func TestWithRow(t *testing.T) {
+	db := openShared(t)
+	u := create(t, db)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithRow(context.Background(), db, u.ID, func(tx *gorm.DB, v *user) error {
+				time.Sleep(5 * time.Millisecond) // 拉长读和写之间的窗口，没有锁住时别的 worker 会读到旧值
+				return tx.Model(v).Update("age", v.Age+1).Error
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := load(t, db, u.ID); got.Age != 20+workers {
+		t.Errorf("age = %d, want %d", got.Age, 20+workers)
+	}
+	err := WithRow(context.Background(), db, u.ID+1000, func(*gorm.DB, *user) error { return nil })
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("WithRow(missing) = %v", err)
+	}
+	// fn 返回错误时回滚
+	boom := errors.New("boom")
+	err = WithRow(context.Background(), db, u.ID, func(tx *gorm.DB, v *user) error {
+		tx.Model(v).Update("age", 0)
+		return boom
+	})
+	if !errors.Is(err, boom) || load(t, db, u.ID).Age != 20+workers {
+		t.Errorf("WithRow(rollback) = %v", err)
+	}
+}
+
+// 多个 worker 抢任务，每个任务恰好处理一次
+func TestClaim(t *testing.T) {
+	db := openShared(t)
+	jobs := make([]job, 50)
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	pending := func(tx *gorm.DB) *gorm.DB { return tx.Where("done = ?", false).Order("id") }
+
+	var wg sync.WaitGroup
+	for w := 1; w <= workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				n, err := Claim(context.Background(), db, 3, pending, func(tx *gorm.DB, rows []job) error {
+					for _, j := range rows {
+						if j.Done {
+							t.Errorf("job %d claimed twice", j.ID)
+						}
+						if err := tx.Model(&j).Updates(map[string]any{"done": true, "worker": w}).Error; err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if n == 0 {
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var left int64
+	db.Model(&job{}).Where("done = ? OR worker = 0", false).Count(&left)
+	if left != 0 {
+		t.Errorf("%d jobs not processed", left)
+	}
+}
+
+// dryPool 给 DryRun 用的连接：gorm 的事务需要 Begin/Commit，SQL 只生成不执行
+type dryPool struct{ gorm.ConnPool }
+
+func (p *dryPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryTx{p.ConnPool}, nil
+}
+
+type dryTx struct{ gorm.ConnPool }
+
+func (*dryTx) Commit() error   { return nil }
+func (*dryTx) Rollback() error { return nil }
+
+// sqlite 会丢掉锁子句，用 MySQL 方言 DryRun 检查生成的 SQL
+func TestLockingSQL(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: &dryPool{}, SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sqls []string
+	db.Callback().Query().After("gorm:query").Register("test:sql", func(tx *gorm.DB) {
+		sqls = append(sqls, tx.Statement.SQL.String())
+	})
+
+	ctx := context.Background()
+	if err := WithRow(ctx, db, 1, func(*gorm.DB, *user) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	pending := func(tx *gorm.DB) *gorm.DB { return tx.Where("done = ?", false) }
+	if _, err := Claim(ctx, db, 3, pending, func(*gorm.DB, []job) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for _, scope := range []func(*gorm.DB) *gorm.DB{ForShare, NoWait} {
+		db.Scopes(scope).Find(&[]job{})
+	}
+
+	for i, want := range []string{"FOR UPDATE", "FOR UPDATE SKIP LOCKED", "FOR SHARE", "FOR UPDATE NOWAIT"} {
+		if i >= len(sqls) || !strings.HasSuffix(sqls[i], want) {
+			t.Errorf("query %d = %q, want suffix %q", i, sqls, want)
+		}
+	}
+}
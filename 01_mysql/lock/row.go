@@ -0,0 +1,75 @@
+package lock
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 下面几个函数是 gorm 的 Scope，用法：tx.Scopes(lock.ForUpdate).First(&u, id)
+// 行锁只在事务里有意义，事务提交或回滚时释放；sqlite 没有行锁，gorm 会忽略这些子句
+
+// ForUpdate 排他锁：SELECT ... FOR UPDATE，其他事务不能再加锁、修改或删除这些行
+func ForUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate})
+}
+
+// ForShare 共享锁：SELECT ... FOR SHARE，其他事务可以读、可以加共享锁，但不能修改
+func ForShare(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: clause.LockingStrengthShare})
+}
+
+// SkipLocked 排他锁并跳过已经被别人锁住的行：SELECT ... FOR UPDATE SKIP LOCKED
+// 多个 worker 从同一张表里抢任务时，互相不会等待，也不会拿到同一行
+func SkipLocked(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked})
+}
+
+// NoWait 排他锁，行已经被锁住时立即报错而不是等待：SELECT ... FOR UPDATE NOWAIT
+func NoWait(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsNoWait})
+}
+
+// WithRow 在事务里按主键锁定一行（FOR UPDATE），再调用 fn 修改，fn 返回后提交
+// 从读取到写回期间其他事务不能修改这一行，适合“读出来、算一下、写回去”的场景
+// 记录不存在时返回 gorm.ErrRecordNotFound；db 已经在事务里时使用 savepoint
+//
+//	lock.WithRow(ctx, db, id, func(tx *gorm.DB, u *User3) error {
+//		return tx.Model(u).Update("age", u.Age+1)
+//	})
+func WithRow[T any](ctx context.Context, db *gorm.DB, id any, fn func(tx *gorm.DB, v *T) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		v := new(T)
+		if err := tx.Scopes(ForUpdate).Take(v, id).Error; err != nil {
+			return err
+		}
+		return fn(tx, v)
+	})
+}
+
+// Claim 在事务里领取最多 n 行其他事务没有锁住的记录（FOR UPDATE SKIP LOCKED），交给 fn 处理
+// scope 用来加条件和排序，例如只领取未处理的任务；fn 里需要把记录标记为已处理，
+// 否则事务提交后锁释放，这些行会被再次领取
+// 返回领取到的行数，为 0 时表示暂时没有可领取的记录
+func Claim[T any](ctx context.Context, db *gorm.DB, n int, scope func(*gorm.DB) *gorm.DB, fn func(tx *gorm.DB, rows []T) error) (int, error) {
+	var claimed int
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(new(T)).Scopes(SkipLocked).Limit(n)
+		if scope != nil {
+			q = q.Scopes(scope)
+		}
+		var rows []T
+		if err := q.Find(&rows).Error; err != nil {
+			return err
+		}
+		if claimed = len(rows); claimed == 0 {
+			return nil
+		}
+		return fn(tx, rows)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return claimed, nil
+}
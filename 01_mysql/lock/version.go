@@ -0,0 +1,178 @@
+// Package lock 并发更新同一行时的两种保护：乐观锁（版本号）和行锁（SELECT ... FOR UPDATE）
+//
+// 乐观锁：模型加一个 lock.Version 字段并注册插件，之后按主键的更新都会带上版本号条件，
+// 别人先改过这一行时返回 ErrStaleObject，而不是悄悄覆盖别人的修改
+//
+//	type User3 struct {
+//		gorm.Model
+//		Name    string
+//		Version lock.Version
+//	}
+//
+//	db.Use(lock.Plugin{})
+//	db.Model(&u).Update("age", 30)
+//	// UPDATE user3 SET age=30, version=3 WHERE id = 1 AND version = 2
+//
+// 行锁见 ForUpdate、WithRow 和 Claim
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleObject 更新时版本号对不上：读出来之后这一行已经被别人改过（或删除）了
+// 重新读取后再更新，或者把冲突交给调用方处理
+var ErrStaleObject = errors.New("lock: stale object")
+
+// Version 乐观锁版本号，创建时为 1，每次更新加 1
+type Version int64
+
+var versionType = reflect.TypeOf(Version(0))
+
+// Plugin 乐观锁插件，只对有 Version 字段的模型生效
+//
+//   - 创建：版本号为 0 时设置为 1
+//   - 更新：db.Model(&u) / db.Save(&u) 这类带着当前版本号的更新，条件里加 version = 当前版本，
+//     同时把版本号加 1；没有更新到任何行时返回 ErrStaleObject，并把内存里的版本号还原
+//   - 没有当前版本号的批量更新（db.Model(&User3{}).Where(...).Updates(map)）不做检查，
+//     只让版本号自增，使其他人手里的旧版本失效；struct 形式的批量更新不会改版本号
+type Plugin struct{}
+
+func (Plugin) Name() string { return "lock" }
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("lock:create", initVersion),
+		cb.Update().Before("gorm:update").Register("lock:before_update", beforeUpdate),
+		cb.Update().After("gorm:update").Register("lock:after_update", afterUpdate),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const versionKey = "lock:version"
+
+// versionField 模型里 Version 类型的字段
+func versionField(s *schema.Schema) *schema.Field {
+	if s == nil {
+		return nil
+	}
+	for _, f := range s.Fields {
+		if f.FieldType == versionType && f.DBName != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+func initVersion(db *gorm.DB) {
+	stmt := db.Statement
+	f := versionField(stmt.Schema)
+	if db.Error != nil || f == nil {
+		return
+	}
+	switch d := stmt.Dest.(type) {
+	case map[string]any:
+		initMap(f, d)
+		return
+	case []map[string]any:
+		for _, m := range d {
+			initMap(f, m)
+		}
+		return
+	}
+	set := func(rv reflect.Value) {
+		if _, zero := f.ValueOf(stmt.Context, rv); zero {
+			db.AddError(f.Set(stmt.Context, rv, Version(1)))
+		}
+	}
+	switch rv := stmt.ReflectValue; rv.Kind() {
+	case reflect.Struct:
+		set(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			set(reflect.Indirect(rv.Index(i)))
+		}
+	}
+}
+
+func initMap(f *schema.Field, m map[string]any) {
+	if _, ok := m[f.DBName]; ok {
+		return
+	}
+	if _, ok := m[f.Name]; ok {
+		return
+	}
+	m[f.DBName] = Version(1)
+}
+
+func beforeUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	f := versionField(stmt.Schema)
+	if db.Error != nil || f == nil || stmt.ReflectValue.Kind() != reflect.Struct {
+		return
+	}
+	cur, zero := f.ValueOf(stmt.Context, stmt.ReflectValue)
+	if zero {
+		if m, ok := stmt.Dest.(map[string]any); ok {
+			m[f.DBName] = gorm.Expr("? + 1", clause.Column{Name: f.DBName})
+			selectColumn(stmt, f)
+		}
+		return
+	}
+
+	v := cur.(Version)
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: v},
+	}})
+	stmt.SetColumn(f.DBName, v+1, true)
+	selectColumn(stmt, f)
+	stmt.Settings.Store(versionKey, v)
+}
+
+// selectColumn 用了 Select 时把版本号也加进去，否则 gorm 不会写这一列
+func selectColumn(stmt *gorm.Statement, f *schema.Field) {
+	if len(stmt.Selects) == 0 {
+		return
+	}
+	for _, s := range stmt.Selects {
+		if s == "*" || s == f.Name || s == f.DBName {
+			return
+		}
+	}
+	stmt.Selects = append(stmt.Selects, f.DBName)
+}
+
+func afterUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	v, ok := stmt.Settings.LoadAndDelete(versionKey)
+	if !ok {
+		return
+	}
+	if db.Error == nil && db.RowsAffected > 0 {
+		return
+	}
+	// 更新失败，内存里的版本号还原成更新前的值，方便调用方判断和重试
+	f := versionField(stmt.Schema)
+	f.Set(stmt.Context, stmt.ReflectValue, v)
+	if dv := reflect.Indirect(reflect.ValueOf(stmt.Dest)); dv.Kind() == reflect.Struct && dv.CanAddr() && dv.Type() == stmt.Schema.ModelType {
+		f.Set(stmt.Context, dv, v)
+	}
+	if db.Error == nil {
+		var id any
+		if pk := stmt.Schema.PrioritizedPrimaryField; pk != nil {
+			id, _ = pk.ValueOf(stmt.Context, stmt.ReflectValue)
+		}
+		db.AddError(fmt.Errorf("%w: %s id=%v version=%d", ErrStaleObject, stmt.Table, id, v))
+	}
+}
@@ -0,0 +1,254 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+var testFS = fstest.MapFS{
+	"sql/0001_create_items.up.sql":         {Data: []byte("CREATE TABLE items (id integer PRIMARY KEY, name text);")},
+	"sql/0001_create_items.mysql.up.sql":   {Data: []byte("CREATE TABLE items (id bigint PRIMARY KEY, name longtext);")},
+	"sql/0001_create_items.down.sql":       {Data: []byte("DROP TABLE items;")},
+	"sql/0002_seed_items.up.sql":           {Data: []byte("-- 分号在字符串里不能拆开\nINSERT INTO items VALUES (1, 'a;b');\nINSERT INTO items VALUES (2, 'it''s');")},
+	"sql/0002_seed_items.down.sql":         {Data: []byte("DELETE FROM items;")},
+	"sql/0004_create_tags.sqlite.up.sql":   {Data: []byte("CREATE TABLE tags (id integer PRIMARY KEY);")},
+	"sql/0004_create_tags.sqlite.down.sql": {Data: []byte("DROP TABLE tags;")},
+}
+
+func testMigrations(t *testing.T, driver string) []Migration {
+	t.Helper()
+	list, err := LoadFS(testFS, "sql", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(list, Go(3, "index_items_name",
+		func(tx *gorm.DB) error { return tx.Exec("CREATE INDEX idx_items_name ON items(name)").Error },
+		func(tx *gorm.DB) error { return tx.Exec("DROP INDEX idx_items_name").Error },
+	))
+}
+
+func versions(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+	list, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vs []int64
+	for _, s := range list {
+		if s.Applied {
+			vs = append(vs, s.Version)
+		}
+	}
+	return vs
+}
+
+func TestLoadFS(t *testing.T) {
+	list, err := LoadFS(testFS, "sql", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0004 只有 sqlite 版本
+	if len(list) != 2 || !strings.Contains(list[0].UpSQL, "bigint") || list[0].DownSQL != "DROP TABLE items;" {
+		t.Errorf("mysql migrations = %+v", list)
+	}
+
+	bad := fstest.MapFS{"0001-create.up.sql": {Data: []byte("x")}}
+	if _, err := LoadFS(bad, ".", "sqlite"); err == nil {
+		t.Error("LoadFS() with bad file name should fail")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := SplitStatements("CREATE TABLE a (x text DEFAULT ';'); -- c;\n/* d; */ INSERT INTO a VALUES (\"q\\\";\");\n")
+	if len(got) != 2 || got[0] != "CREATE TABLE a (x text DEFAULT ';')" || !strings.HasSuffix(got[1], `("q\";")`) {
+		t.Errorf("SplitStatements() = %q", got)
+	}
+}
+
+func TestUpDownTo(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	var out bytes.Buffer
+	m, err := New(db, testMigrations(t, "sqlite"), Options{Out: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(t, m); len(got) != 4 || got[3] != 4 {
+		t.Fatalf("applied after up = %v", got)
+	}
+	var n int64
+	db.Table("items").Count(&n)
+	if n != 2 {
+		t.Errorf("items = %d, want 2", n)
+	}
+	if !db.Migrator().HasIndex("items", "idx_items_name") {
+		t.Error("go migration did not create index")
+	}
+	// 再执行一次没有变化
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(t, m); len(got) != 2 || got[1] != 2 || db.Migrator().HasTable("tags") {
+		t.Fatalf("applied after down 2 = %v", got)
+	}
+
+	if err := m.To(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(t, m); len(got) != 3 || got[2] != 3 {
+		t.Fatalf("applied after to 3 = %v", got)
+	}
+	if cur, _ := m.Current(ctx); cur != 3 {
+		t.Errorf("Current() = %d, want 3", cur)
+	}
+
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(t, m); len(got) != 0 || db.Migrator().HasTable("items") {
+		t.Fatalf("applied after to 0 = %v", got)
+	}
+	if err := m.To(ctx, 9); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To(9) = %v, want ErrUnknownVersion", err)
+	}
+	if !strings.Contains(out.String(), "up 0003_index_items_name") {
+		t.Errorf("progress output = %q", out.String())
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	list := []Migration{{Version: 1, Name: "broken", UpSQL: "CREATE TABLE a (id integer); INSERT INTO missing VALUES (1);"}}
+	m, _ := New(db, list, Options{})
+	if err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "0001_broken up") {
+		t.Fatalf("Up() = %v, want error", err)
+	}
+	// sqlite 的 DDL 也在事务里，建表会一起回滚
+	if db.Migrator().HasTable("a") || len(versions(t, m)) != 0 {
+		t.Error("failed migration left changes behind")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	list := testMigrations(t, "sqlite")
+	m, _ := New(db, list, Options{})
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只改空白不算修改
+	list[1].UpSQL = strings.ReplaceAll(list[1].UpSQL, "\n", "  \r\n") + "\n"
+	m, _ = New(db, list, Options{})
+	if err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("whitespace change treated as drift: %v", err)
+	}
+
+	list[0].UpSQL += " -- changed"
+	m, _ = New(db, list, Options{})
+	if err := m.Up(ctx); !errors.Is(err, ErrDrift) {
+		t.Fatalf("Up() = %v, want ErrDrift", err)
+	}
+	st, _ := m.Status(ctx)
+	if !st[0].Drift || st[1].Drift {
+		t.Errorf("status = %+v", st)
+	}
+
+	var out bytes.Buffer
+	m, _ = New(db, list, Options{Out: &out, AllowDrift: true})
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "warning: 0001_create_items") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestIrreversibleAndOrphan(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m, _ := New(db, []Migration{
+		{Version: 1, Name: "a", UpSQL: "CREATE TABLE a (id integer);"},
+		{Version: 2, Name: "b", UpSQL: "CREATE TABLE b (id integer);", DownSQL: "DROP TABLE b;"},
+	}, Options{})
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 2); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Down(2) = %v, want ErrIrreversible", err)
+	}
+	// 有一步不能回滚时一步都不执行
+	if got := versions(t, m); len(got) != 2 {
+		t.Errorf("applied after failed down = %v", got)
+	}
+
+	// 代码里删掉了版本 2
+	m, _ = New(db, []Migration{{Version: 1, Name: "a", UpSQL: "CREATE TABLE a (id integer);"}}, Options{})
+	st, _ := m.Status(ctx)
+	if len(st) != 2 || !st[1].Orphan {
+		t.Errorf("status = %+v", st)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Errorf("Up() with orphan = %v", err)
+	}
+	if err := m.To(ctx, 1); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To(1) = %v, want ErrUnknownVersion for orphan", err)
+	}
+
+	if _, err := New(db, []Migration{{Version: 1, UpSQL: "x"}, {Version: 1, UpSQL: "y"}}, Options{}); err == nil {
+		t.Error("New() with duplicate versions should fail")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	var out bytes.Buffer
+	m, _ := New(db, testMigrations(t, "sqlite"), Options{Out: &out, DryRun: true})
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"-- 0001_create_items (up)\nCREATE TABLE items",
+		"INSERT INTO items VALUES (1, 'a;b');",
+		"-- 0003_index_items_name (up)\nCREATE INDEX idx_items_name ON items(name);",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dry run output missing %q:\n%s", want, got)
+		}
+	}
+	// 数据库没有任何变化，连记录表都不创建
+	if db.Migrator().HasTable("items") || db.Migrator().HasTable(DefaultTable) {
+		t.Error("dry run changed the database")
+	}
+}
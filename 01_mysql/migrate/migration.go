@@ -0,0 +1,195 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个版本的结构变更
+// 可以写成 SQL（UpSQL/DownSQL），也可以写成 Go 函数（Up/Down），两者只能选一种
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// Go 创建一个 Go 函数形式的迁移，down 为 nil 表示不可回滚
+func Go(version int64, name string, up, down func(tx *gorm.DB) error) Migration {
+	return Migration{Version: version, Name: name, Up: up, Down: down}
+}
+
+// IsSQL 是否是 SQL 迁移
+func (m Migration) IsSQL() bool { return m.Up == nil }
+
+// Reversible 是否可以回滚
+func (m Migration) Reversible() bool {
+	if m.IsSQL() {
+		return strings.TrimSpace(m.DownSQL) != ""
+	}
+	return m.Down != nil
+}
+
+// Checksum up 脚本的 sha256，用于发现已经执行过的迁移被改动（drift）
+// Go 迁移无法对代码取哈希，只对版本和名称取哈希
+func (m Migration) Checksum() string {
+	src := m.UpSQL
+	if !m.IsSQL() {
+		src = fmt.Sprintf("go:%d:%s", m.Version, m.Name)
+	}
+	sum := sha256.Sum256([]byte(normalize(src)))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize 统一换行和行尾空白，避免 Windows 上 checkout 导致误报
+func normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// 文件名格式：{版本}_{名称}[.{驱动}].{up|down}.sql
+// 例如 0001_create_user3.up.sql、0001_create_user3.sqlite.up.sql
+// 带驱动的文件优先于通用文件，用来处理 mysql 和 sqlite 语法不同的地方
+var fileRe = regexp.MustCompile(`^(\d+)_([a-zA-Z0-9_]+?)(?:\.(mysql|sqlite))?\.(up|down)\.sql$`)
+
+// LoadFS 从目录中加载某个驱动的 SQL 迁移
+func LoadFS(fsys fs.FS, dir, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read %s: %w", dir, err)
+	}
+
+	type parts struct {
+		name     string
+		up, down string
+	}
+	byVersion := map[int64]*parts{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrate: bad migration file name %q, want 0001_name[.driver].up.sql", e.Name())
+		}
+		fileDriver := m[3]
+		if fileDriver != "" && fileDriver != driver {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		p := byVersion[version]
+		if p == nil {
+			p = &parts{name: m[2]}
+			byVersion[version] = p
+		}
+		if p.name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, p.name, m[2])
+		}
+		specific := fileDriver != ""
+		switch m[4] {
+		case "up":
+			if p.up == "" || specific {
+				p.up = string(body)
+			}
+		case "down":
+			if p.down == "" || specific {
+				p.down = string(body)
+			}
+		}
+	}
+
+	var list []Migration
+	for v, p := range byVersion {
+		if strings.TrimSpace(p.up) == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up script for %s", v, p.name, driver)
+		}
+		list = append(list, Migration{Version: v, Name: p.name, UpSQL: p.up, DownSQL: p.down})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// SplitStatements 把一个 SQL 脚本拆成多条语句
+// mysql 驱动默认不允许一次执行多条语句，所以按分号拆开逐条执行
+// 会跳过引号和注释里的分号
+func SplitStatements(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+		quote rune
+	)
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			cur.WriteRune(c)
+			if c == quote {
+				// 两个连续的引号是转义
+				if i+1 < len(runes) && runes[i+1] == quote {
+					cur.WriteRune(runes[i+1])
+					i++
+				} else {
+					quote = 0
+				}
+			} else if c == '\\' && quote != '`' && i+1 < len(runes) {
+				cur.WriteRune(runes[i+1])
+				i++
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			cur.WriteRune(c)
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-', c == '#':
+			// 单行注释，直接跳过
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			cur.WriteRune('\n')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// 块注释，跳到 */ 之后
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			cur.WriteRune(' ')
+		case c == ';':
+			if s := strings.TrimSpace(cur.String()); s != "" {
+				stmts = append(stmts, s)
+			}
+			cur.Reset()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
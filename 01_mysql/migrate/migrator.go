@@ -0,0 +1,353 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultTable 记录已执行迁移的表
+const DefaultTable = "schema_migrations"
+
+var (
+	// ErrDrift 已执行的迁移脚本被修改过
+	ErrDrift = errors.New("migrate: applied migration has been modified")
+	// ErrIrreversible 迁移没有 down 脚本，无法回滚
+	ErrIrreversible = errors.New("migrate: migration is irreversible")
+	// ErrUnknownVersion 版本不存在
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+)
+
+// Options 迁移选项
+type Options struct {
+	Table      string    // 记录表名，默认 schema_migrations
+	Out        io.Writer // 进度和 dry-run SQL 的输出，默认丢弃
+	DryRun     bool      // 只打印将要执行的 SQL，不修改数据库
+	AllowDrift bool      // 已执行的迁移被修改时只警告，不报错
+}
+
+// Status 单个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Drift     bool // 已执行，但脚本和执行时不一致
+	Orphan    bool // 数据库里有记录，代码里找不到对应的迁移
+}
+
+// record schema_migrations 表的一行
+type record struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Checksum  string `gorm:"size:64;not null"`
+	AppliedAt time.Time
+}
+
+// Migrator 按版本号顺序执行迁移
+// 每个迁移在一个事务里执行，并在同一个事务里写入 schema_migrations
+// 注意 mysql 的 DDL 会隐式提交事务，一个迁移里的多条 DDL 失败时不能整体回滚，所以每个迁移尽量只做一件事
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	opts       Options
+}
+
+// New 创建 Migrator，migrations 不需要事先排序，但版本号不能重复
+func New(db *gorm.DB, migrations []Migration, opts Options) (*Migrator, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	list := append([]Migration(nil), migrations...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, mg := range list {
+		if mg.Version <= 0 {
+			return nil, fmt.Errorf("migrate: %s: version must be positive", mg)
+		}
+		if i > 0 && list[i-1].Version == mg.Version {
+			return nil, fmt.Errorf("migrate: duplicate version %d (%s, %s)", mg.Version, list[i-1].Name, mg.Name)
+		}
+		if mg.IsSQL() && mg.UpSQL == "" {
+			return nil, fmt.Errorf("migrate: %s has neither up SQL nor Up func", mg)
+		}
+	}
+	return &Migrator{db: db, migrations: list, opts: opts}, nil
+}
+
+// Migrations 返回排好序的迁移列表
+func (m *Migrator) Migrations() []Migration { return m.migrations }
+
+// Status 返回所有迁移的状态，包括数据库里有记录但代码里已经不存在的版本
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list []Status
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			s.Applied, s.AppliedAt = true, r.AppliedAt
+			s.Drift = r.Checksum != mg.Checksum()
+			delete(applied, mg.Version)
+		}
+		list = append(list, s)
+	}
+	for _, r := range applied {
+		list = append(list, Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Orphan: true})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Current 返回当前已执行的最大版本，没有执行过任何迁移时返回 0
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	for version := range applied {
+		v = max(v, version)
+	}
+	return v, nil
+}
+
+// Up 执行所有未执行的迁移，不会回滚任何版本
+func (m *Migrator) Up(ctx context.Context) error {
+	list, err := m.check(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		if !s.Applied {
+			if err := m.run(ctx, *m.find(s.Version), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+	list, err := m.check(ctx)
+	if err != nil {
+		return err
+	}
+	var target int64
+	var applied []Status
+	for _, s := range list {
+		if s.Applied {
+			applied = append(applied, s)
+		}
+	}
+	if steps < len(applied) {
+		target = applied[len(applied)-steps-1].Version
+	}
+	return m.to(ctx, list, target)
+}
+
+// To 迁移到指定版本：比当前版本高就执行 up，低就回滚，0 表示全部回滚
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	list, err := m.check(ctx)
+	if err != nil {
+		return err
+	}
+	return m.to(ctx, list, version)
+}
+
+func (m *Migrator) to(ctx context.Context, list []Status, target int64) error {
+	// 先回滚高于目标版本的（从新到旧），再执行不高于目标版本且未执行的（从旧到新）
+	// 中间版本漏执行的情况（例如合并分支后插入了更早的版本号）也会被补上
+	// 回滚前先确认每一步都能回滚，避免回滚到一半停下
+	var downs []Migration
+	for i := len(list) - 1; i >= 0; i-- {
+		s := list[i]
+		if !s.Applied || s.Version <= target {
+			continue
+		}
+		mg := m.find(s.Version)
+		if mg == nil {
+			return fmt.Errorf("%w %d (%s): applied but not found in code, cannot roll back", ErrUnknownVersion, s.Version, s.Name)
+		}
+		if !mg.Reversible() {
+			return fmt.Errorf("%w: %s", ErrIrreversible, mg)
+		}
+		downs = append(downs, *mg)
+	}
+	for _, mg := range downs {
+		if err := m.run(ctx, mg, false); err != nil {
+			return err
+		}
+	}
+	for _, s := range list {
+		if s.Applied || s.Orphan || s.Version > target {
+			continue
+		}
+		if err := m.run(ctx, *m.find(s.Version), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check 读取状态并检查 drift
+func (m *Migrator) check(ctx context.Context) ([]Status, error) {
+	list, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range list {
+		if !s.Drift {
+			continue
+		}
+		if !m.opts.AllowDrift {
+			return nil, fmt.Errorf("%w: %04d_%s, restore the original script or add a new migration", ErrDrift, s.Version, s.Name)
+		}
+		fmt.Fprintf(m.opts.Out, "warning: %04d_%s has been modified after it was applied\n", s.Version, s.Name)
+	}
+	return list, nil
+}
+
+// run 在事务里执行一个迁移并更新记录表
+func (m *Migrator) run(ctx context.Context, mg Migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+		if !mg.Reversible() {
+			return fmt.Errorf("%w: %s", ErrIrreversible, mg)
+		}
+	}
+
+	if m.opts.DryRun {
+		fmt.Fprintf(m.opts.Out, "-- %s (%s)\n", mg, direction)
+		return m.dryRun(ctx, mg, up)
+	}
+
+	start := time.Now()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx, mg, up); err != nil {
+			return err
+		}
+		if up {
+			return tx.Table(m.opts.Table).Create(&record{
+				Version:   mg.Version,
+				Name:      mg.Name,
+				Checksum:  mg.Checksum(),
+				AppliedAt: time.Now(),
+			}).Error
+		}
+		return tx.Table(m.opts.Table).Where("version = ?", mg.Version).Delete(&record{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %s %s: %w", mg, direction, err)
+	}
+	fmt.Fprintf(m.opts.Out, "%s %s (%v)\n", direction, mg, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func apply(tx *gorm.DB, mg Migration, up bool) error {
+	if !mg.IsSQL() {
+		if up {
+			return mg.Up(tx)
+		}
+		return mg.Down(tx)
+	}
+	script := mg.UpSQL
+	if !up {
+		script = mg.DownSQL
+	}
+	for _, stmt := range SplitStatements(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dryRun 打印 SQL 而不执行
+// SQL 迁移直接打印脚本；Go 迁移用 gorm 的 DryRun 会话执行，通过 logger 收集生成的 SQL
+func (m *Migrator) dryRun(ctx context.Context, mg Migration, up bool) error {
+	if mg.IsSQL() {
+		script := mg.UpSQL
+		if !up {
+			script = mg.DownSQL
+		}
+		for _, stmt := range SplitStatements(script) {
+			fmt.Fprintf(m.opts.Out, "%s;\n", stmt)
+		}
+		return nil
+	}
+	tx := m.db.Session(&gorm.Session{
+		DryRun:  true,
+		Context: ctx,
+		Logger:  &printLogger{out: m.opts.Out},
+	})
+	if err := apply(tx, mg, up); err != nil {
+		return fmt.Errorf("migrate: %s dry run: %w", mg, err)
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移，记录表不存在时自动创建（dry-run 时当作空表）
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(m.opts.Table) {
+		if m.opts.DryRun {
+			return map[int64]record{}, nil
+		}
+		if err := db.Table(m.opts.Table).AutoMigrate(&record{}); err != nil {
+			return nil, fmt.Errorf("migrate: create %s: %w", m.opts.Table, err)
+		}
+	}
+	var rows []record
+	if err := db.Table(m.opts.Table).Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("migrate: read %s: %w", m.opts.Table, err)
+	}
+	applied := make(map[int64]record, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// printLogger 把 gorm 生成的 SQL 写到 out，DryRun 会话里 gorm 仍然会调用 Trace
+type printLogger struct {
+	out io.Writer
+}
+
+func (l *printLogger) LogMode(logger.LogLevel) logger.Interface      { return l }
+func (l *printLogger) Info(context.Context, string, ...interface{})  {}
+func (l *printLogger) Warn(context.Context, string, ...interface{})  {}
+func (l *printLogger) Error(context.Context, string, ...interface{}) {}
+
+func (l *printLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	if sql, _ := fc(); sql != "" {
+		fmt.Fprintf(l.out, "%s;\n", sql)
+	}
+}
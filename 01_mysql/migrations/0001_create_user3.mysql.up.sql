@@ -0,0 +1,13 @@
+-- 03_CRUD_创建.go 的 User3，对应 gorm.Model + Name/Age/Birthday
+-- IF NOT EXISTS：以前用 AutoMigrate 建过表的库也可以直接接入迁移
+CREATE TABLE IF NOT EXISTS `user3` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `name` longtext,
+  `age` bigint,
+  `birthday` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user3_deleted_at` (`deleted_at`)
+);
@@ -0,0 +1,11 @@
+-- 03_CRUD_创建.go 的 User3，对应 gorm.Model + Name/Age/Birthday
+CREATE TABLE IF NOT EXISTS `user3` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `name` text,
+  `age` integer,
+  `birthday` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_user3_deleted_at` ON `user3`(`deleted_at`);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `02_user`;
@@ -0,0 +1,6 @@
+-- 02_连接到数据库.go 的 User
+CREATE TABLE IF NOT EXISTS `02_user` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` longtext,
+  PRIMARY KEY (`id`)
+);
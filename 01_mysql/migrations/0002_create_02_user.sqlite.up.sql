@@ -0,0 +1,5 @@
+-- 02_连接到数据库.go 的 User
+CREATE TABLE IF NOT EXISTS `02_user` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text
+);
@@ -0,0 +1 @@
+ALTER TABLE `user3` DROP COLUMN `version`;
@@ -0,0 +1,2 @@
+-- 乐观锁版本号（lock.Version），已有的行从 1 开始
+ALTER TABLE `user3` ADD COLUMN `version` bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `user3_roles`;
+DROP TABLE IF EXISTS `roles`;
+DROP TABLE IF EXISTS `user3_orders`;
+DROP TABLE IF EXISTS `user3_profiles`;
@@ -0,0 +1,46 @@
+-- model.Profile / Order / Role：User3 的一对一资料、一对多订单、多对多角色
+-- 外键 ON DELETE CASCADE 兜底：即使绕过 relation.Delete 直接物理删除用户，也不会留下孤儿行
+CREATE TABLE IF NOT EXISTS `user3_profiles` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `bio` varchar(200),
+  `avatar` varchar(255),
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user3_profiles_user_id` (`user_id`),
+  INDEX `idx_user3_profiles_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_user3_profile` FOREIGN KEY (`user_id`) REFERENCES `user3` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `user3_orders` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `no` varchar(32) NOT NULL,
+  `amount` bigint NOT NULL DEFAULT 0,
+  `status` varchar(16) NOT NULL DEFAULT 'pending',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user3_orders_no` (`no`),
+  INDEX `idx_user3_orders_user_id` (`user_id`),
+  INDEX `idx_user3_orders_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_user3_orders` FOREIGN KEY (`user_id`) REFERENCES `user3` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `roles` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(32) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_roles_name` (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `user3_roles` (
+  `user3_id` bigint unsigned NOT NULL,
+  `role_id` bigint unsigned NOT NULL,
+  PRIMARY KEY (`user3_id`, `role_id`),
+  CONSTRAINT `fk_user3_roles_user3` FOREIGN KEY (`user3_id`) REFERENCES `user3` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_user3_roles_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE CASCADE
+);
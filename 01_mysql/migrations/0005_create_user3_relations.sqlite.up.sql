@@ -0,0 +1,39 @@
+-- model.Profile / Order / Role：User3 的一对一资料、一对多订单、多对多角色
+-- sqlite 的外键需要 _foreign_keys=1（database.Open 默认打开）
+CREATE TABLE IF NOT EXISTS `user3_profiles` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL REFERENCES `user3` (`id`) ON DELETE CASCADE,
+  `bio` text,
+  `avatar` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user3_profiles_user_id` ON `user3_profiles`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_user3_profiles_deleted_at` ON `user3_profiles`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `user3_orders` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `user_id` integer NOT NULL REFERENCES `user3` (`id`) ON DELETE CASCADE,
+  `no` text NOT NULL,
+  `amount` integer NOT NULL DEFAULT 0,
+  `status` text NOT NULL DEFAULT 'pending'
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user3_orders_no` ON `user3_orders`(`no`);
+CREATE INDEX IF NOT EXISTS `idx_user3_orders_user_id` ON `user3_orders`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_user3_orders_deleted_at` ON `user3_orders`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `roles` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_roles_name` ON `roles`(`name`);
+
+CREATE TABLE IF NOT EXISTS `user3_roles` (
+  `user3_id` integer NOT NULL REFERENCES `user3` (`id`) ON DELETE CASCADE,
+  `role_id` integer NOT NULL REFERENCES `roles` (`id`) ON DELETE CASCADE,
+  PRIMARY KEY (`user3_id`, `role_id`)
+);
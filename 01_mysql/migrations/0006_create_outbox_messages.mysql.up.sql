@@ -0,0 +1,14 @@
+-- outbox.Message：和业务数据在同一个事务里写入的事件，由 outbox.Relay 投递
+CREATE TABLE IF NOT EXISTS `outbox_messages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `topic` varchar(128) NOT NULL,
+  `aggregate_id` varchar(128) NOT NULL DEFAULT '',
+  `payload` longtext NOT NULL,
+  `created_at` datetime(3) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(3) NOT NULL,
+  `sent_at` datetime(3) NULL,
+  `last_error` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_outbox_messages_pending` (`sent_at`, `next_attempt_at`)
+);
@@ -0,0 +1,13 @@
+-- outbox.Message：和业务数据在同一个事务里写入的事件，由 outbox.Relay 投递
+CREATE TABLE IF NOT EXISTS `outbox_messages` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `topic` text NOT NULL,
+  `aggregate_id` text NOT NULL DEFAULT '',
+  `payload` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  `attempts` integer NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime NOT NULL,
+  `sent_at` datetime,
+  `last_error` text
+);
+CREATE INDEX IF NOT EXISTS `idx_outbox_messages_pending` ON `outbox_messages`(`sent_at`, `next_attempt_at`);
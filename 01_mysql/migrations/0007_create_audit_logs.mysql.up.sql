@@ -0,0 +1,15 @@
+-- audit.Entry：模型的变更历史，同一条记录的版本号唯一，并发写入时冲突的一方重试
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `table_name` varchar(64) NOT NULL,
+  `record_id` varchar(64) NOT NULL,
+  `version` bigint NOT NULL,
+  `action` varchar(16) NOT NULL,
+  `actor` varchar(128),
+  `before` text,
+  `after` text,
+  `diff` text,
+  `created_at` datetime(3),
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_audit_record` (`table_name`, `record_id`, `version`)
+);
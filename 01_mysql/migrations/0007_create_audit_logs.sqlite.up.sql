@@ -0,0 +1,14 @@
+-- audit.Entry：模型的变更历史，同一条记录的版本号唯一，并发写入时冲突的一方重试
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `table_name` text NOT NULL,
+  `record_id` text NOT NULL,
+  `version` integer NOT NULL,
+  `action` text NOT NULL,
+  `actor` text,
+  `before` text,
+  `after` text,
+  `diff` text,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_audit_record` ON `audit_logs`(`table_name`, `record_id`, `version`);
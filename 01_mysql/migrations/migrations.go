@@ -0,0 +1,64 @@
+// Package migrations 课程用到的表结构，按版本号管理
+//
+// 新增迁移：在本目录添加 {版本}_{名称}.up.sql / .down.sql，
+// mysql 和 sqlite 语法不同时加上驱动名，例如 0001_create_user3.sqlite.up.sql；
+// 需要写逻辑的迁移在 goMigrations 里用 migrate.Go 注册
+package migrations
+
+import (
+	"context"
+	"embed"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/migrate"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// user3AgeIndex 迁移里使用当时的表结构快照，不要直接引用会继续变化的业务模型
+type user3AgeIndex struct {
+	Age int `gorm:"index:idx_user3_age"`
+}
+
+func (user3AgeIndex) TableName() string { return "user3" }
+
+// goMigrations Go 写的迁移，可以用 gorm 的 Migrator 屏蔽不同数据库的语法差异
+var goMigrations = []migrate.Migration{
+	migrate.Go(3, "index_user3_age",
+		func(tx *gorm.DB) error {
+			return tx.Migrator().CreateIndex(&user3AgeIndex{}, "idx_user3_age")
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&user3AgeIndex{}, "idx_user3_age")
+		},
+	),
+}
+
+// All 返回某个驱动（mysql | sqlite）的全部迁移
+func All(driver string) ([]migrate.Migration, error) {
+	list, err := migrate.LoadFS(files, ".", driver)
+	if err != nil {
+		return nil, err
+	}
+	return append(list, goMigrations...), nil
+}
+
+// New 按 db 的驱动创建 Migrator
+func New(db *gorm.DB, opts migrate.Options) (*migrate.Migrator, error) {
+	list, err := All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, list, opts)
+}
+
+// Up 执行所有未执行的迁移，代替课程里的 db.AutoMigrate
+func Up(db *gorm.DB) error {
+	m, err := New(db, migrate.Options{})
+	if err != nil {
+		return err
+	}
+	return m.Up(context.Background())
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/migrate"
+)
+
+func TestAllDrivers(t *testing.T) {
+	for _, driver := range []string{database.DriverMySQL, database.DriverSQLite} {
+		list, err := All(driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 7 {
+			t.Errorf("%s: %d migrations, want 7", driver, len(list))
+		}
+		for _, m := range list {
+			if !m.Reversible() {
+				t.Errorf("%s: %s has no down", driver, m)
+			}
+		}
+	}
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// 迁移建出来的表要能直接给课程里的 User3 使用
+	type User3 struct {
+		gorm.Model
+		Name     string
+		Age      int
+		Birthday *time.Time
+	}
+	u := User3{Name: "张三", Age: 20}
+	if err := db.Table("user3").Create(&u).Error; err != nil || u.ID == 0 {
+		t.Fatalf("create user3: %v", err)
+	}
+	if !db.Migrator().HasIndex("user3", "idx_user3_age") {
+		t.Error("missing idx_user3_age")
+	}
+	var version int64
+	if db.Table("user3").Select("version").Where("id = ?", u.ID).Scan(&version); version != 1 {
+		t.Errorf("default version = %d, want 1", version)
+	}
+	for _, table := range []string{"user3_profiles", "user3_orders", "roles", "user3_roles", "outbox_messages", "audit_logs"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("missing table %s", table)
+		}
+	}
+
+	m, _ := New(db, migrate.Options{})
+	if err := m.To(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if db.Migrator().HasTable("user3") || db.Migrator().HasTable("02_user") || db.Migrator().HasTable("user3_orders") {
+		t.Error("tables left after rolling back everything")
+	}
+}
@@ -0,0 +1,73 @@
+// Package model 课程里用到的表对应的模型
+//
+// 课程文件（package main）为了讲解方便各自定义了一份同名结构体，
+// 工具和命令（迁移、造数、接口）统一使用这里的定义，表结构以 migrations 为准
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/lock"
+)
+
+// User 02_连接到数据库.go 的用户表
+type User struct {
+	ID   uint
+	Name string `validate:"notblank"`
+}
+
+func (User) TableName() string { return "02_user" }
+
+// User3 03_CRUD_创建.go 的用户表
+type User3 struct {
+	gorm.Model        // 包含 ID, CreatedAt, UpdatedAt, DeletedAt 字段
+	Name       string `validate:"notblank,max=50"`
+	Age        int    `validate:"min=18,max=150"`
+	Birthday   *time.Time
+	Version    lock.Version `gorm:"not null;default:1"` // 乐观锁，注册 lock.Plugin 后生效
+
+	// 关联，默认不加载，查询时用 relation.Load 选择 Preload 或 Joins
+	Profile *Profile `gorm:"foreignKey:UserID"`     // 一对一
+	Orders  []Order  `gorm:"foreignKey:UserID"`     // 一对多
+	Roles   []Role   `gorm:"many2many:user3_roles"` // 多对多，中间表 user3_roles(user3_id, role_id)
+}
+
+func (User3) TableName() string { return "user3" }
+
+// Profile 用户资料，每个 User3 至多一份
+type Profile struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;uniqueIndex"`
+	Bio    string `validate:"max=200"`
+	Avatar string `validate:"max=255"`
+}
+
+func (Profile) TableName() string { return "user3_profiles" }
+
+// 订单状态
+const (
+	OrderPending  = "pending"
+	OrderPaid     = "paid"
+	OrderCanceled = "canceled"
+)
+
+// Order 用户的订单，金额以分为单位
+type Order struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	No     string `gorm:"not null;uniqueIndex" validate:"notblank,max=32"`
+	Amount int64  `validate:"min=0"`
+	Status string `gorm:"not null;default:pending" validate:"max=16"`
+}
+
+func (Order) TableName() string { return "user3_orders" }
+
+// Role 角色，和用户多对多；角色本身不随用户删除
+type Role struct {
+	ID   uint
+	Name string `gorm:"not null;uniqueIndex" validate:"notblank,max=32"`
+}
+
+func (Role) TableName() string { return "roles" }
@@ -0,0 +1,144 @@
+package observe
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// buckets 耗时分布的桶（秒）
+var buckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+type seriesKey struct{ op, table string }
+
+type series struct {
+	count, errors, rows, slow int64
+	sum                       float64
+	buckets                   []int64 // 与 buckets 对应，累计值在输出时计算
+}
+
+type metrics struct {
+	mu       sync.Mutex
+	series   map[seriesKey]*series
+	nplusone map[string]int64 // 表 -> 次数
+}
+
+func newMetrics() *metrics {
+	return &metrics{series: map[seriesKey]*series{}, nplusone: map[string]int64{}}
+}
+
+func (m *metrics) observe(q Query, slow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := seriesKey{q.Op, q.Table}
+	s, ok := m.series[k]
+	if !ok {
+		s = &series{buckets: make([]int64, len(buckets))}
+		m.series[k] = s
+	}
+	s.count++
+	s.rows += q.Rows
+	if q.Err != nil {
+		s.errors++
+	}
+	if slow {
+		s.slow++
+	}
+	sec := q.Duration.Seconds()
+	s.sum += sec
+	for i, le := range buckets {
+		if sec <= le {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+func (m *metrics) nPlusOne(table string) {
+	m.mu.Lock()
+	m.nplusone[table]++
+	m.mu.Unlock()
+}
+
+// WritePrometheus 按 Prometheus 文本格式输出指标
+func (p *Plugin) WritePrometheus(w io.Writer) error {
+	m := p.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]seriesKey, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].op != keys[j].op {
+			return keys[i].op < keys[j].op
+		}
+		return keys[i].table < keys[j].table
+	})
+
+	var b strings.Builder
+	counter := func(name, help string, value func(*series) int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s{%s} %d\n", name, labels(k), value(m.series[k]))
+		}
+	}
+	counter("gorm_queries_total", "Statements executed.", func(s *series) int64 { return s.count })
+	counter("gorm_query_errors_total", "Statements that returned an error (record not found excluded).", func(s *series) int64 { return s.errors })
+	counter("gorm_rows_affected_total", "Rows affected or returned.", func(s *series) int64 { return s.rows })
+	counter("gorm_slow_queries_total", "Statements slower than the slow threshold.", func(s *series) int64 { return s.slow })
+
+	name := "gorm_query_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Statement latency.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		s := m.series[k]
+		var cum int64
+		for i, le := range buckets {
+			cum += s.buckets[i]
+			fmt.Fprintf(&b, "%s_bucket{%s,le=%q} %d\n", name, labels(k), strconv.FormatFloat(le, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels(k), s.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", name, labels(k), strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", name, labels(k), s.count)
+	}
+
+	tables := make([]string, 0, len(m.nplusone))
+	for t := range m.nplusone {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	name = "gorm_n_plus_one_total"
+	fmt.Fprintf(&b, "# HELP %s Requests where the same query shape repeated past the N+1 threshold.\n# TYPE %s counter\n", name, name)
+	for _, t := range tables {
+		fmt.Fprintf(&b, "%s{table=\"%s\"} %d\n", name, escape(t), m.nplusone[t])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP 输出指标，挂到 /metrics 给 Prometheus 抓取
+func (p *Plugin) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WritePrometheus(w)
+}
+
+// Middleware 给每个请求的 context 加上统计，handler 里用 r.Context() 执行 SQL 才能检测 N+1
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithRequest(r.Context())))
+	})
+}
+
+func labels(k seriesKey) string {
+	return fmt.Sprintf(`op="%s",table="%s"`, escape(k.op), escape(k.table))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string { return labelEscaper.Replace(s) }
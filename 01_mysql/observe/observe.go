@@ -0,0 +1,326 @@
+// Package observe 记录每条 SQL 的耗时、影响行数和调用位置
+//
+//   - 慢查询：超过 SlowThreshold 的语句打一条日志，带调用位置；SELECT 会附上 EXPLAIN 的结果
+//   - N+1：同一个请求里相同形状的查询执行了 NPlusOne 次以上时报警（每种形状只报一次）
+//   - 指标：按操作和表统计次数、错误数、影响行数、耗时分布
+//
+// 不用再在代码里注释/取消注释 logger.Default.LogMode(logger.Info)：
+//
+//	obs := observe.New(observe.Config{SlowThreshold: 100 * time.Millisecond, Explain: true})
+//	db.Use(obs)
+//	http.Handle("/metrics", obs)          // Prometheus 文本格式
+//	handler = observe.Middleware(handler) // 每个请求单独统计，用于发现 N+1
+package observe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 默认值
+const (
+	DefaultSlowThreshold = 200 * time.Millisecond
+	DefaultNPlusOne      = 5
+)
+
+// Config 插件配置
+type Config struct {
+	// SlowThreshold 慢查询阈值，默认 200ms，负数表示不记录慢查询
+	SlowThreshold time.Duration
+	// Explain 慢的 SELECT 是否附上执行计划（mysql: EXPLAIN，sqlite: EXPLAIN QUERY PLAN）
+	// Row/Rows 执行完时结果集还占着连接，这类查询不附执行计划
+	Explain bool
+	// NPlusOne 同一请求里同一形状的查询执行多少次视为 N+1，默认 5，负数表示不检测
+	NPlusOne int
+	// Logger 默认 log.Default()
+	Logger *log.Logger
+}
+
+// Query 一条执行完的语句
+type Query struct {
+	Op       string // create | query | update | delete | row | raw
+	Table    string
+	SQL      string // 用 Dialector.Explain 代入参数后的 SQL，参数值原样出现在日志里，敏感数据要注意
+	Shape    string // 去掉参数、合并 IN 列表后的形状，用于 N+1 判断
+	Duration time.Duration
+	Rows     int64
+	Err      error
+	Caller   string // 调用 gorm 的代码位置 file:line
+}
+
+// Plugin 观测插件，同时是输出 Prometheus 指标的 http.Handler
+type Plugin struct {
+	cfg     Config
+	metrics *metrics
+}
+
+// New 创建插件
+func New(cfg Config) *Plugin {
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = DefaultSlowThreshold
+	}
+	if cfg.NPlusOne == 0 {
+		cfg.NPlusOne = DefaultNPlusOne
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	return &Plugin{cfg: cfg, metrics: newMetrics()}
+}
+
+func (p *Plugin) Name() string { return "observe" }
+
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("observe:start", start),
+		cb.Query().Before("*").Register("observe:start", start),
+		cb.Update().Before("*").Register("observe:start", start),
+		cb.Delete().Before("*").Register("observe:start", start),
+		cb.Row().Before("*").Register("observe:start", start),
+		cb.Raw().Before("*").Register("observe:start", start),
+		cb.Create().After("*").Register("observe:finish", p.finish("create")),
+		cb.Query().After("*").Register("observe:finish", p.finish("query")),
+		cb.Update().After("*").Register("observe:finish", p.finish("update")),
+		cb.Delete().After("*").Register("observe:finish", p.finish("delete")),
+		cb.Row().After("*").Register("observe:finish", p.finish("row")),
+		cb.Raw().After("*").Register("observe:finish", p.finish("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	startKey = "observe:start"
+	skipKey  = "observe:skip" // 插件自己执行的 EXPLAIN 不统计
+)
+
+func start(db *gorm.DB) {
+	db.Statement.Settings.Store(startKey, time.Now())
+}
+
+func (p *Plugin) finish(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		s := db.Statement
+		v, ok := s.Settings.LoadAndDelete(startKey)
+		if !ok || db.DryRun {
+			return
+		}
+		if _, skip := s.Settings.Load(skipKey); skip {
+			return
+		}
+		sql := s.SQL.String()
+		if sql == "" {
+			return // 被前面的回调中止，没有真正执行
+		}
+		q := Query{
+			Op:       op,
+			Table:    s.Table,
+			SQL:      db.Dialector.Explain(sql, s.Vars...),
+			Shape:    Shape(sql),
+			Duration: time.Since(v.(time.Time)),
+			Rows:     db.RowsAffected,
+			Caller:   caller(),
+		}
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			q.Err = db.Error
+		}
+		p.record(db, q)
+	}
+}
+
+func (p *Plugin) record(db *gorm.DB, q Query) {
+	slow := p.cfg.SlowThreshold > 0 && q.Duration >= p.cfg.SlowThreshold
+	p.metrics.observe(q, slow)
+	if slow {
+		msg := fmt.Sprintf("observe: slow query %s rows=%d caller=%s\n  %s", q.Duration.Round(time.Microsecond), q.Rows, q.Caller, q.SQL)
+		// Row/Rows 的结果集还没读完，同一个连接（事务）上不能再执行 EXPLAIN
+		if p.cfg.Explain && q.Err == nil && q.Op != "row" && isSelect(q.SQL) {
+			if plan, err := explain(db); err != nil {
+				msg += "\n  explain failed: " + err.Error()
+			} else if plan != "" {
+				msg += "\n" + plan
+			}
+		}
+		p.cfg.Logger.Print(msg)
+	}
+	if r := requestFrom(db.Statement.Context); r != nil {
+		if shape, n := r.add(q, p.cfg.NPlusOne); shape != "" {
+			p.metrics.nPlusOne(q.Table)
+			p.cfg.Logger.Printf("observe: possible N+1, same query ran %d times in one request, caller=%s\n  %s", n, q.Caller, shape)
+		}
+	}
+}
+
+// explain 在同一个连接（事务）上执行执行计划，返回按行格式化的结果
+func explain(db *gorm.DB) (string, error) {
+	var prefix string
+	switch db.Dialector.Name() {
+	case "mysql":
+		prefix = "EXPLAIN "
+	case "sqlite":
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return "", nil
+	}
+	s := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true, Logger: logger.Discard}).Set(skipKey, true)
+	rows, err := tx.Raw(prefix+s.SQL.String(), s.Vars...).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("  " + strings.Join(cols, " | "))
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		cells := make([]string, len(vals))
+		for i, v := range vals {
+			switch v := v.(type) {
+			case nil:
+				cells[i] = "NULL"
+			case []byte:
+				cells[i] = string(v)
+			default:
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		b.WriteString("\n  " + strings.Join(cells, " | "))
+	}
+	return b.String(), rows.Err()
+}
+
+func isSelect(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "SELECT")
+}
+
+var (
+	inListRe = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	spaceRe  = regexp.MustCompile(`\s+`)
+)
+
+// Shape 语句的形状：参数都是 ?，IN (?,?,?) 合并成 IN (?)，空白合并
+//
+//	SELECT * FROM `orders` WHERE `user_id` = ?       // 每个用户查一次 -> 同一个形状
+//	SELECT * FROM `orders` WHERE `user_id` IN (?,?)  // Preload 的写法
+func Shape(sql string) string {
+	sql = inListRe.ReplaceAllString(sql, "(?)")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(sql, " "))
+}
+
+// pkgPath 本包的路径，找调用位置时跳过
+var pkgPath = reflect.TypeOf(Plugin{}).PkgPath()
+
+// caller 第一个不在 gorm 和本包里的调用位置
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		inPkg := strings.HasPrefix(f.Function, pkgPath+".") && !strings.HasSuffix(f.File, "_test.go")
+		if !strings.Contains(f.File, "gorm.io/") && !inPkg {
+			return fmt.Sprintf("%s:%d", shortFile(f.File), f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// shortFile 只保留最后两级目录，日志里更好读
+func shortFile(file string) string {
+	parts := strings.Split(file, "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
+
+type requestKey struct{}
+
+// request 一个请求里的统计
+type request struct {
+	mu      sync.Mutex
+	stats   RequestStats
+	counts  map[string]int
+	flagged map[string]bool
+}
+
+// RequestStats 一个请求执行的 SQL 汇总
+type RequestStats struct {
+	Queries  int
+	Duration time.Duration
+	NPlusOne []string // 被判定为 N+1 的查询形状
+}
+
+// WithRequest 开始统计一个请求，N+1 检测只在这样的 context 上生效
+func WithRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{counts: map[string]int{}, flagged: map[string]bool{}})
+}
+
+// Report 返回请求到目前为止的统计，ctx 不是 WithRequest 创建的时返回 false
+func Report(ctx context.Context) (RequestStats, bool) {
+	r := requestFrom(ctx)
+	if r == nil {
+		return RequestStats{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.NPlusOne = append([]string(nil), st.NPlusOne...)
+	return st, true
+}
+
+func requestFrom(ctx context.Context) *request {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(requestKey{}).(*request)
+	return r
+}
+
+// add 记录一条语句，刚好达到 N+1 阈值时返回它的形状和次数
+func (r *request) add(q Query, threshold int) (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Queries++
+	r.stats.Duration += q.Duration
+	if threshold <= 0 || (q.Op != "query" && q.Op != "row") {
+		return "", 0
+	}
+	r.counts[q.Shape]++
+	n := r.counts[q.Shape]
+	if n < threshold || r.flagged[q.Shape] {
+		return "", 0
+	}
+	r.flagged[q.Shape] = true
+	r.stats.NPlusOne = append(r.stats.NPlusOne, q.Shape)
+	return q.Shape, n
+}
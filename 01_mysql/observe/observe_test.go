@@ -0,0 +1,189 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+)
+
+// user 与课程里的 User3 一致
+type user struct {
+	gorm.Model
+	Name string
+	Age  int
+}
+
+func (user) TableName() string { return "user3" }
+
+func openDB(t *testing.T, cfg Config) (*gorm.DB, *Plugin, *bytes.Buffer) {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err := db.Create(&user{Name: "u", Age: i}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	cfg.Logger = log.New(&buf, "", 0)
+	p := New(cfg)
+	if err := db.Use(p); err != nil {
+		t.Fatal(err)
+	}
+	return db, p, &buf
+}
+
+func TestSlowQuery(t *testing.T) {
+	db, _, buf := openDB(t, Config{SlowThreshold: time.Nanosecond, Explain: true, NPlusOne: -1})
+
+	var users []user
+	if err := db.Where("age > ?", 5).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"observe: slow query",
+		"rows=5",
+		"caller=observe/observe_test.go:",
+		"SELECT * FROM `user3` WHERE age > 5",
+		"SEARCH user3 USING INDEX idx_user3_deleted_at", // EXPLAIN QUERY PLAN 的结果
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+	// EXPLAIN 本身不会再被记录
+	if n := strings.Count(out, "observe: slow query"); n != 1 {
+		t.Errorf("%d slow queries logged, want 1:\n%s", n, out)
+	}
+
+	// Rows 的结果集占着唯一的连接，不能再去 EXPLAIN
+	buf.Reset()
+	done := make(chan error, 1)
+	go func() {
+		rows, err := db.Model(&user{}).Where("age > ?", 5).Rows()
+		if err == nil {
+			err = rows.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Rows() blocked on EXPLAIN")
+	}
+	if out := buf.String(); !strings.Contains(out, "observe: slow query") || strings.Contains(out, "SEARCH") || strings.Contains(out, "explain failed") {
+		t.Errorf("Rows() log:\n%s", out)
+	}
+
+	buf.Reset()
+	db, _, buf = openDB(t, Config{NPlusOne: -1})
+	db.Find(&users)
+	if buf.Len() != 0 {
+		t.Errorf("fast query logged: %s", buf)
+	}
+}
+
+func TestNPlusOne(t *testing.T) {
+	db, _, buf := openDB(t, Config{SlowThreshold: -1})
+	ctx := WithRequest(context.Background())
+
+	// 每个 id 查一次：典型的 N+1
+	for id := 1; id <= 8; id++ {
+		var u user
+		if err := db.WithContext(ctx).First(&u, id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 一次 IN 查询不算
+	var users []user
+	db.WithContext(ctx).Find(&users, []int{1, 2, 3})
+
+	st, ok := Report(ctx)
+	if !ok {
+		t.Fatal("Report() not ok")
+	}
+	if st.Queries != 9 {
+		t.Errorf("Queries = %d, want 9", st.Queries)
+	}
+	if len(st.NPlusOne) != 1 || !strings.Contains(st.NPlusOne[0], "`user3`.`id` = ?") {
+		t.Errorf("NPlusOne = %q", st.NPlusOne)
+	}
+	if n := strings.Count(buf.String(), "possible N+1"); n != 1 {
+		t.Errorf("N+1 logged %d times:\n%s", n, buf)
+	}
+	if !strings.Contains(buf.String(), "ran 5 times") {
+		t.Errorf("log = %s", buf)
+	}
+
+	// 没有 WithRequest 的 context 不检测
+	if _, ok := Report(context.Background()); ok {
+		t.Error("Report(Background) ok")
+	}
+}
+
+func TestShape(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"SELECT * FROM `user3` WHERE id IN (?,?,?)", "SELECT * FROM `user3` WHERE id IN (?)"},
+		{"SELECT *\n  FROM `user3`  WHERE id IN ( ? , ? )", "SELECT * FROM `user3` WHERE id IN (?)"},
+		{"INSERT INTO `user3` (`name`,`age`) VALUES (?,?),(?,?)", "INSERT INTO `user3` (`name`,`age`) VALUES (?),(?)"},
+	} {
+		if got := Shape(tc.in); got != tc.want {
+			t.Errorf("Shape(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	db, p, _ := openDB(t, Config{SlowThreshold: -1, NPlusOne: 2})
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for id := 1; id <= 3; id++ {
+			var u user
+			db.WithContext(r.Context()).First(&u, id)
+		}
+		db.WithContext(r.Context()).Model(&user{}).Where("id = ?", 1).Update("age", 100)
+		db.WithContext(r.Context()).Exec("UPDATE nope SET x = 1")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE gorm_queries_total counter",
+		`gorm_queries_total{op="query",table="user3"} 3`,
+		`gorm_queries_total{op="update",table="user3"} 1`,
+		`gorm_query_errors_total{op="raw",table=""} 1`,
+		`gorm_rows_affected_total{op="update",table="user3"} 1`,
+		`gorm_slow_queries_total{op="query",table="user3"} 0`,
+		"# TYPE gorm_query_duration_seconds histogram",
+		`gorm_query_duration_seconds_bucket{op="query",table="user3",le="+Inf"} 3`,
+		`gorm_query_duration_seconds_count{op="query",table="user3"} 3`,
+		`gorm_n_plus_one_total{table="user3"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+}
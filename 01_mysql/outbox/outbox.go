@@ -0,0 +1,155 @@
+// Package outbox 事务性发件箱：事件和业务数据在同一个事务里写入，再由 Relay 投递出去
+//
+// 直接在 Create 之后发消息有两个问题：事务回滚了消息却已经发出；或者提交了但进程在发消息之前崩溃，消息丢了。
+// 发件箱把"要发的消息"当作业务数据的一部分写进 outbox_messages 表，提交了就一定会被投递：
+//
+//	db.Use(outbox.NewPlugin().OnCreate(&model.User3{}, "user.created"))
+//	db.Create(&u) // 同一个事务里多写一行 outbox_messages
+//
+//	relay := outbox.NewRelay(db, outbox.NewBus())
+//	go relay.Run(ctx) // 轮询未发送的消息，发布成功后标记为已发送，失败按退避重试
+//
+// 投递语义是至少一次：发布成功但标记已发送之前崩溃，重启后会再发一次，消费方按 Message.ID 去重
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Message 发件箱里的一条消息
+type Message struct {
+	ID            uint64 `gorm:"primaryKey"`
+	Topic         string `gorm:"size:128;not null"`
+	AggregateID   string `gorm:"size:128;not null;default:''"` // 产生事件的记录，例如用户 ID
+	Payload       string `gorm:"not null"`                     // JSON
+	CreatedAt     time.Time
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	SentAt        *time.Time
+	LastError     string
+}
+
+func (Message) TableName() string { return "outbox_messages" }
+
+// Enqueue 在 tx 里写入一条消息，tx 应该是写业务数据的那个事务
+//
+//	db.Transaction(func(tx *gorm.DB) error {
+//		if err := tx.Model(&u).Update("age", 20).Error; err != nil {
+//			return err
+//		}
+//		return outbox.Enqueue(tx, "user.updated", strconv.Itoa(int(u.ID)), u)
+//	})
+func Enqueue(tx *gorm.DB, topic, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("outbox: marshal %s payload: %w", topic, err)
+	}
+	now := time.Now()
+	m := Message{Topic: topic, AggregateID: aggregateID, Payload: string(data), CreatedAt: now, NextAttemptAt: now}
+	return tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&m).Error
+}
+
+// Plugin 模型创建时自动写入事件，和 Create 在同一个事务里
+type Plugin struct {
+	created map[reflect.Type]string
+}
+
+// NewPlugin 创建插件，再用 OnCreate 注册模型
+func NewPlugin() *Plugin {
+	return &Plugin{created: map[reflect.Type]string{}}
+}
+
+// OnCreate model 创建后写入 topic 事件，payload 是创建后的记录（已经有主键和时间戳）
+func (p *Plugin) OnCreate(model any, topic string) *Plugin {
+	p.created[reflect.Indirect(reflect.ValueOf(model)).Type()] = topic
+	return p
+}
+
+func (p *Plugin) Name() string { return "outbox" }
+
+// Initialize 注册在 gorm:after_create 之后、提交之前，写失败时整个创建回滚
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:after_create").Before("gorm:commit_or_rollback_transaction").
+		Register("outbox:created", p.afterCreate)
+}
+
+func (p *Plugin) afterCreate(db *gorm.DB) {
+	s := db.Statement
+	if db.Error != nil || s.Schema == nil || db.RowsAffected == 0 {
+		return
+	}
+	topic, ok := p.created[s.Schema.ModelType]
+	if !ok {
+		return
+	}
+	var msgs []Message
+	now := time.Now()
+	each(s, func(id string, payload any) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			db.AddError(fmt.Errorf("outbox: marshal %s payload: %w", topic, err))
+			return
+		}
+		msgs = append(msgs, Message{Topic: topic, AggregateID: id, Payload: string(data), CreatedAt: now, NextAttemptAt: now})
+	})
+	if db.Error != nil || len(msgs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&msgs).Error; err != nil {
+		db.AddError(fmt.Errorf("outbox: enqueue %s: %w", topic, err))
+	}
+}
+
+// each 遍历本次创建的记录：结构体、结构体切片，以及 map 创建
+func each(s *gorm.Statement, fn func(id string, v any)) {
+	switch dest := s.Dest.(type) {
+	case map[string]any:
+		fn(mapID(s.Schema, dest), dest)
+		return
+	case *map[string]any:
+		fn(mapID(s.Schema, *dest), *dest)
+		return
+	case []map[string]any:
+		for _, m := range dest {
+			fn(mapID(s.Schema, m), m)
+		}
+		return
+	}
+	rv := reflect.Indirect(s.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Struct:
+		fn(structID(s, rv), rv.Addr().Interface())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			item := reflect.Indirect(rv.Index(i))
+			fn(structID(s, item), item.Addr().Interface())
+		}
+	}
+}
+
+func structID(s *gorm.Statement, rv reflect.Value) string {
+	var ids []string
+	for _, f := range s.Schema.PrimaryFields {
+		v, _ := f.ValueOf(s.Context, rv)
+		ids = append(ids, fmt.Sprint(v))
+	}
+	return strings.Join(ids, ",")
+}
+
+func mapID(s *schema.Schema, m map[string]any) string {
+	if f := s.PrioritizedPrimaryField; f != nil {
+		for _, k := range []string{f.DBName, f.Name} {
+			if v, ok := m[k]; ok {
+				return fmt.Sprint(v)
+			}
+		}
+	}
+	return ""
+}
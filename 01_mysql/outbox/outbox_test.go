@@ -0,0 +1,343 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/fixtures/fixturestest"
+	"mysql-demo/migrations"
+	"mysql-demo/model"
+	"mysql-demo/validate"
+)
+
+func use(t *testing.T, db *gorm.DB) *gorm.DB {
+	t.Helper()
+	if err := db.Use(NewPlugin().OnCreate(&model.User3{}, "user.created")); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// openFile 文件里的 SQLite，关掉再打开模拟进程重启
+func openFile(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, DSN: path, LogLevel: "silent", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(validate.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return use(t, db)
+}
+
+func closeDB(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	sqlDB, _ := db.DB()
+	if err := sqlDB.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func messages(t *testing.T, db *gorm.DB) []Message {
+	t.Helper()
+	var ms []Message
+	if err := db.Order("id").Find(&ms).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ms
+}
+
+func newRelay(db *gorm.DB, pub Publisher) *Relay {
+	r := NewRelay(db, pub)
+	r.Logger = log.New(io.Discard, "", 0)
+	return r
+}
+
+// 消息和业务数据一起提交、一起回滚
+func TestAtomic(t *testing.T) {
+	db, _ := fixturestest.Open(t)
+	use(t, db)
+
+	u := model.User3{Name: "张三", Age: 20}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	ms := messages(t, db)
+	if len(ms) != 1 || ms[0].Topic != "user.created" || ms[0].AggregateID != strconv.Itoa(int(u.ID)) || ms[0].SentAt != nil {
+		t.Fatalf("messages = %+v", ms)
+	}
+	var payload model.User3
+	if err := json.Unmarshal([]byte(ms[0].Payload), &payload); err != nil || payload.ID != u.ID || payload.Name != "张三" {
+		t.Errorf("payload = %s, %v", ms[0].Payload, err)
+	}
+
+	// 批量创建每条一个事件
+	if err := db.Create([]model.User3{{Name: "a", Age: 20}, {Name: "b", Age: 21}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(messages(t, db)); n != 3 {
+		t.Errorf("after batch create %d messages, want 3", n)
+	}
+
+	// 事务回滚：用户和消息都不在
+	rollback := errors.New("rollback")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&model.User3{Name: "李四", Age: 30}).Error; err != nil {
+			return err
+		}
+		if err := Enqueue(tx, "user.welcome", "", map[string]string{"name": "李四"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	// 校验失败的创建也不会写消息
+	if err := db.Create(&model.User3{Name: "小孩", Age: 1}).Error; err == nil {
+		t.Fatal("create with age 1 should fail validation")
+	}
+	var users int64
+	db.Model(&model.User3{}).Count(&users)
+	if n := len(messages(t, db)); n != 3 || users != 3 {
+		t.Errorf("after rollback: %d messages, %d users, want 3 and 3", n, users)
+	}
+}
+
+func TestRelay(t *testing.T) {
+	db, _ := fixturestest.Open(t)
+	use(t, db)
+	bus := NewBus()
+	created, all := bus.Subscribe("user.created", 10), bus.Subscribe("*", 10)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := db.Create(&model.User3{Name: name, Age: 20}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := newRelay(db, bus)
+	r.Batch = 2
+	ctx := context.Background()
+	for _, want := range []int{2, 1, 0} {
+		if n, err := r.Poll(ctx); err != nil || n != want {
+			t.Fatalf("Poll() = %d, %v, want %d", n, err, want)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		if m := <-created; m.ID != uint64(i) {
+			t.Errorf("message %d out of order: %+v", i, m)
+		}
+		<-all
+	}
+	for _, m := range messages(t, db) {
+		if m.SentAt == nil || m.Attempts != 0 {
+			t.Errorf("not marked sent: %+v", m)
+		}
+	}
+
+	// Run 在后台投递新消息，ctx 结束时返回
+	r.Interval = 10 * time.Millisecond
+	rctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- r.Run(rctx) }()
+	if err := db.Create(&model.User3{Name: "d", Age: 20}).Error; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-created:
+		if m.ID != 4 {
+			t.Errorf("Run delivered %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not deliver")
+	}
+	// 收到消息时 sent_at 可能还没提交；取消不会打断这次提交，Run 返回时已经标记好了
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v", err)
+	}
+	for _, m := range messages(t, db) {
+		if m.SentAt == nil {
+			t.Errorf("not marked sent after Run: %+v", m)
+		}
+	}
+
+	n, err := r.Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil || n != 4 {
+		t.Errorf("Purge() = %d, %v", n, err)
+	}
+}
+
+// 发布失败按退避重试，没到时间不会再领取
+func TestRetry(t *testing.T) {
+	db, _ := fixturestest.Open(t)
+	use(t, db)
+	if err := db.Create(&model.User3{Name: "张三", Age: 20}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	r := newRelay(db, PublisherFunc(func(context.Context, Message) error {
+		if calls++; calls <= 2 {
+			return errors.New("broker down")
+		}
+		return nil
+	}))
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	poll := func(want int) Message {
+		t.Helper()
+		if n, err := r.Poll(ctx); err != nil || n != want {
+			t.Fatalf("Poll() = %d, %v, want %d", n, err, want)
+		}
+		return messages(t, db)[0]
+	}
+
+	m := poll(1)
+	if m.Attempts != 1 || m.LastError != "broker down" || m.SentAt != nil || !m.NextAttemptAt.Equal(now.Add(time.Second)) {
+		t.Fatalf("after 1st failure: %+v", m)
+	}
+	poll(0) // 还没到重试时间
+
+	now = now.Add(time.Second)
+	if m = poll(1); m.Attempts != 2 || !m.NextAttemptAt.Equal(now.Add(2*time.Second)) {
+		t.Fatalf("after 2nd failure: %+v", m)
+	}
+	now = now.Add(2 * time.Second)
+	if m = poll(1); m.SentAt == nil || m.Attempts != 2 || calls != 3 {
+		t.Fatalf("after success: %+v, calls = %d", m, calls)
+	}
+
+	backoff := Exponential(time.Second, time.Minute)
+	for attempt, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 7: time.Minute, 100: time.Minute} {
+		if got := backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+// 发布之后、标记已发送之前进程崩溃：重启后重新投递，消息不会丢（可能重复）
+func TestCrashRecovery(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, out := filepath.Join(dir, "app.db"), filepath.Join(dir, "events.jsonl")
+
+	db := openFile(t, dbPath)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := db.Create(&model.User3{Name: name, Age: 20}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := NewFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := 0
+	crashing := newRelay(db, PublisherFunc(func(ctx context.Context, m Message) error {
+		if err := file.Publish(ctx, m); err != nil {
+			return err
+		}
+		if published++; published == 2 {
+			panic("crash")
+		}
+		return nil
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("relay did not crash")
+			}
+		}()
+		crashing.Poll(context.Background())
+	}()
+	file.Close()
+	closeDB(t, db)
+
+	// 重启
+	db = openFile(t, dbPath)
+	defer closeDB(t, db)
+	for _, m := range messages(t, db) {
+		if m.SentAt != nil {
+			t.Fatalf("marked sent before crash: %+v", m)
+		}
+	}
+	file, err = NewFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if n, err := newRelay(db, file).Poll(context.Background()); err != nil || n != 3 {
+		t.Fatalf("Poll() after restart = %d, %v", n, err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	seen := map[uint64]int{}
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var e Envelope
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || e.Topic != "user.created" {
+			t.Fatalf("bad line %s: %v", sc.Text(), err)
+		}
+		seen[e.ID]++
+	}
+	// 1、2 在崩溃前已经发出，重启后又发了一次
+	if seen[1] != 2 || seen[2] != 2 || seen[3] != 1 {
+		t.Errorf("deliveries = %v", seen)
+	}
+	for _, m := range messages(t, db) {
+		if m.SentAt == nil {
+			t.Errorf("not sent after restart: %+v", m)
+		}
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		var e Envelope
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil || r.Header.Get("X-Outbox-Topic") != e.Topic {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if len(keys) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	pub := &HTTP{URL: srv.URL}
+	m := Message{ID: 7, Topic: "user.created", Payload: `{"name":"张三"}`}
+	err := pub.Publish(context.Background(), m)
+	if err == nil || err.Error() != "outbox: POST "+srv.URL+": 503 Service Unavailable: try later" {
+		t.Errorf("first Publish() = %v", err)
+	}
+	if err := pub.Publish(context.Background(), m); err != nil {
+		t.Errorf("second Publish() = %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "7" || keys[1] != "7" {
+		t.Errorf("Idempotency-Key = %v", keys)
+	}
+}
@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Publisher 把消息发到下游，返回 nil 表示下游已经收到；返回错误时 Relay 按退避重试
+// 同一条消息可能被发布多次（至少一次），实现不需要去重
+type Publisher interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+// PublisherFunc 让普通函数实现 Publisher
+type PublisherFunc func(ctx context.Context, m Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, m Message) error { return f(ctx, m) }
+
+// Envelope 消息在文件和 HTTP 里的格式，payload 原样嵌入
+type Envelope struct {
+	ID          uint64          `json:"id"`
+	Topic       string          `json:"topic"`
+	AggregateID string          `json:"aggregate_id,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func envelope(m Message) Envelope {
+	return Envelope{ID: m.ID, Topic: m.Topic, AggregateID: m.AggregateID, Payload: json.RawMessage(m.Payload), CreatedAt: m.CreatedAt}
+}
+
+// Bus 进程内的消息总线，按 topic 订阅
+//
+//	bus := outbox.NewBus()
+//	ch := bus.Subscribe("user.created", 16)
+//	go func() { for m := range ch { ... } }()
+//
+// Publish 在订阅者的 channel 满时等待，直到放进去或者 ctx 结束（这时返回错误，Relay 会重试）
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]chan Message
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[string][]chan Message{}}
+}
+
+// Subscribe 订阅 topic，"*" 订阅全部
+func (b *Bus) Subscribe(topic string, buffer int) <-chan Message {
+	ch := make(chan Message, buffer)
+	b.mu.Lock()
+	b.subs[topic] = append(b.subs[topic], ch)
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *Bus) Publish(ctx context.Context, m Message) error {
+	b.mu.RLock()
+	subs := append(append([]chan Message(nil), b.subs[m.Topic]...), b.subs["*"]...)
+	b.mu.RUnlock()
+	for _, ch := range subs {
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// File 把消息按 JSON Lines 追加到文件，每条写完后 fsync
+type File struct {
+	mu   sync.Mutex
+	f    *os.File
+	sync bool
+}
+
+// NewFile 打开（或创建）文件用于追加
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f, sync: true}, nil
+}
+
+func (p *File) Publish(_ context.Context, m Message) error {
+	line, err := json.Marshal(envelope(m))
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if p.sync {
+		return p.f.Sync()
+	}
+	return nil
+}
+
+func (p *File) Close() error { return p.f.Close() }
+
+// HTTP 把消息 POST 到 URL，body 是 Envelope，2xx 表示成功
+// Idempotency-Key 头是消息 ID，接收方用它去重
+type HTTP struct {
+	URL    string
+	Client *http.Client // 默认 10 秒超时
+	Header http.Header  // 额外的请求头，例如鉴权
+}
+
+func (p *HTTP) Publish(ctx context.Context, m Message) error {
+	body, err := json.Marshal(envelope(m))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range p.Header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatUint(m.ID, 10))
+	req.Header.Set("X-Outbox-Topic", m.Topic)
+
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("outbox: POST %s: %s: %s", p.URL, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/lock"
+)
+
+// Relay 轮询 outbox_messages，把未发送的消息交给 Publisher
+//
+// 每批消息用 lock.Claim 领取（FOR UPDATE SKIP LOCKED），多个进程同时跑 Relay 也不会重复领取；
+// 发布成功标记 sent_at，失败时 attempts+1，按 Backoff 推迟 next_attempt_at 再试
+type Relay struct {
+	db  *gorm.DB
+	pub Publisher
+
+	Batch    int           // 每次领取的条数，默认 100
+	Interval time.Duration // 没有消息时的轮询间隔，默认 1 秒
+	// Backoff 第 attempt 次失败后等多久再试，默认 1s、2s、4s……最长 10 分钟
+	Backoff func(attempt int) time.Duration
+	// Logger 默认 log.Default()
+	Logger *log.Logger
+
+	now func() time.Time
+}
+
+func NewRelay(db *gorm.DB, pub Publisher) *Relay {
+	return &Relay{
+		db:       db,
+		pub:      pub,
+		Batch:    100,
+		Interval: time.Second,
+		Backoff:  Exponential(time.Second, 10*time.Minute),
+		Logger:   log.Default(),
+		now:      time.Now,
+	}
+}
+
+// Exponential base * 2^(attempt-1)，不超过 max
+func Exponential(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		return min(d, max)
+	}
+}
+
+// Run 持续投递直到 ctx 结束；一批领满时立即领下一批，否则等 Interval
+func (r *Relay) Run(ctx context.Context) error {
+	t := time.NewTicker(r.Interval)
+	defer t.Stop()
+	for {
+		n, err := r.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.Logger.Printf("outbox: poll: %v", err)
+		}
+		if err == nil && n == r.Batch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Poll 领取一批到期的消息并发布，返回领取的条数（包括发布失败的）
+// 单条发布失败不会中断这一批，只在读写数据库出错时返回错误
+//
+// ctx 只控制发布：ctx 结束后不再发布这一批剩下的消息，但已经发出去的照常标记 sent_at 并提交，
+// 否则事务被取消、回滚，这些消息下次会再发一次
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	now := r.now()
+	due := func(db *gorm.DB) *gorm.DB {
+		return db.Where("sent_at IS NULL AND next_attempt_at <= ?", now).Order("id")
+	}
+	return lock.Claim(context.WithoutCancel(ctx), r.db, r.Batch, due, func(tx *gorm.DB, msgs []Message) error {
+		for _, m := range msgs {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := r.deliver(ctx, tx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Relay) deliver(ctx context.Context, tx *gorm.DB, m Message) error {
+	q := tx.Model(&Message{}).Where("id = ?", m.ID)
+	perr := r.pub.Publish(ctx, m)
+	if perr == nil {
+		return q.Update("sent_at", r.now()).Error
+	}
+	attempt := m.Attempts + 1
+	wait := r.Backoff(attempt)
+	r.Logger.Printf("outbox: publish %s #%d failed (attempt %d), retry in %v: %v", m.Topic, m.ID, attempt, wait, perr)
+	return q.Updates(map[string]any{
+		"attempts":        attempt,
+		"next_attempt_at": r.now().Add(wait),
+		"last_error":      truncate(perr.Error(), 1024),
+	}).Error
+}
+
+// Purge 删除 sent_at 早于 before 的已发送消息，返回删除的条数
+func (r *Relay) Purge(ctx context.Context, before time.Time) (int64, error) {
+	tx := r.db.WithContext(ctx).Where("sent_at IS NOT NULL AND sent_at < ?", before).Delete(&Message{})
+	if tx.Error != nil {
+		return 0, fmt.Errorf("outbox: purge: %w", tx.Error)
+	}
+	return tx.RowsAffected, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
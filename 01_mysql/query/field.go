@@ -0,0 +1,139 @@
+package query
+
+import (
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// Cond 查询条件，可以直接传给 gorm 的 Where / Or / Not
+type Cond = clause.Expression
+
+// Expr 字段描述，Select、Omit、Order 里用
+type Expr interface {
+	// Name 结构体字段名，gorm 的 Select / Omit 认这个名字
+	Name() string
+	// Column 列，表名是 clause.CurrentTable，执行时换成语句实际操作的表（包括加了租户前缀的表）
+	Column() clause.Column
+}
+
+// Field 类型为 T 的字段，条件的参数类型和字段类型一致，写错类型编译不通过
+//
+//	q.User3.Age.Gt(18)      // age > 18
+//	q.User3.Age.Gt("18")    // 编译错误
+type Field[T any] struct {
+	name   string
+	column string
+}
+
+// NewField 由生成的代码调用
+func NewField[T any](name, column string) Field[T] {
+	return Field[T]{name: name, column: column}
+}
+
+func (f Field[T]) Name() string { return f.name }
+
+func (f Field[T]) Column() clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: f.column}
+}
+
+func (f Field[T]) Eq(v T) Cond  { return clause.Eq{Column: f.Column(), Value: v} }
+func (f Field[T]) Neq(v T) Cond { return clause.Neq{Column: f.Column(), Value: v} }
+func (f Field[T]) Gt(v T) Cond  { return clause.Gt{Column: f.Column(), Value: v} }
+func (f Field[T]) Gte(v T) Cond { return clause.Gte{Column: f.Column(), Value: v} }
+func (f Field[T]) Lt(v T) Cond  { return clause.Lt{Column: f.Column(), Value: v} }
+func (f Field[T]) Lte(v T) Cond { return clause.Lte{Column: f.Column(), Value: v} }
+
+// In 没有参数时是恒假的条件（gorm 会生成 IN (NULL)）
+func (f Field[T]) In(vs ...T) Cond { return clause.IN{Column: f.Column(), Values: values(vs)} }
+
+func (f Field[T]) NotIn(vs ...T) Cond { return clause.Not(f.In(vs...)) }
+
+// Between 闭区间 [lo, hi]
+func (f Field[T]) Between(lo, hi T) Cond {
+	return clause.And(f.Gte(lo), f.Lte(hi))
+}
+
+func (f Field[T]) IsNull() Cond  { return clause.Eq{Column: f.Column(), Value: nil} }
+func (f Field[T]) NotNull() Cond { return clause.Neq{Column: f.Column(), Value: nil} }
+
+func (f Field[T]) Asc() clause.OrderByColumn { return clause.OrderByColumn{Column: f.Column()} }
+func (f Field[T]) Desc() clause.OrderByColumn {
+	return clause.OrderByColumn{Column: f.Column(), Desc: true}
+}
+
+// Value 更新时的赋值，见 Table.Update
+func (f Field[T]) Value(v T) Assign { return Assign{column: f.column, value: v} }
+
+// Expr 用 SQL 表达式赋值，例如 q.User3.Age.Expr(gorm.Expr("age + ?", 1))
+func (f Field[T]) Expr(e clause.Expr) Assign { return Assign{column: f.column, value: e} }
+
+// String 字符串字段，比 Field 多了 LIKE
+type String struct {
+	Field[string]
+}
+
+func NewString(name, column string) String {
+	return String{NewField[string](name, column)}
+}
+
+// Like pattern 原样传给数据库，% 和 _ 需要调用方自己加
+func (f String) Like(pattern string) Cond {
+	return clause.Like{Column: f.Column(), Value: pattern}
+}
+
+// HasPrefix / Contains 参数里的 % 和 _ 按普通字符匹配
+func (f String) HasPrefix(prefix string) Cond {
+	return likeEscaped{f.Column(), escapeLike(prefix) + "%"}
+}
+func (f String) Contains(sub string) Cond {
+	return likeEscaped{f.Column(), "%" + escapeLike(sub) + "%"}
+}
+
+// Assign 一个字段的新值
+type Assign struct {
+	column string
+	value  any
+}
+
+// And / Or / Not 组合条件
+func And(conds ...Cond) Cond { return clause.And(conds...) }
+func Or(conds ...Cond) Cond  { return clause.Or(conds...) }
+func Not(conds ...Cond) Cond { return clause.Not(conds...) }
+
+// Names 字段名列表，用于原生的 db.Select / db.Omit
+func Names(fields ...Expr) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+func values[T any](vs []T) []any {
+	out := make([]any, len(vs))
+	for i, v := range vs {
+		out[i] = v
+	}
+	return out
+}
+
+// likeEscape 转义符用 !：mysql 和 sqlite 的字符串字面量里都不需要再转义它（\ 在两边写法不同）
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+func escapeLike(s string) string { return likeEscaper.Replace(s) }
+
+// likeEscaped column LIKE ? ESCAPE '!'
+type likeEscaped struct {
+	column  clause.Column
+	pattern string
+}
+
+func (l likeEscaped) Build(b clause.Builder) {
+	b.WriteQuoted(l.column)
+	b.WriteString(" LIKE ")
+	b.AddVar(b, l.pattern)
+	b.WriteString(" ESCAPE '" + likeEscape + "'")
+}
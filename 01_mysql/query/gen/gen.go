@@ -0,0 +1,205 @@
+// Package gen 生成 query 包里每个模型的字段描述，由 cmd/querygen 调用
+//
+// 用 gorm 自己的 schema.Parse 解析模型，所以字段、列名、表名和运行时完全一致；
+// 生成的代码对每个字段有一行编译期引用（var _ T = m.Name），模型字段改名、删除或改类型而没有重新生成时
+// query 包编译失败；重新生成之后，用到旧字段的地方编译失败
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gorm.io/gorm/schema"
+)
+
+// Reserved query.Table 的方法名，模型字段不能用（嵌入之后会被字段遮住）
+// 不直接反射 query.Table：query 包编译不过时（正是需要重新生成的时候）生成器也要能运行
+var Reserved = []string{
+	"Count", "Create", "DB", "Delete", "Find", "First", "Limit", "Offset",
+	"Omit", "Or", "Order", "Scopes", "Select", "Take", "Unscoped", "Update", "Where",
+}
+
+type field struct {
+	Name   string // 结构体字段名
+	GoType string // 结构体里字段的类型，用于编译期检查
+	Column string
+	Type   string // 描述的类型：String 或 Field[T]
+	Ctor   string // 构造函数调用
+}
+
+type modelInfo struct {
+	Name   string // 结构体名
+	Type   string // model.User3
+	Table  string
+	Fields []field
+}
+
+// Generate 生成 query 包的代码，models 是结构体值，例如 model.User3{}
+func Generate(models ...any) ([]byte, error) {
+	reserved := map[string]bool{}
+	for _, name := range Reserved {
+		reserved[name] = true
+	}
+	imports := map[string]string{"gorm.io/gorm": "gorm"}
+	cache := &sync.Map{}
+
+	var infos []modelInfo
+	seen := map[string]bool{}
+	for _, m := range models {
+		s, err := schema.Parse(m, cache, schema.NamingStrategy{})
+		if err != nil {
+			return nil, fmt.Errorf("gen: parse %T: %w", m, err)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("gen: duplicate model %s", s.Name)
+		}
+		seen[s.Name] = true
+
+		info := modelInfo{Name: s.Name, Type: typeExpr(s.ModelType, imports), Table: s.Table}
+		for _, f := range s.Fields {
+			if f.DBName == "" {
+				continue // 关联字段、gorm:"-"
+			}
+			if reserved[f.Name] || f.Name == "Table" {
+				return nil, fmt.Errorf("gen: %s.%s clashes with query.Table.%s, rename the field", s.Name, f.Name, f.Name)
+			}
+			t := f.FieldType
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem() // *time.Time 的条件参数是 time.Time，NULL 用 IsNull
+			}
+			fd := field{Name: f.Name, GoType: typeExpr(f.FieldType, imports), Column: f.DBName}
+			if t.Kind() == reflect.String && t.PkgPath() == "" {
+				fd.Type = "String"
+				fd.Ctor = fmt.Sprintf("NewString(%q, %q)", f.Name, f.DBName)
+			} else {
+				expr := typeExpr(t, imports)
+				fd.Type = "Field[" + expr + "]"
+				fd.Ctor = fmt.Sprintf("NewField[%s](%q, %q)", expr, f.Name, f.DBName)
+			}
+			info.Fields = append(info.Fields, fd)
+		}
+		infos = append(infos, info)
+	}
+
+	// import 分三组：标准库、第三方、模型所在的模块
+	own := map[string]bool{}
+	for _, m := range models {
+		first, _, _ := strings.Cut(reflect.Indirect(reflect.ValueOf(m)).Type().PkgPath(), "/")
+		own[first] = true
+	}
+	groups := make([][]string, 3)
+	for p := range imports {
+		first, _, _ := strings.Cut(p, "/")
+		switch {
+		case own[first]:
+			groups[2] = append(groups[2], p)
+		case strings.Contains(first, "."):
+			groups[1] = append(groups[1], p)
+		default:
+			groups[0] = append(groups[0], p)
+		}
+	}
+	var importGroups [][]string
+	for _, g := range groups {
+		if len(g) > 0 {
+			sort.Strings(g)
+			importGroups = append(importGroups, g)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]any{"Imports": importGroups, "Models": infos}); err != nil {
+		return nil, err
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: format: %w\n%s", err, buf.Bytes())
+	}
+	return out, nil
+}
+
+// typeExpr reflect.Type 在生成的代码里的写法，顺便记下需要的 import
+func typeExpr(t reflect.Type, imports map[string]string) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		alias := path.Base(t.PkgPath())
+		imports[t.PkgPath()] = alias
+		return alias + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeExpr(t.Elem(), imports)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "[]byte"
+		}
+		return "[]" + typeExpr(t.Elem(), imports)
+	case reflect.Map:
+		return "map[" + typeExpr(t.Key(), imports) + "]" + typeExpr(t.Elem(), imports)
+	}
+	return t.String()
+}
+
+var tmpl = template.Must(template.New("query").Parse(`// Code generated by cmd/querygen. DO NOT EDIT.
+
+package query
+
+import (
+{{- range $i, $g := .Imports}}
+{{- if $i}}
+{{end}}
+{{- range $g}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+// Query 所有模型的查询入口
+type Query struct {
+{{- range .Models}}
+	{{.Name}} {{.Name}}Query
+{{- end}}
+}
+
+// Use 绑定数据库；db 为 nil 时只能用字段拼条件，不能执行
+func Use(db *gorm.DB) *Query {
+	return &Query{
+{{- range .Models}}
+		{{.Name}}: new{{.Name}}Query(db),
+{{- end}}
+	}
+}
+{{range $m := .Models}}
+// {{$m.Name}}Query {{$m.Type}}，表 {{$m.Table}}
+type {{$m.Name}}Query struct {
+	Table[{{$m.Type}}]
+{{range $m.Fields}}
+	{{.Name}} {{.Type}}
+{{- end}}
+}
+
+func new{{$m.Name}}Query(db *gorm.DB) {{$m.Name}}Query {
+	return {{$m.Name}}Query{
+		Table: NewTable[{{$m.Type}}](db),
+{{- range $m.Fields}}
+		{{.Name}}: {{.Ctor}},
+{{- end}}
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m {{$m.Type}}) {
+{{- range $m.Fields}}
+	var _ {{.GoType}} = m.{{.Name}}
+{{- end}}
+}
+{{end}}`))
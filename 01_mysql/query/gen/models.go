@@ -0,0 +1,12 @@
+package gen
+
+import "mysql-demo/model"
+
+// Models 生成查询代码的模型，model 里新增结构体后加到这里
+var Models = []any{
+	model.User{},
+	model.User3{},
+	model.Profile{},
+	model.Order{},
+	model.Role{},
+}
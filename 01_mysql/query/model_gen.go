@@ -0,0 +1,193 @@
+// Code generated by cmd/querygen. DO NOT EDIT.
+
+package query
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/lock"
+	"mysql-demo/model"
+)
+
+// Query 所有模型的查询入口
+type Query struct {
+	User    UserQuery
+	User3   User3Query
+	Profile ProfileQuery
+	Order   OrderQuery
+	Role    RoleQuery
+}
+
+// Use 绑定数据库；db 为 nil 时只能用字段拼条件，不能执行
+func Use(db *gorm.DB) *Query {
+	return &Query{
+		User:    newUserQuery(db),
+		User3:   newUser3Query(db),
+		Profile: newProfileQuery(db),
+		Order:   newOrderQuery(db),
+		Role:    newRoleQuery(db),
+	}
+}
+
+// UserQuery model.User，表 02_user
+type UserQuery struct {
+	Table[model.User]
+
+	ID   Field[uint]
+	Name String
+}
+
+func newUserQuery(db *gorm.DB) UserQuery {
+	return UserQuery{
+		Table: NewTable[model.User](db),
+		ID:    NewField[uint]("ID", "id"),
+		Name:  NewString("Name", "name"),
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m model.User) {
+	var _ uint = m.ID
+	var _ string = m.Name
+}
+
+// User3Query model.User3，表 user3
+type User3Query struct {
+	Table[model.User3]
+
+	ID        Field[uint]
+	CreatedAt Field[time.Time]
+	UpdatedAt Field[time.Time]
+	DeletedAt Field[gorm.DeletedAt]
+	Name      String
+	Age       Field[int]
+	Birthday  Field[time.Time]
+	Version   Field[lock.Version]
+}
+
+func newUser3Query(db *gorm.DB) User3Query {
+	return User3Query{
+		Table:     NewTable[model.User3](db),
+		ID:        NewField[uint]("ID", "id"),
+		CreatedAt: NewField[time.Time]("CreatedAt", "created_at"),
+		UpdatedAt: NewField[time.Time]("UpdatedAt", "updated_at"),
+		DeletedAt: NewField[gorm.DeletedAt]("DeletedAt", "deleted_at"),
+		Name:      NewString("Name", "name"),
+		Age:       NewField[int]("Age", "age"),
+		Birthday:  NewField[time.Time]("Birthday", "birthday"),
+		Version:   NewField[lock.Version]("Version", "version"),
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m model.User3) {
+	var _ uint = m.ID
+	var _ time.Time = m.CreatedAt
+	var _ time.Time = m.UpdatedAt
+	var _ gorm.DeletedAt = m.DeletedAt
+	var _ string = m.Name
+	var _ int = m.Age
+	var _ *time.Time = m.Birthday
+	var _ lock.Version = m.Version
+}
+
+// ProfileQuery model.Profile，表 user3_profiles
+type ProfileQuery struct {
+	Table[model.Profile]
+
+	ID        Field[uint]
+	CreatedAt Field[time.Time]
+	UpdatedAt Field[time.Time]
+	DeletedAt Field[gorm.DeletedAt]
+	UserID    Field[uint]
+	Bio       String
+	Avatar    String
+}
+
+func newProfileQuery(db *gorm.DB) ProfileQuery {
+	return ProfileQuery{
+		Table:     NewTable[model.Profile](db),
+		ID:        NewField[uint]("ID", "id"),
+		CreatedAt: NewField[time.Time]("CreatedAt", "created_at"),
+		UpdatedAt: NewField[time.Time]("UpdatedAt", "updated_at"),
+		DeletedAt: NewField[gorm.DeletedAt]("DeletedAt", "deleted_at"),
+		UserID:    NewField[uint]("UserID", "user_id"),
+		Bio:       NewString("Bio", "bio"),
+		Avatar:    NewString("Avatar", "avatar"),
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m model.Profile) {
+	var _ uint = m.ID
+	var _ time.Time = m.CreatedAt
+	var _ time.Time = m.UpdatedAt
+	var _ gorm.DeletedAt = m.DeletedAt
+	var _ uint = m.UserID
+	var _ string = m.Bio
+	var _ string = m.Avatar
+}
+
+// OrderQuery model.Order，表 user3_orders
+type OrderQuery struct {
+	Table[model.Order]
+
+	ID        Field[uint]
+	CreatedAt Field[time.Time]
+	UpdatedAt Field[time.Time]
+	DeletedAt Field[gorm.DeletedAt]
+	UserID    Field[uint]
+	No        String
+	Amount    Field[int64]
+	Status    String
+}
+
+func newOrderQuery(db *gorm.DB) OrderQuery {
+	return OrderQuery{
+		Table:     NewTable[model.Order](db),
+		ID:        NewField[uint]("ID", "id"),
+		CreatedAt: NewField[time.Time]("CreatedAt", "created_at"),
+		UpdatedAt: NewField[time.Time]("UpdatedAt", "updated_at"),
+		DeletedAt: NewField[gorm.DeletedAt]("DeletedAt", "deleted_at"),
+		UserID:    NewField[uint]("UserID", "user_id"),
+		No:        NewString("No", "no"),
+		Amount:    NewField[int64]("Amount", "amount"),
+		Status:    NewString("Status", "status"),
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m model.Order) {
+	var _ uint = m.ID
+	var _ time.Time = m.CreatedAt
+	var _ time.Time = m.UpdatedAt
+	var _ gorm.DeletedAt = m.DeletedAt
+	var _ uint = m.UserID
+	var _ string = m.No
+	var _ int64 = m.Amount
+	var _ string = m.Status
+}
+
+// RoleQuery model.Role，表 roles
+type RoleQuery struct {
+	Table[model.Role]
+
+	ID   Field[uint]
+	Name String
+}
+
+func newRoleQuery(db *gorm.DB) RoleQuery {
+	return RoleQuery{
+		Table: NewTable[model.Role](db),
+		ID:    NewField[uint]("ID", "id"),
+		Name:  NewString("Name", "name"),
+	}
+}
+
+// 模型字段改名、删除或改类型后这里编译不通过，需要重新生成
+var _ = func(m model.Role) {
+	var _ uint = m.ID
+	var _ string = m.Name
+}
@@ -0,0 +1,19 @@
+// Package query 从 model 里的结构体生成的类型安全查询
+//
+// 课程里的查询都用字符串写列名，db.Select("Name", "Age")、db.Where("age > ?", 18)，
+// 字段改了名字、写错了拼写，只有执行到那一行才报错。这里每个字段都是一个带类型的变量：
+//
+//	q := query.Use(db)
+//	users, err := q.User3.Where(q.User3.Age.Gt(18), q.User3.Name.HasPrefix("张")).
+//		Order(q.User3.Age.Desc()).Limit(10).Find(ctx)
+//
+//	// 也可以只用条件，配合原生的 gorm
+//	db.Where(q.User3.Age.Between(18, 30)).Select(query.Names(q.User3.Name, q.User3.Age)).Find(&users)
+//
+// 结构体字段改名、删除或改类型后，没有重新生成时 query 包编译不通过；重新生成后，用到旧名字的地方编译不通过。
+// 字段、表名和 gorm 解析出来的完全一致（包括嵌入的 gorm.Model 和 column 标签），关联字段不生成。
+//
+// model 里新增模型后，把它加到 query/gen/models.go 的 gen.Models 里再执行 go generate ./query
+package query
+
+//go:generate go run ../cmd/querygen -out model_gen.go
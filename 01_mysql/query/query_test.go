@@ -0,0 +1,144 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/fixtures/fixturestest"
+	"mysql-demo/model"
+	"mysql-demo/query/gen"
+)
+
+// 改了 model 没有重新生成时失败
+func TestGeneratedUpToDate(t *testing.T) {
+	want, err := gen.Generate(gen.Models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("model_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("model_gen.go is out of date, run go generate ./query")
+	}
+}
+
+func TestReservedNames(t *testing.T) {
+	var methods []string
+	tt := reflect.TypeFor[Table[model.User]]()
+	for i := range tt.NumMethod() {
+		methods = append(methods, tt.Method(i).Name)
+	}
+	if !slices.Equal(methods, gen.Reserved) {
+		t.Errorf("gen.Reserved = %v, Table methods = %v", gen.Reserved, methods)
+	}
+
+	type bad struct {
+		ID    uint
+		Order int
+	}
+	if _, err := gen.Generate(bad{}); err == nil || !strings.Contains(err.Error(), "bad.Order clashes") {
+		t.Errorf("Generate(bad) = %v", err)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	db, _ := fixturestest.Open(t)
+	q := Use(nil) // 只拼条件
+	u := q.User3
+
+	stmt := db.Session(&gorm.Session{DryRun: true}).
+		Where(u.Age.Between(18, 30), u.Name.Contains("50%_off")).
+		Or(u.ID.In(1, 2), u.Birthday.IsNull()).
+		Order(u.Age.Desc()).
+		Find(&[]model.User3{}).Statement
+	want := "SELECT * FROM `user3` WHERE (((`user3`.`age` >= ? AND `user3`.`age` <= ?) AND `user3`.`name` LIKE ? ESCAPE '!') " +
+		"OR (`user3`.`id` IN (?,?) AND `user3`.`birthday` IS NULL)) AND `user3`.`deleted_at` IS NULL ORDER BY `user3`.`age` DESC"
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("SQL =\n%s\nwant\n%s", got, want)
+	}
+	if got := stmt.Vars[2]; got != "%50!%!_off%" {
+		t.Errorf("escaped pattern = %v", got)
+	}
+
+	if _, err := u.Find(context.Background()); !errors.Is(err, ErrNoDB) {
+		t.Errorf("Find without db = %v", err)
+	}
+	if got := Names(u.Name, u.Age); strings.Join(got, ",") != "Name,Age" {
+		t.Errorf("Names() = %v", got)
+	}
+}
+
+func TestTable(t *testing.T) {
+	db, _ := fixturestest.Open(t, "users")
+	ctx := context.Background()
+	q := Use(db)
+	u := q.User3
+
+	adults := u.Where(u.Age.Gte(60))
+	n, err := adults.Count(ctx)
+	if err != nil || n == 0 {
+		t.Fatalf("Count() = %d, %v", n, err)
+	}
+	// Table 不可变，后面的 Limit 不影响 adults
+	top, err := adults.Order(u.Age.Desc(), u.ID.Asc()).Limit(3).Find(ctx)
+	if err != nil || len(top) != min(3, int(n)) {
+		t.Fatalf("Find() = %d rows, %v", len(top), err)
+	}
+	if all, _ := adults.Find(ctx); int64(len(all)) != n {
+		t.Errorf("adults changed after Limit: %d rows, want %d", len(all), n)
+	}
+	for i := 1; i < len(top); i++ {
+		if top[i].Age > top[i-1].Age || top[i].Age < 60 {
+			t.Errorf("not ordered by age desc: %+v", top)
+		}
+	}
+
+	// Select 只查部分字段
+	first, err := u.Select(u.ID, u.Name).Where(u.ID.Eq(top[0].ID)).First(ctx)
+	if err != nil || first.Name != top[0].Name || first.Age != 0 {
+		t.Errorf("Select().First() = %+v, %v", first, err)
+	}
+
+	// 类型安全的更新
+	rows, err := u.Where(u.ID.Eq(top[0].ID)).Update(ctx, u.Name.Value("改名"), u.Age.Expr(gorm.Expr("age + ?", 1)))
+	if err != nil || rows != 1 {
+		t.Fatalf("Update() = %d, %v", rows, err)
+	}
+	got, _ := u.Where(u.ID.Eq(top[0].ID)).Take(ctx)
+	if got.Name != "改名" || got.Age != top[0].Age+1 {
+		t.Errorf("after update: %+v", got)
+	}
+	if _, err := u.Update(ctx, u.Age.Value(20)); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("Update without where = %v", err)
+	}
+
+	// 创建和软删除
+	if err := q.Role.Create(ctx, &model.Role{Name: "adminX"}, &model.Role{Name: "admin_2"}); err != nil {
+		t.Fatal(err)
+	}
+	// _ 按普通字符匹配，不会匹配到 adminX
+	roles, _ := q.Role.Where(q.Role.Name.HasPrefix("admin_")).Find(ctx)
+	if len(roles) != 1 || roles[0].Name != "admin_2" {
+		t.Errorf("HasPrefix = %+v", roles)
+	}
+	rows, err = u.Where(u.ID.Eq(top[0].ID)).Delete(ctx)
+	if err != nil || rows != 1 {
+		t.Fatalf("Delete() = %d, %v", rows, err)
+	}
+	if _, err := u.Where(u.ID.Eq(top[0].ID)).First(ctx); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("First() after delete = %v", err)
+	}
+	if v, err := u.Unscoped().Where(u.ID.Eq(top[0].ID), u.DeletedAt.NotNull()).First(ctx); err != nil || v.ID != top[0].ID {
+		t.Errorf("Unscoped().First() = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoDB Use(nil) 得到的描述只能拼条件，不能执行
+var ErrNoDB = errors.New("query: no database, create it with query.Use(db)")
+
+// Table 模型 T 的链式查询，每次调用返回新的 Table，可以放心复用
+//
+//	adults := q.User3.Where(q.User3.Age.Gte(18))
+//	n, _ := adults.Count(ctx)
+//	page, _ := adults.Order(q.User3.ID.Desc()).Limit(10).Find(ctx)
+//
+// 生成的 XxxQuery 嵌入了 Table，字段和方法写在一起；
+// 模型字段不能和这里的方法重名，生成器会检查
+type Table[T any] struct {
+	db *gorm.DB
+}
+
+// NewTable 由生成的代码调用
+func NewTable[T any](db *gorm.DB) Table[T] {
+	if db != nil {
+		db = db.Model(new(T)).Session(&gorm.Session{})
+	}
+	return Table[T]{db: db}
+}
+
+func (t Table[T]) with(f func(*gorm.DB) *gorm.DB) Table[T] {
+	if t.db == nil {
+		return t
+	}
+	// Session 之后再链式调用会复制语句，不会改到 t 自己
+	return Table[T]{db: f(t.db).Session(&gorm.Session{})}
+}
+
+// DB 返回当前条件下的 *gorm.DB，用于写这里不支持的部分（Joins、Preload、Group……）
+func (t Table[T]) DB(ctx context.Context) *gorm.DB {
+	if t.db == nil {
+		return nil
+	}
+	return t.db.WithContext(ctx)
+}
+
+func (t Table[T]) Where(conds ...Cond) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Where(clause.And(conds...)) })
+}
+
+// Or 和之前的条件是 OR 的关系：Where(a).Or(b) -> a OR b
+func (t Table[T]) Or(conds ...Cond) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Or(clause.And(conds...)) })
+}
+
+// Select 只查询（创建、更新时只写入）这些字段
+func (t Table[T]) Select(fields ...Expr) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Select(Names(fields...)) })
+}
+
+func (t Table[T]) Omit(fields ...Expr) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Omit(Names(fields...)...) })
+}
+
+func (t Table[T]) Order(cols ...clause.OrderByColumn) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Order(clause.OrderBy{Columns: cols}) })
+}
+
+func (t Table[T]) Limit(n int) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Limit(n) })
+}
+
+func (t Table[T]) Offset(n int) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Offset(n) })
+}
+
+// Unscoped 包含软删除的记录，Delete 时物理删除
+func (t Table[T]) Unscoped() Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+}
+
+// Scopes 复用 gorm 的 scope，例如 relation.Load
+func (t Table[T]) Scopes(fs ...func(*gorm.DB) *gorm.DB) Table[T] {
+	return t.with(func(db *gorm.DB) *gorm.DB { return db.Scopes(fs...) })
+}
+
+func (t Table[T]) Find(ctx context.Context) ([]T, error) {
+	if t.db == nil {
+		return nil, ErrNoDB
+	}
+	var vs []T
+	return vs, t.db.WithContext(ctx).Find(&vs).Error
+}
+
+// First 按主键排序的第一条，查不到时返回 gorm.ErrRecordNotFound
+func (t Table[T]) First(ctx context.Context) (*T, error) {
+	return t.one(ctx, (*gorm.DB).First)
+}
+
+// Take 不排序取一条
+func (t Table[T]) Take(ctx context.Context) (*T, error) {
+	return t.one(ctx, (*gorm.DB).Take)
+}
+
+func (t Table[T]) one(ctx context.Context, f func(*gorm.DB, any, ...any) *gorm.DB) (*T, error) {
+	if t.db == nil {
+		return nil, ErrNoDB
+	}
+	v := new(T)
+	if err := f(t.db.WithContext(ctx), v).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (t Table[T]) Count(ctx context.Context) (int64, error) {
+	if t.db == nil {
+		return 0, ErrNoDB
+	}
+	var n int64
+	return n, t.db.WithContext(ctx).Count(&n).Error
+}
+
+// Create 创建记录，Select / Omit 对它生效
+func (t Table[T]) Create(ctx context.Context, vs ...*T) error {
+	if t.db == nil {
+		return ErrNoDB
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return t.db.WithContext(ctx).Create(vs).Error
+}
+
+// Update 按条件更新，返回影响的行数；没有 Where 时 gorm 拒绝执行（ErrMissingWhereClause）
+//
+//	q.User3.Where(q.User3.ID.Eq(1)).Update(ctx, q.User3.Name.Value("张三"), q.User3.Age.Value(20))
+func (t Table[T]) Update(ctx context.Context, assigns ...Assign) (int64, error) {
+	if t.db == nil {
+		return 0, ErrNoDB
+	}
+	values := make(map[string]any, len(assigns))
+	for _, a := range assigns {
+		values[a.column] = a.value
+	}
+	tx := t.db.WithContext(ctx).Updates(values)
+	return tx.RowsAffected, tx.Error
+}
+
+// Delete 按条件删除（有 DeletedAt 时是软删除），返回影响的行数
+func (t Table[T]) Delete(ctx context.Context) (int64, error) {
+	if t.db == nil {
+		return 0, ErrNoDB
+	}
+	tx := t.db.WithContext(ctx).Delete(new(T))
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,227 @@
+// Package relation 关联的加载、保存和级联删除
+//
+// GORM 创建时会顺带创建关联（见 03_CRUD_创建.md 的关联创建），但更新和删除不会：
+// Save 只 upsert 关联，不会删掉从切片里去掉的订单；Delete 只删主记录，订单留下来变成孤儿。
+// 这里按 schema 里解析出的关系统一处理：
+//
+//	db.Scopes(relation.Load("Profile", "Orders", "Roles")).First(&u, id)
+//	u.Orders = u.Orders[1:]                   // 去掉第一个订单
+//	relation.Save(ctx, db, &u)                // 被去掉的订单会被删除
+//	relation.Delete(ctx, db, &u)              // 软删除用户、资料和订单，保留角色关系
+//	relation.Delete(ctx, db.Unscoped(), &u)   // 物理删除，连同中间表的行
+package relation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoPrimaryKey Delete 的对象还没有主键
+var ErrNoPrimaryKey = errors.New("relation: primary key is zero")
+
+// Load 按关系类型选择加载方式，用在 Scopes 里
+//
+//   - 一对一、属于（has one / belongs to）：Joins，和主表一条 SQL 查出来，结果行数不变
+//   - 一对多、多对多以及嵌套的 "Orders.Items"：Preload，每个关系多一条 IN 查询；
+//     用 JOIN 会让主表的行按子表成倍重复，分页也会错
+//
+// 名字不是模型的关系时返回错误，不会悄悄忽略
+func Load(names ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		s, err := parse(db)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		for _, name := range names {
+			root, _, nested := strings.Cut(name, ".")
+			rel, ok := s.Relationships.Relations[root]
+			if !ok {
+				db.AddError(fmt.Errorf("relation: %s has no relation %q", s.Name, root))
+				continue
+			}
+			if !nested && (rel.Type == schema.HasOne || rel.Type == schema.BelongsTo) {
+				db = db.Joins(name)
+			} else {
+				db = db.Preload(name)
+			}
+		}
+		return db
+	}
+}
+
+// parse 解析 Model（没有时用 Dest）的 schema，Scopes 执行时 gorm 还没有解析
+func parse(db *gorm.DB) (*schema.Schema, error) {
+	model := db.Statement.Model
+	if model == nil {
+		model = db.Statement.Dest
+	}
+	if model == nil {
+		return nil, errors.New("relation: Load needs Model or a destination")
+	}
+	return parseSchema(db, model)
+}
+
+func parseSchema(db *gorm.DB, v any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(v); err != nil {
+		return nil, fmt.Errorf("relation: parse %T: %w", v, err)
+	}
+	return stmt.Schema, nil
+}
+
+// Save 在一个事务里保存 v 以及它已加载的一对一、一对多、多对多关联
+//
+// 关联字段是 nil 表示没有加载，保持数据库里的原样；非 nil 时数据库会和它完全一致：
+// 新的子记录被创建，修改过的被更新，不在里面的子记录被物理删除（多对多只删中间表的行）。
+// 所以清空订单要传空切片 []Order{}，而不是 nil
+func Save(ctx context.Context, db *gorm.DB, v any) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		s, rv, err := target(tx, v)
+		if err != nil {
+			return err
+		}
+		// 没有主键时 Save 是创建，之后才有主键给子记录做外键
+		if err := tx.Omit(clause.Associations).Save(v).Error; err != nil {
+			return err
+		}
+		for _, rel := range owned(s) {
+			fv := reflect.Indirect(rv).FieldByIndex(rel.Field.StructField.Index)
+			if fv.IsNil() {
+				continue
+			}
+			value := fv.Interface()
+			if fv.Kind() == reflect.Ptr {
+				value = fv.Elem().Addr().Interface()
+			}
+			// 一对一换成另一条记录时 gorm 先插入新的再删旧的，外键上有唯一索引（user3_profiles.user_id）会冲突，
+			// 所以先把旧的删掉
+			if rel.Type == schema.HasOne {
+				if err := dropReplaced(ctx, tx, rel, rv, fv.Elem()); err != nil {
+					return fmt.Errorf("relation: save %s.%s: %w", s.Name, rel.Name, err)
+				}
+			}
+			// 被去掉的子记录物理删除，避免软删除的行占着唯一索引
+			// （Association.Unscoped 只是把"外键置空"换成删除，是否软删除看 DB 本身）
+			d := tx.Unscoped()
+			if rel.Type != schema.Many2Many {
+				// 子记录改过的字段也要写入，默认只 upsert；多对多的另一端（角色）是共享的，不跟着改
+				d = d.Session(&gorm.Session{FullSaveAssociations: true})
+			}
+			if err := d.Model(v).Association(rel.Name).Unscoped().Replace(value); err != nil {
+				return fmt.Errorf("relation: save %s.%s: %w", s.Name, rel.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 在一个事务里删除 v 和依附于它的记录
+//
+// db 是普通连接时软删除：主记录和一对一、一对多的子记录一起打上 deleted_at，多对多关系保留，
+// 恢复时可以原样找回。db.Unscoped() 时物理删除：子记录和中间表的行都删掉，关联的另一端（角色）不动。
+// 只处理一层关系，孙子表由数据库外键 ON DELETE CASCADE 兜底
+func Delete(ctx context.Context, db *gorm.DB, v any) error {
+	hard := db.Statement.Unscoped
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		s, rv, err := target(tx, v)
+		if err != nil {
+			return err
+		}
+		if err := checkPrimaryKey(ctx, s, rv); err != nil {
+			return err
+		}
+		fresh := func() *gorm.DB {
+			d := tx.Session(&gorm.Session{NewDB: true})
+			if hard {
+				d = d.Unscoped()
+			}
+			return d
+		}
+		for _, rel := range owned(s) {
+			if rel.Type == schema.Many2Many {
+				if !hard {
+					continue
+				}
+				if err := fresh().Model(v).Association(rel.Name).Clear(); err != nil {
+					return fmt.Errorf("relation: delete %s.%s: %w", s.Name, rel.Name, err)
+				}
+				continue
+			}
+			q := children(ctx, fresh(), rel, rv)
+			child := reflect.New(rel.FieldSchema.ModelType).Interface()
+			if err := q.Delete(child).Error; err != nil {
+				return fmt.Errorf("relation: delete %s.%s: %w", s.Name, rel.Name, err)
+			}
+		}
+		return fresh().Delete(v).Error
+	})
+}
+
+// children 限定到 rv 的子记录
+func children(ctx context.Context, q *gorm.DB, rel *schema.Relationship, rv reflect.Value) *gorm.DB {
+	for _, ref := range rel.References {
+		if !ref.OwnPrimaryKey {
+			continue
+		}
+		pk, _ := ref.PrimaryKey.ValueOf(ctx, rv)
+		q = q.Where(clause.Eq{Column: clause.Column{Name: ref.ForeignKey.DBName}, Value: pk})
+	}
+	return q
+}
+
+// dropReplaced 物理删除 rv 的一对一子记录里不是 child 的那些；child 还没有主键时全部删除
+func dropReplaced(ctx context.Context, tx *gorm.DB, rel *schema.Relationship, rv, child reflect.Value) error {
+	q := children(ctx, tx.Session(&gorm.Session{NewDB: true}).Unscoped(), rel, rv)
+	var same []clause.Expression
+	for _, f := range rel.FieldSchema.PrimaryFields {
+		v, zero := f.ValueOf(ctx, child)
+		if zero {
+			same = nil
+			break
+		}
+		same = append(same, clause.Eq{Column: clause.Column{Name: f.DBName}, Value: v})
+	}
+	if len(same) > 0 {
+		q = q.Where(clause.Not(clause.And(same...)))
+	}
+	return q.Delete(reflect.New(rel.FieldSchema.ModelType).Interface()).Error
+}
+
+// target 解析 v 的 schema，v 必须是结构体指针
+func target(db *gorm.DB, v any) (*schema.Schema, reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, rv, fmt.Errorf("relation: want pointer to struct, got %T", v)
+	}
+	s, err := parseSchema(db, v)
+	if err != nil {
+		return nil, rv, err
+	}
+	return s, rv.Elem(), nil
+}
+
+func checkPrimaryKey(ctx context.Context, s *schema.Schema, rv reflect.Value) error {
+	for _, f := range s.PrimaryFields {
+		if _, zero := f.ValueOf(ctx, rv); zero {
+			return fmt.Errorf("%w: %s", ErrNoPrimaryKey, s.Name)
+		}
+	}
+	return nil
+}
+
+// owned 依附于主记录的关系：一对一、一对多、多对多（属于关系指向的是别人，不处理）
+func owned(s *schema.Schema) []*schema.Relationship {
+	var rels []*schema.Relationship
+	rels = append(rels, s.Relationships.HasOne...)
+	rels = append(rels, s.Relationships.HasMany...)
+	rels = append(rels, s.Relationships.Many2Many...)
+	return rels
+}
@@ -0,0 +1,248 @@
+package relation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"mysql-demo/fixtures/fixturestest"
+	"mysql-demo/model"
+)
+
+// seed 一个用户：资料、两个订单、两个角色；另有一个用户共享 admin 角色
+func seed(t *testing.T) (*gorm.DB, *model.User3, *model.User3) {
+	t.Helper()
+	db, _ := fixturestest.Open(t)
+	admin, editor := model.Role{Name: "admin"}, model.Role{Name: "editor"}
+	if err := db.Create([]*model.Role{&admin, &editor}).Error; err != nil {
+		t.Fatal(err)
+	}
+	u := &model.User3{
+		Name:    "张三",
+		Age:     20,
+		Profile: &model.Profile{Bio: "hi"},
+		Orders:  []model.Order{{No: "A1", Amount: 100}, {No: "A2", Amount: 200}},
+		Roles:   []model.Role{admin, editor},
+	}
+	other := &model.User3{Name: "李四", Age: 30, Orders: []model.Order{{No: "B1"}}, Roles: []model.Role{admin}}
+	// 创建时 GORM 自己会级联：子记录带上外键，已存在的角色只写中间表
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, u, other
+}
+
+func count(t *testing.T, db *gorm.DB, table, where string, args ...any) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Table(table).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// orphans 子表里指向不存在的用户的行
+func orphans(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var total int64
+	for _, q := range []string{
+		"SELECT COUNT(*) FROM user3_profiles p LEFT JOIN user3 u ON u.id = p.user_id WHERE u.id IS NULL",
+		"SELECT COUNT(*) FROM user3_orders o LEFT JOIN user3 u ON u.id = o.user_id WHERE u.id IS NULL",
+		"SELECT COUNT(*) FROM user3_roles r LEFT JOIN user3 u ON u.id = r.user3_id WHERE u.id IS NULL",
+		// 软删除的用户下面还有没删除的子记录，也算孤儿
+		"SELECT COUNT(*) FROM user3_orders o JOIN user3 u ON u.id = o.user_id WHERE u.deleted_at IS NOT NULL AND o.deleted_at IS NULL",
+		"SELECT COUNT(*) FROM user3_profiles p JOIN user3 u ON u.id = p.user_id WHERE u.deleted_at IS NOT NULL AND p.deleted_at IS NULL",
+	} {
+		var n int64
+		if err := db.Raw(q).Scan(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		total += n
+	}
+	return total
+}
+
+func TestLoad(t *testing.T) {
+	db, u, _ := seed(t)
+
+	var sqls []string
+	tx := db.Session(&gorm.Session{Logger: recorder{&sqls}})
+	var got model.User3
+	if err := tx.Scopes(Load("Profile", "Orders", "Roles")).First(&got, u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Profile == nil || got.Profile.Bio != "hi" || len(got.Orders) != 2 || len(got.Roles) != 2 {
+		t.Fatalf("loaded %+v", got)
+	}
+	// 一条带 JOIN 的主查询 + 订单、中间表、角色各一条（Preload 的 SQL 先打出来，主查询最后）
+	if len(sqls) != 4 || !strings.Contains(sqls[3], "LEFT JOIN `user3_profiles` `Profile`") {
+		t.Errorf("queries:\n%s", strings.Join(sqls, "\n"))
+	}
+
+	// 切片也可以，Joins 不会让用户重复；有 JOIN 时排序的列要带表名
+	var all []model.User3
+	if err := db.Scopes(Load("Profile", "Orders")).Order("user3.id").Find(&all).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Profile != nil || len(all[1].Orders) != 1 {
+		t.Errorf("users = %+v", all)
+	}
+
+	err := db.Scopes(Load("Nope")).First(&got).Error
+	if err == nil || !strings.Contains(err.Error(), `no relation "Nope"`) {
+		t.Errorf("unknown relation: %v", err)
+	}
+}
+
+func TestSave(t *testing.T) {
+	db, u, other := seed(t)
+	ctx := context.Background()
+
+	var got model.User3
+	db.Scopes(Load("Profile", "Orders", "Roles")).First(&got, u.ID)
+	got.Name = "张三丰"
+	got.Profile.Bio = "updated"
+	got.Orders[0].Status = model.OrderPaid                                  // 修改
+	got.Orders = append(got.Orders[:1], model.Order{No: "A3", Amount: 300}) // 去掉 A2，新增 A3
+	got.Roles = got.Roles[:1]                                               // 只保留 admin
+	if err := Save(ctx, db, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	var check model.User3
+	db.Scopes(Load("Profile", "Orders", "Roles")).First(&check, u.ID)
+	if check.Name != "张三丰" || check.Profile.Bio != "updated" {
+		t.Errorf("user = %+v, profile = %+v", check, check.Profile)
+	}
+	var nos []string
+	for _, o := range check.Orders {
+		nos = append(nos, o.No+":"+o.Status)
+	}
+	if strings.Join(nos, ",") != "A1:paid,A3:pending" {
+		t.Errorf("orders = %v", nos)
+	}
+	if len(check.Roles) != 1 || check.Roles[0].Name != "admin" {
+		t.Errorf("roles = %+v", check.Roles)
+	}
+	// 去掉的订单被物理删除，角色本身还在
+	if n := count(t, db.Unscoped(), "user3_orders", "no = ?", "A2"); n != 0 {
+		t.Errorf("A2 still exists")
+	}
+	if n := count(t, db, "roles", "1 = 1"); n != 2 {
+		t.Errorf("%d roles, want 2", n)
+	}
+
+	// nil 表示没有加载，不动；空切片表示清空
+	lazy := model.User3{Model: gorm.Model{ID: other.ID}, Name: "李四", Age: 31, Version: other.Version, Orders: []model.Order{}}
+	if err := Save(ctx, db, &lazy); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "user3_orders", "user_id = ?", other.ID); n != 0 {
+		t.Errorf("%d orders left after clearing", n)
+	}
+	if n := count(t, db, "user3_roles", "user3_id = ?", other.ID); n != 1 {
+		t.Errorf("roles of an unloaded relation changed: %d", n)
+	}
+
+	// 换一份新的资料：旧的先删掉，不会撞上 user_id 的唯一索引
+	check.Profile = &model.Profile{Bio: "replaced"}
+	if err := Save(ctx, db, &check); err != nil {
+		t.Fatal(err)
+	}
+	var profiles []model.Profile
+	db.Unscoped().Where("user_id = ?", u.ID).Find(&profiles)
+	if len(profiles) != 1 || profiles[0].Bio != "replaced" {
+		t.Errorf("profiles after replace = %+v", profiles)
+	}
+
+	// 没有主键时先创建用户
+	fresh := model.User3{Name: "王五", Age: 40, Profile: &model.Profile{Bio: "new"}}
+	if err := Save(ctx, db, &fresh); err != nil || fresh.ID == 0 || fresh.Profile.UserID != fresh.ID {
+		t.Errorf("save new: %v, %+v", err, fresh)
+	}
+	if n := orphans(t, db); n != 0 {
+		t.Errorf("%d orphan rows", n)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, u, other := seed(t)
+	ctx := context.Background()
+
+	// 软删除：子记录一起软删除，角色关系保留，可以恢复
+	if err := Delete(ctx, db, u); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "user3_orders", "user_id = ? AND deleted_at IS NULL", u.ID); n != 0 {
+		t.Errorf("%d live orders after soft delete", n)
+	}
+	if n := count(t, db, "user3_orders", "user_id = ?", u.ID); n != 2 {
+		t.Errorf("%d orders kept after soft delete, want 2", n)
+	}
+	if n := count(t, db, "user3_roles", "user3_id = ?", u.ID); n != 2 {
+		t.Errorf("%d role links after soft delete, want 2", n)
+	}
+	if n := orphans(t, db); n != 0 {
+		t.Errorf("%d orphan rows after soft delete", n)
+	}
+
+	// 物理删除：子记录和中间表的行都没了，别人的数据和角色不受影响
+	if err := Delete(ctx, db.Unscoped(), u); err != nil {
+		t.Fatal(err)
+	}
+	for table, where := range map[string]string{
+		"user3":          "id = ?",
+		"user3_profiles": "user_id = ?",
+		"user3_orders":   "user_id = ?",
+		"user3_roles":    "user3_id = ?",
+	} {
+		if n := count(t, db, table, where, u.ID); n != 0 {
+			t.Errorf("%s: %d rows left", table, n)
+		}
+	}
+	if count(t, db, "user3_orders", "user_id = ?", other.ID) != 1 || count(t, db, "roles", "1 = 1") != 2 {
+		t.Error("other user's data changed")
+	}
+	if n := orphans(t, db); n != 0 {
+		t.Errorf("%d orphan rows after hard delete", n)
+	}
+
+	if err := Delete(ctx, db, &model.User3{}); !errors.Is(err, ErrNoPrimaryKey) {
+		t.Errorf("Delete(zero) = %v", err)
+	}
+}
+
+// 绕过 relation.Delete 直接物理删除，外键 ON DELETE CASCADE 兜底
+func TestForeignKeyCascade(t *testing.T) {
+	db, u, _ := seed(t)
+	if err := db.Exec("DELETE FROM user3 WHERE id = ?", u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := orphans(t, db); n != 0 {
+		t.Errorf("%d orphan rows", n)
+	}
+	// 外键也挡住了指向不存在用户的订单
+	if err := db.Create(&model.Order{No: "X", UserID: 999}).Error; err == nil {
+		t.Error("order with unknown user created")
+	}
+}
+
+// recorder 记下执行过的 SQL
+type recorder struct{ sqls *[]string }
+
+func (r recorder) LogMode(logger.LogLevel) logger.Interface { return r }
+func (r recorder) Info(context.Context, string, ...any)     {}
+func (r recorder) Warn(context.Context, string, ...any)     {}
+func (r recorder) Error(context.Context, string, ...any)    {}
+func (r recorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	*r.sqls = append(*r.sqls, sql)
+}
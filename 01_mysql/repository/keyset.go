@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor 游标无法解析，或者不是用同一组排序字段生成的
+var ErrInvalidCursor = errors.New("repository: invalid cursor")
+
+// KeysetQuery 游标分页（keyset pagination）查询
+//
+// offset 分页要先扫过前面所有的行再丢掉，越往后越慢；游标分页记住上一页最后一行的排序值，
+// 下一页直接用 WHERE (age, id) > (?, ?) 走索引定位，每一页的代价都一样
+// 排序字段最后会自动补上主键保证唯一；排序字段不能有 NULL 值
+type KeysetQuery struct {
+	Filters []Filter
+	Sort    []Sort // 为空时按主键升序
+	Size    int    // 每页条数，默认 DefaultPageSize，最大 MaxPageSize
+
+	After  string // 上一次返回的 Next，取下一页
+	Before string // 上一次返回的 Prev，取上一页；与 After 同时设置时以 After 为准
+}
+
+// CursorPage 游标分页结果，游标对调用方是不透明的字符串
+type CursorPage[T any] struct {
+	Items []T
+	Next  string // 没有下一页时为空
+	Prev  string // 没有上一页时为空
+}
+
+// cursor 游标的内容，序列化成 JSON 后 base64 编码
+type cursor struct {
+	Key    string            `json:"k"` // 排序字段的签名，例如 age:desc,id:asc
+	Values []json.RawMessage `json:"v"`
+}
+
+// keyCol 一个排序列
+type keyCol struct {
+	field *schema.Field
+	desc  bool
+}
+
+// FindKeyset 游标分页查询
+func (r *Repository[T]) FindKeyset(ctx context.Context, q KeysetQuery) (CursorPage[T], error) {
+	tx, s, err := r.query(ctx, Query{Filters: q.Filters})
+	if err != nil {
+		return CursorPage[T]{}, err
+	}
+	cols, err := keyColumns(s, q.Sort)
+	if err != nil {
+		return CursorPage[T]{}, err
+	}
+	sig := signature(cols)
+	size := pageSize(q.Size)
+
+	token, backward := q.After, false
+	if token == "" && q.Before != "" {
+		token, backward = q.Before, true
+	}
+	if token != "" {
+		values, err := decodeCursor(token, sig, cols)
+		if err != nil {
+			return CursorPage[T]{}, err
+		}
+		tx = tx.Where(seek(cols, values, backward))
+	}
+
+	// 往前翻页时把排序反过来查，再把结果倒回来
+	order := make([]clause.OrderByColumn, len(cols))
+	for i, c := range cols {
+		order[i] = clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: c.field.DBName}, Desc: c.desc != backward}
+	}
+	// 多取一条用来判断后面还有没有
+	var items []T
+	if err := tx.Clauses(clause.OrderBy{Columns: order}).Limit(size + 1).Find(&items).Error; err != nil {
+		return CursorPage[T]{}, err
+	}
+	more := len(items) > size
+	if more {
+		items = items[:size]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+
+	page := CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	// 向后翻：有多余的行说明还有下一页，带了 After 说明有上一页；向前翻反过来
+	hasNext, hasPrev := more, token != ""
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if page.Next, err = encodeCursor(ctx, sig, cols, &items[len(items)-1]); err != nil {
+			return CursorPage[T]{}, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = encodeCursor(ctx, sig, cols, &items[0]); err != nil {
+			return CursorPage[T]{}, err
+		}
+	}
+	return page, nil
+}
+
+// keyColumns 排序列，最后补上主键作为唯一的决胜列
+func keyColumns(s *schema.Schema, sorts []Sort) ([]keyCol, error) {
+	var cols []keyCol
+	pkSorted := false
+	for _, o := range sorts {
+		f, err := column(s, o.Field)
+		if err != nil {
+			return nil, err
+		}
+		pkSorted = pkSorted || f.PrimaryKey
+		cols = append(cols, keyCol{field: f, desc: o.Desc})
+	}
+	if !pkSorted {
+		pk := s.PrioritizedPrimaryField
+		if pk == nil {
+			return nil, fmt.Errorf("repository: keyset pagination needs a primary key on %s", s.Name)
+		}
+		cols = append(cols, keyCol{field: pk})
+	}
+	return cols, nil
+}
+
+func signature(cols []keyCol) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		dir := "asc"
+		if c.desc {
+			dir = "desc"
+		}
+		parts[i] = c.field.DBName + ":" + dir
+	}
+	return strings.Join(parts, ",")
+}
+
+// seek 生成 "在游标之后" 的条件
+// 各列方向可能不同，所以不能写成行比较 (a, b) > (?, ?)，展开成：
+//
+//	a > ? OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?)
+func seek(cols []keyCol, values []any, backward bool) clause.Expression {
+	var ors []clause.Expression
+	for i, c := range cols {
+		var ands []clause.Expression
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: colOf(cols[j]), Value: values[j]})
+		}
+		if c.desc != backward {
+			ands = append(ands, clause.Lt{Column: colOf(c), Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: colOf(c), Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+func colOf(c keyCol) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: c.field.DBName}
+}
+
+func encodeCursor(ctx context.Context, sig string, cols []keyCol, row any) (string, error) {
+	rv := reflect.ValueOf(row).Elem()
+	c := cursor{Key: sig}
+	for _, col := range cols {
+		v, zero := col.field.ValueOf(ctx, rv)
+		if zero {
+			if pv := reflect.ValueOf(v); pv.Kind() == reflect.Ptr && pv.IsNil() {
+				return "", fmt.Errorf("repository: keyset column %s is NULL", col.field.DBName)
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, b)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor 按字段类型还原游标里的值，例如时间还原成 time.Time 再交给驱动
+func decodeCursor(token, sig string, cols []keyCol) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if c.Key != sig || len(c.Values) != len(cols) {
+		return nil, fmt.Errorf("%w: cursor was created for sort %q, not %q", ErrInvalidCursor, c.Key, sig)
+	}
+	values := make([]any, len(cols))
+	for i, col := range cols {
+		p := reflect.New(col.field.FieldType)
+		if err := json.Unmarshal(c.Values[i], p.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCursor, col.field.DBName, err)
+		}
+		values[i] = p.Elem().Interface()
+	}
+	return values, nil
+}
+
+// errStop 调用方提前结束 range 循环时用来中断 FindInBatches
+var errStop = errors.New("repository: iteration stopped")
+
+// Iter 流式遍历满足条件的所有记录，每次从数据库取 batchSize 条（默认 500），内存占用与总数无关
+//
+//	for u, err := range users.Iter(ctx, repository.Query{}, 1000) {
+//		if err != nil {
+//			return err
+//		}
+//		w.Write(u)
+//	}
+//
+// 底层是 FindInBatches，按主键分批（WHERE id > 上一批最后的 id），所以忽略 q.Sort 和分页参数
+// 遍历过程中可以随时 break，剩下的批次不会再查询
+func (r *Repository[T]) Iter(ctx context.Context, q Query, batchSize int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		tx, _, err := r.query(ctx, Query{Filters: q.Filters, WithDeleted: q.WithDeleted, OnlyDeleted: q.OnlyDeleted})
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if batchSize <= 0 {
+			batchSize = 500
+		}
+		var batch []T
+		res := tx.FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			for _, v := range batch {
+				if !yield(v, nil) {
+					return errStop
+				}
+			}
+			return ctx.Err()
+		})
+		if err := res.Error; err != nil && !errors.Is(err, errStop) {
+			yield(zero, err)
+		}
+	}
+}
+
+// IterBatches 同 Iter，但按批返回，适合批量写出（例如每批一次 CreateInBatches）
+// 每次返回的切片在下一批时会被复用，需要保留时自己复制
+func (r *Repository[T]) IterBatches(ctx context.Context, q Query, batchSize int) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		tx, _, err := r.query(ctx, Query{Filters: q.Filters, WithDeleted: q.WithDeleted, OnlyDeleted: q.OnlyDeleted})
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if batchSize <= 0 {
+			batchSize = 500
+		}
+		var batch []T
+		res := tx.FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			if !yield(batch, nil) {
+				return errStop
+			}
+			return ctx.Err()
+		})
+		if err := res.Error; err != nil && !errors.Is(err, errStop) {
+			yield(nil, err)
+		}
+	}
+}
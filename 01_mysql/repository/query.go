@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnknownField 过滤、排序或更新时用到了模型里不存在的字段
+var ErrUnknownField = errors.New("repository: unknown field")
+
+// Op 比较运算符
+type Op string
+
+const (
+	OpEq      Op = "="
+	OpNe      Op = "<>"
+	OpGt      Op = ">"
+	OpGte     Op = ">="
+	OpLt      Op = "<"
+	OpLte     Op = "<="
+	OpLike    Op = "LIKE"
+	OpIn      Op = "IN"
+	OpNotIn   Op = "NOT IN"
+	OpIsNull  Op = "IS NULL"
+	OpNotNull Op = "IS NOT NULL"
+)
+
+// Filter 一个过滤条件，Field 可以是结构体字段名（Age）也可以是列名（age）
+// 字段会按模型校验，不会把任意字符串拼进 SQL
+type Filter struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+// 常用条件的简写
+func Eq(field string, v any) Filter     { return Filter{field, OpEq, v} }
+func Ne(field string, v any) Filter     { return Filter{field, OpNe, v} }
+func Gt(field string, v any) Filter     { return Filter{field, OpGt, v} }
+func Gte(field string, v any) Filter    { return Filter{field, OpGte, v} }
+func Lt(field string, v any) Filter     { return Filter{field, OpLt, v} }
+func Lte(field string, v any) Filter    { return Filter{field, OpLte, v} }
+func Like(field, pattern string) Filter { return Filter{field, OpLike, pattern} }
+func In(field string, v any) Filter     { return Filter{field, OpIn, v} }
+func IsNull(field string) Filter        { return Filter{Field: field, Op: OpIsNull} }
+
+// Sort 排序字段
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Asc / Desc 排序的简写
+func Asc(field string) Sort  { return Sort{Field: field} }
+func Desc(field string) Sort { return Sort{Field: field, Desc: true} }
+
+// DefaultPageSize / MaxPageSize 分页大小的默认值和上限
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Query 查询条件：过滤、排序、分页
+type Query struct {
+	Filters []Filter
+	Sort    []Sort // 为空时按主键升序，保证分页稳定
+	Page    int    // 从 1 开始，0 表示不分页（Find）或第一页（FindPage）
+	Size    int    // 每页条数，FindPage 里默认 DefaultPageSize，最大 MaxPageSize
+
+	WithDeleted bool // 包含软删除的记录
+	OnlyDeleted bool // 只查软删除的记录
+}
+
+// Page 分页结果
+type Page[T any] struct {
+	Items []T
+	Total int64
+	Page  int
+	Size  int
+}
+
+// Pages 总页数
+func (p Page[T]) Pages() int {
+	if p.Size == 0 {
+		return 0
+	}
+	return int((p.Total + int64(p.Size) - 1) / int64(p.Size))
+}
+
+// column 把字段名解析成列
+func column(s *schema.Schema, name string) (*schema.Field, error) {
+	f := s.LookUpField(name)
+	if f == nil || f.DBName == "" {
+		return nil, fmt.Errorf("%w %q in %s", ErrUnknownField, name, s.Name)
+	}
+	return f, nil
+}
+
+// expr 把 Filter 转成 gorm 的 clause 表达式
+func (f Filter) expr(s *schema.Schema) (clause.Expression, error) {
+	field, err := column(s, f.Field)
+	if err != nil {
+		return nil, err
+	}
+	col := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	switch f.Op {
+	case OpEq, "":
+		return clause.Eq{Column: col, Value: f.Value}, nil
+	case OpNe:
+		return clause.Neq{Column: col, Value: f.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: col, Value: f.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: col, Value: f.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: col, Value: f.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: col, Value: f.Value}, nil
+	case OpLike:
+		return clause.Like{Column: col, Value: f.Value}, nil
+	case OpIn, OpNotIn:
+		in := clause.IN{Column: col, Values: toSlice(f.Value)}
+		if f.Op == OpNotIn {
+			return clause.Not(in), nil
+		}
+		return in, nil
+	case OpIsNull:
+		return clause.Eq{Column: col, Value: nil}, nil
+	case OpNotNull:
+		return clause.Neq{Column: col, Value: nil}, nil
+	}
+	return nil, fmt.Errorf("repository: unsupported operator %q", f.Op)
+}
+
+// toSlice IN 的值可以传任意切片，例如 []int{1, 2}
+func toSlice(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{v}
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+// where 追加过滤条件
+func where(tx *gorm.DB, s *schema.Schema, filters []Filter) (*gorm.DB, error) {
+	var exprs []clause.Expression
+	for _, f := range filters {
+		e, err := f.expr(s)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 0 {
+		return tx, nil
+	}
+	return tx.Clauses(clause.Where{Exprs: exprs}), nil
+}
+
+// order 追加排序，最后总是带上主键，避免相同排序值时分页结果不稳定
+func order(tx *gorm.DB, s *schema.Schema, sorts []Sort) (*gorm.DB, error) {
+	var cols []clause.OrderByColumn
+	pkSorted := false
+	for _, o := range sorts {
+		f, err := column(s, o.Field)
+		if err != nil {
+			return nil, err
+		}
+		pkSorted = pkSorted || f.PrimaryKey
+		cols = append(cols, clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Desc: o.Desc})
+	}
+	if pk := s.PrioritizedPrimaryField; pk != nil && !pkSorted {
+		cols = append(cols, clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}})
+	}
+	if len(cols) == 0 {
+		return tx, nil
+	}
+	return tx.Clauses(clause.OrderBy{Columns: cols}), nil
+}
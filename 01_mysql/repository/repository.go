@@ -0,0 +1,361 @@
+// Package repository 基于 GORM 的泛型仓储
+//
+// 课程里的 CRUD 都是在 *gorm.DB 上直接调用，同样的写法（查不到、软删除、分页、
+// 只更新部分字段）在每个函数里重复一遍。Repository[T] 把这些常用操作收拢起来：
+//
+//	users := repository.New[User3](db)
+//	users.Create(ctx, &User3{Name: "张三", Age: 20})
+//	page, _ := users.FindPage(ctx, repository.Query{
+//		Filters: []repository.Filter{repository.Gte("Age", 18)},
+//		Sort:    []repository.Sort{repository.Desc("CreatedAt")},
+//		Page:    1, Size: 10,
+//	})
+//
+// 需要的功能不在这里时，用 DB(ctx) 拿到 *gorm.DB 直接写
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrNotFound 记录不存在，就是 gorm.ErrRecordNotFound，两者都可以用 errors.Is 判断
+	ErrNotFound = gorm.ErrRecordNotFound
+	// ErrNotSoftDelete 模型没有 DeletedAt 字段，不能恢复
+	ErrNotSoftDelete = errors.New("repository: model has no soft delete field")
+)
+
+// Repository 模型 T 的仓储，T 是结构体类型（不是指针）
+type Repository[T any] struct {
+	db *gorm.DB
+}
+
+// New 创建仓储
+func New[T any](db *gorm.DB) *Repository[T] {
+	return &Repository[T]{db: db}
+}
+
+// DB 返回绑定了 ctx 的 *gorm.DB（在 WithTx 里就是事务），用于写仓储不支持的查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx)
+}
+
+func (r *Repository[T]) model(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(new(T))
+}
+
+// Schema 返回模型解析后的 schema
+func (r *Repository[T]) Schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("repository: parse %T: %w", *new(T), err)
+	}
+	return stmt.Schema, nil
+}
+
+// Select 返回只写入指定字段的仓储，对 Create 和 Update 生效
+func (r *Repository[T]) Select(fields ...string) *Repository[T] {
+	return &Repository[T]{db: r.db.Select(fields).Session(&gorm.Session{})}
+}
+
+// Omit 返回忽略指定字段的仓储，对 Create 和 Update 生效
+func (r *Repository[T]) Omit(fields ...string) *Repository[T] {
+	return &Repository[T]{db: r.db.Omit(fields...).Session(&gorm.Session{})}
+}
+
+// WithTx 在事务中执行 fn，fn 返回错误或 panic 时回滚
+// 需要在同一个事务里操作其他模型时，用 repository.New[Other](tx.DB(ctx))
+func (r *Repository[T]) WithTx(ctx context.Context, fn func(tx *Repository[T]) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository[T]{db: tx})
+	})
+}
+
+// Create 创建一条记录，会调用 BeforeCreate 等钩子
+func (r *Repository[T]) Create(ctx context.Context, v *T) error {
+	return r.db.WithContext(ctx).Create(v).Error
+}
+
+// CreateBatch 批量创建，batchSize <= 0 时一条 SQL 插入全部
+// 分成多批时 GORM 会开启事务，任何一批失败都会整体回滚
+func (r *Repository[T]) CreateBatch(ctx context.Context, vs []*T, batchSize int) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = len(vs)
+	}
+	return r.db.WithContext(ctx).CreateInBatches(vs, batchSize).Error
+}
+
+// CreateMap 用 map 创建记录，key 为字段名或列名
+// 注意：map 创建不会调用钩子，也不会自动填充 CreatedAt/UpdatedAt 以外的默认值
+func (r *Repository[T]) CreateMap(ctx context.Context, values ...map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	for _, m := range values {
+		for k := range m {
+			if _, err := column(s, k); err != nil {
+				return err
+			}
+		}
+	}
+	// sqlite 用 RETURNING 回填主键时不支持 []map，所以逐条插入，放在一个事务里
+	return r.model(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, m := range values {
+			if err := tx.Create(m).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get 按主键查询，查不到时返回 ErrNotFound
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	s, err := r.Schema()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := byID(r.db.WithContext(ctx), s, id)
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := tx.Take(v).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Find 按条件查询，q.Page 为 0 时返回全部匹配的记录
+func (r *Repository[T]) Find(ctx context.Context, q Query) ([]T, error) {
+	tx, s, err := r.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = order(tx, s, q.Sort); err != nil {
+		return nil, err
+	}
+	if q.Page > 0 {
+		size := pageSize(q.Size)
+		tx = tx.Limit(size).Offset((q.Page - 1) * size)
+	}
+	var out []T
+	if err := tx.Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FindPage 分页查询，同时返回总数
+func (r *Repository[T]) FindPage(ctx context.Context, q Query) (Page[T], error) {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	q.Size = pageSize(q.Size)
+	total, err := r.count(ctx, q)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	p := Page[T]{Total: total, Page: q.Page, Size: q.Size}
+	// 超出范围的页直接返回空，省一次查询
+	if int64((q.Page-1)*q.Size) >= total {
+		p.Items = []T{}
+		return p, nil
+	}
+	if p.Items, err = r.Find(ctx, q); err != nil {
+		return Page[T]{}, err
+	}
+	return p, nil
+}
+
+// Count 统计满足条件的记录数（不含软删除）
+func (r *Repository[T]) Count(ctx context.Context, filters ...Filter) (int64, error) {
+	return r.count(ctx, Query{Filters: filters})
+}
+
+func (r *Repository[T]) count(ctx context.Context, q Query) (int64, error) {
+	tx, _, err := r.query(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = tx.Count(&n).Error
+	return n, err
+}
+
+// Update 按主键更新
+// fields 是字段掩码：只更新列出的字段，即使是零值也会写入（例如把 Age 改成 0）；
+// fields 为空时与 gorm 的 Updates 相同，只更新非零字段
+// 记录不存在（或已软删除）时返回 ErrNotFound
+func (r *Repository[T]) Update(ctx context.Context, id any, v *T, fields ...string) error {
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	tx, err := byID(r.model(ctx), s, id)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		if err := checkMask(s, fields); err != nil {
+			return err
+		}
+		tx = tx.Select(fields)
+	}
+	return r.updated(ctx, s, id, tx.Updates(v))
+}
+
+// UpdateMap 按主键用 map 更新，map 里的零值也会写入
+func (r *Repository[T]) UpdateMap(ctx context.Context, id any, values map[string]any) error {
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	fields := make([]string, 0, len(values))
+	for k := range values {
+		fields = append(fields, k)
+	}
+	if err := checkMask(s, fields); err != nil {
+		return err
+	}
+	tx, err := byID(r.model(ctx), s, id)
+	if err != nil {
+		return err
+	}
+	return r.updated(ctx, s, id, tx.Updates(values))
+}
+
+// Delete 按主键删除；模型有 DeletedAt 时是软删除，可以用 Restore 恢复
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	tx, err := byID(r.db.WithContext(ctx), s, id)
+	if err != nil {
+		return err
+	}
+	return affected(tx.Delete(new(T)))
+}
+
+// Restore 恢复软删除的记录，记录不存在或没有被删除时返回 ErrNotFound
+func (r *Repository[T]) Restore(ctx context.Context, id any) error {
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	deletedAt := softDeleteField(s)
+	if deletedAt == nil {
+		return ErrNotSoftDelete
+	}
+	tx, err := byID(r.model(ctx).Unscoped(), s, id)
+	if err != nil {
+		return err
+	}
+	tx = tx.Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAt.DBName}, Value: nil})
+	return affected(tx.Update(deletedAt.DBName, nil))
+}
+
+// query 构造带过滤和软删除范围的查询
+func (r *Repository[T]) query(ctx context.Context, q Query) (*gorm.DB, *schema.Schema, error) {
+	s, err := r.Schema()
+	if err != nil {
+		return nil, nil, err
+	}
+	tx := r.model(ctx)
+	if q.WithDeleted || q.OnlyDeleted {
+		tx = tx.Unscoped()
+	}
+	if q.OnlyDeleted {
+		f := softDeleteField(s)
+		if f == nil {
+			return nil, nil, ErrNotSoftDelete
+		}
+		q.Filters = append(q.Filters[:len(q.Filters):len(q.Filters)], Filter{Field: f.DBName, Op: OpNotNull})
+	}
+	tx, err = where(tx, s, q.Filters)
+	return tx, s, err
+}
+
+func byID(tx *gorm.DB, s *schema.Schema, id any) (*gorm.DB, error) {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, fmt.Errorf("repository: %s has no primary key", s.Name)
+	}
+	return tx.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: id}), nil
+}
+
+// checkMask 字段掩码里的字段必须存在，且不能是主键
+func checkMask(s *schema.Schema, fields []string) error {
+	for _, name := range fields {
+		f, err := column(s, name)
+		if err != nil {
+			return err
+		}
+		if f.PrimaryKey {
+			return fmt.Errorf("repository: cannot update primary key %q", name)
+		}
+	}
+	return nil
+}
+
+// softDeleteField 返回 gorm.DeletedAt 类型的字段
+func softDeleteField(s *schema.Schema) *schema.Field {
+	for _, f := range s.Fields {
+		if _, ok := f.FieldType.MethodByName("DeleteClauses"); ok && f.DBName != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+// updated 是 Update 用的 affected：MySQL 默认只统计真正改变的行，
+// 写入相同的值时 RowsAffected 也是 0，所以要再查一次行是否存在
+func (r *Repository[T]) updated(ctx context.Context, s *schema.Schema, id any, tx *gorm.DB) error {
+	if tx.Error != nil || tx.RowsAffected > 0 {
+		return tx.Error
+	}
+	q, err := byID(r.model(ctx), s, id)
+	if err != nil {
+		return err
+	}
+	var n int64
+	if err := q.Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// affected 没有影响任何行时返回 ErrNotFound
+func affected(tx *gorm.DB) error {
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func pageSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	return min(size, MaxPageSize)
+}
@@ -0,0 +1,455 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+	"mysql-demo/validate"
+)
+
+// user 与课程里的 User3 一致
+type user struct {
+	gorm.Model
+	Name     string `validate:"notblank,max=50"`
+	Age      int    `validate:"min=18,max=150"`
+	Birthday *time.Time
+}
+
+func (user) TableName() string { return "user3" }
+
+// plain 没有软删除字段，对应 02_user
+type plain struct {
+	ID   uint
+	Name string
+}
+
+func newRepo(t *testing.T) (*Repository[user], *gorm.DB) {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(validate.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}, &plain{}); err != nil {
+		t.Fatal(err)
+	}
+	return New[user](db), db
+}
+
+func seed(t *testing.T, r *Repository[user], ages ...int) []*user {
+	t.Helper()
+	var us []*user
+	for i, age := range ages {
+		us = append(us, &user{Name: string(rune('a' + i)), Age: age})
+	}
+	if err := r.CreateBatch(context.Background(), us, 2); err != nil {
+		t.Fatal(err)
+	}
+	return us
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRepo(t)
+
+	now := time.Now()
+	u := &user{Name: "张三", Age: 20, Birthday: &now}
+	if err := r.Create(ctx, u); err != nil || u.ID == 0 {
+		t.Fatalf("Create() = %v, id = %d", err, u.ID)
+	}
+	// 校验照常生效
+	if err := r.Create(ctx, &user{Name: "小孩", Age: 10}); err == nil {
+		t.Error("Create() with age 10 should fail validation")
+	}
+
+	// 批量里有一条失败时整体回滚
+	err := r.CreateBatch(ctx, []*user{{Name: "a", Age: 20}, {Name: "b", Age: 30}, {Name: "c", Age: 1}}, 2)
+	if err == nil {
+		t.Error("CreateBatch() should fail")
+	}
+	if n, _ := r.Count(ctx); n != 1 {
+		t.Errorf("count after failed batch = %d, want 1", n)
+	}
+
+	// Select / Omit 只写入部分字段
+	u = &user{Name: "李四", Age: 30, Birthday: &now}
+	if err := r.Omit("Birthday").Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := r.Get(ctx, u.ID)
+	if got.Birthday != nil || got.Age != 30 {
+		t.Errorf("after Omit(Birthday) = %+v", got)
+	}
+	u = &user{Name: "王五", Age: 40, Birthday: &now}
+	r.Select("Name", "Age").Create(ctx, u)
+	if got, _ := r.Get(ctx, u.ID); got.Birthday != nil || got.Name != "王五" {
+		t.Errorf("after Select(Name, Age) = %+v", got)
+	}
+
+	// map 创建不调用钩子，但 validate 标签同样生效
+	if _, ok := validate.As(r.CreateMap(ctx, map[string]any{"Name": "map", "Age": 5})); !ok {
+		t.Error("CreateMap() with age 5 should fail validation")
+	}
+	if err := r.CreateMap(ctx, map[string]any{"Name": "map", "Age": 25, "CreatedAt": now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateMap(ctx, map[string]any{"Name": "m1", "Age": 18}, map[string]any{"Name": "m2", "Age": 20}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.Count(ctx, Like("Name", "m%")); n != 3 {
+		t.Errorf("map rows = %d, want 3", n)
+	}
+	if err := r.CreateMap(ctx, map[string]any{"Nmae": "typo"}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("CreateMap() with unknown key = %v", err)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	r, _ := newRepo(t)
+	_, err := r.Get(context.Background(), 42)
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Get(42) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRepo(t)
+	seed(t, r, 20, 35, 50, 35, 18)
+
+	got, err := r.Find(ctx, Query{
+		Filters: []Filter{Gte("Age", 20), Lt("age", 50)},
+		Sort:    []Sort{Desc("Age")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Age 相同的按主键排序
+	if len(got) != 3 || got[0].Name != "b" || got[1].Name != "d" || got[2].Name != "a" {
+		t.Errorf("Find() = %+v", got)
+	}
+
+	got, _ = r.Find(ctx, Query{Filters: []Filter{In("Name", []string{"a", "e"})}})
+	if len(got) != 2 {
+		t.Errorf("Find(In) = %d rows", len(got))
+	}
+
+	page, err := r.FindPage(ctx, Query{Sort: []Sort{Asc("Age")}, Page: 2, Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || page.Pages() != 3 || len(page.Items) != 2 || page.Items[0].Age != 35 {
+		t.Errorf("FindPage() = %+v", page)
+	}
+	page, _ = r.FindPage(ctx, Query{Page: 9, Size: 2})
+	if len(page.Items) != 0 || page.Total != 5 {
+		t.Errorf("FindPage(page 9) = %+v", page)
+	}
+
+	if _, err := r.Find(ctx, Query{Filters: []Filter{Eq("age; DROP TABLE user3", 1)}}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Find() with bad field = %v", err)
+	}
+	if _, err := r.Find(ctx, Query{Sort: []Sort{Asc("Email")}}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Find() with bad sort = %v", err)
+	}
+}
+
+func TestUpdateMask(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRepo(t)
+	us := seed(t, r, 30)
+	id := us[0].ID
+
+	// 没有掩码时零值不会写入
+	if err := r.Update(ctx, id, &user{Name: "新名字", Age: 0}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := r.Get(ctx, id)
+	if got.Name != "新名字" || got.Age != 30 {
+		t.Errorf("after Update = %+v", got)
+	}
+
+	// 掩码里的字段即使是零值也写入（所以也会校验），掩码外的字段不变
+	if _, ok := validate.As(r.Update(ctx, id, &user{Age: 0}, "Age")); !ok {
+		t.Error("masked Update() with age 0 should fail validation")
+	}
+	if err := r.Update(ctx, id, &user{Name: "", Age: 60}, "Age"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = r.Get(ctx, id)
+	if got.Name != "新名字" || got.Age != 60 {
+		t.Errorf("after masked Update = %+v", got)
+	}
+
+	// 没有校验规则的字段：掩码里的零值照样写入
+	birthday := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := r.Update(ctx, id, &user{Birthday: &birthday}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = r.Get(ctx, id); got.Birthday == nil || !got.Birthday.Equal(birthday) {
+		t.Fatalf("after Update birthday = %+v", got)
+	}
+	if err := r.Update(ctx, id, &user{}, "Birthday"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = r.Get(ctx, id)
+	if got.Birthday != nil || got.Name != "新名字" || got.Age != 60 {
+		t.Errorf("after masked zero Update = %+v", got)
+	}
+
+	if err := r.UpdateMap(ctx, id, map[string]any{"age": 40}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = r.Get(ctx, id); got.Age != 40 {
+		t.Errorf("after UpdateMap = %+v", got)
+	}
+
+	if err := r.Update(ctx, id, &user{}, "ID"); err == nil {
+		t.Error("Update() of primary key should fail")
+	}
+	if err := r.Update(ctx, id, &user{}, "Email"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Update() with unknown field = %v", err)
+	}
+	if err := r.Update(ctx, 999, &user{Name: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(999) = %v, want ErrNotFound", err)
+	}
+}
+
+// MySQL 写入相同的值时 RowsAffected 是 0，SQLite 统计的是匹配的行，这里用回调模拟
+func TestUpdateNoChange(t *testing.T) {
+	ctx := context.Background()
+	r, db := newRepo(t)
+	id := seed(t, r, 30)[0].ID
+	err := db.Callback().Update().After("gorm:update").Register("test:found_rows", func(tx *gorm.DB) {
+		tx.RowsAffected = 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Update(ctx, id, &user{Age: 30}, "Age"); err != nil {
+		t.Errorf("no-op Update() = %v, want nil", err)
+	}
+	if err := r.UpdateMap(ctx, id, map[string]any{"age": 30}); err != nil {
+		t.Errorf("no-op UpdateMap() = %v, want nil", err)
+	}
+	if err := r.Update(ctx, 999, &user{Age: 30}, "Age"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(999) = %v, want ErrNotFound", err)
+	}
+	if err := r.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.UpdateMap(ctx, id, map[string]any{"age": 30}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateMap() of deleted row = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRestore(t *testing.T) {
+	ctx := context.Background()
+	r, db := newRepo(t)
+	us := seed(t, r, 20, 30)
+	id := us[0].ID
+
+	if err := r.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete = %v", err)
+	}
+	if n, _ := r.Count(ctx); n != 1 {
+		t.Errorf("count = %d, want 1", n)
+	}
+	deleted, _ := r.Find(ctx, Query{OnlyDeleted: true})
+	all, _ := r.Find(ctx, Query{WithDeleted: true})
+	if len(deleted) != 1 || deleted[0].ID != id || len(all) != 2 {
+		t.Errorf("deleted = %v, all = %d", deleted, len(all))
+	}
+	// 软删除的记录不能更新，也不能再删一次
+	if err := r.Update(ctx, id, &user{Name: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() deleted = %v", err)
+	}
+	if err := r.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() twice = %v", err)
+	}
+
+	if err := r.Restore(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		t.Errorf("Get() after restore = %v", err)
+	}
+	// 没有被删除的记录不能恢复
+	if err := r.Restore(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() twice = %v", err)
+	}
+
+	// 没有 DeletedAt 的模型是真删除
+	p := New[plain](db)
+	p.Create(ctx, &plain{Name: "x"})
+	if err := p.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Restore(ctx, 1); !errors.Is(err, ErrNotSoftDelete) {
+		t.Errorf("Restore() on plain = %v", err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRepo(t)
+
+	boom := errors.New("boom")
+	err := r.WithTx(ctx, func(tx *Repository[user]) error {
+		if err := tx.Create(ctx, &user{Name: "a", Age: 20}); err != nil {
+			return err
+		}
+		// 同一事务里的其他模型
+		if err := New[plain](tx.DB(ctx)).Create(ctx, &plain{Name: "p"}); err != nil {
+			return err
+		}
+		if n, _ := tx.Count(ctx); n != 1 {
+			t.Errorf("count inside tx = %d", n)
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("WithTx() = %v", err)
+	}
+	if n, _ := r.Count(ctx); n != 0 {
+		t.Errorf("count after rollback = %d", n)
+	}
+	if n, _ := New[plain](r.db).Count(ctx); n != 0 {
+		t.Errorf("plain count after rollback = %d", n)
+	}
+
+	if err := r.WithTx(ctx, func(tx *Repository[user]) error {
+		return tx.Create(ctx, &user{Name: "b", Age: 20})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.Count(ctx); n != 1 {
+		t.Errorf("count after commit = %d", n)
+	}
+}
+
+func TestFindKeyset(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRepo(t)
+	// 年龄有重复，保证主键参与排序
+	ages := []int{30, 20, 30, 40, 20, 30, 50}
+	seed(t, r, ages...)
+
+	q := KeysetQuery{Sort: []Sort{Desc("Age")}, Size: 3}
+	var seen []string
+	var pages []CursorPage[user]
+	for {
+		p, err := r.FindKeyset(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, p)
+		for _, u := range p.Items {
+			seen = append(seen, fmt.Sprintf("%d%s", u.Age, u.Name))
+		}
+		if p.Next == "" {
+			break
+		}
+		q.After = p.Next
+	}
+	want := "50g 40d 30a 30c 30f 20b 20e"
+	if got := strings.Join(seen, " "); got != want || len(pages) != 3 {
+		t.Fatalf("keyset pages = %q (%d pages), want %q", got, len(pages), want)
+	}
+	if pages[0].Prev != "" || pages[1].Prev == "" {
+		t.Error("first page should have no Prev, second page should")
+	}
+
+	// 从第三页往回翻，得到和第二页一样的数据
+	back, err := r.FindKeyset(ctx, KeysetQuery{Sort: q.Sort, Size: 3, Before: pages[2].Prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Items) != 3 || back.Items[0].Name != "c" || back.Items[2].Name != "b" || back.Next == "" || back.Prev == "" {
+		t.Errorf("previous page = %+v", back)
+	}
+
+	// 组合排序 + 时间字段：游标里的时间要按 time.Time 还原
+	p1, _ := r.FindKeyset(ctx, KeysetQuery{Sort: []Sort{Asc("Age"), Desc("CreatedAt")}, Size: 4})
+	p2, err := r.FindKeyset(ctx, KeysetQuery{Sort: []Sort{Asc("Age"), Desc("CreatedAt")}, Size: 4, After: p1.Next})
+	if err != nil || len(p1.Items)+len(p2.Items) != len(ages) || p2.Next != "" {
+		t.Errorf("composite keyset = %d + %d items, err %v", len(p1.Items), len(p2.Items), err)
+	}
+
+	// 游标和排序不匹配、被篡改
+	if _, err := r.FindKeyset(ctx, KeysetQuery{Sort: []Sort{Asc("Name")}, After: pages[0].Next}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor with other sort = %v", err)
+	}
+	if _, err := r.FindKeyset(ctx, KeysetQuery{Sort: q.Sort, After: "!!not-base64"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("garbage cursor = %v", err)
+	}
+}
+
+func TestIter(t *testing.T) {
+	ctx := context.Background()
+	r, db := newRepo(t)
+	ages := make([]int, 25)
+	for i := range ages {
+		ages[i] = 18 + i
+	}
+	seed(t, r, ages...)
+
+	// 记录实际执行的查询次数，确认是分批取的
+	var queries int
+	db.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) { queries++ })
+
+	sum := 0
+	n := 0
+	for u, err := range r.Iter(ctx, Query{Filters: []Filter{Gte("Age", 20)}}, 10) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += u.Age
+		n++
+	}
+	if n != 23 || queries != 3 {
+		t.Errorf("Iter() rows = %d, queries = %d, want 23 rows in 3 queries", n, queries)
+	}
+
+	// 提前 break 之后不再查询
+	queries, n = 0, 0
+	for range r.Iter(ctx, Query{}, 10) {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if queries != 1 {
+		t.Errorf("queries after break = %d, want 1", queries)
+	}
+
+	batches := 0
+	for batch, err := range r.IterBatches(ctx, Query{}, 10) {
+		if err != nil || len(batch) == 0 {
+			t.Fatal(err)
+		}
+		batches++
+	}
+	if batches != 3 {
+		t.Errorf("IterBatches() = %d batches, want 3", batches)
+	}
+
+	for _, err := range r.Iter(ctx, Query{Filters: []Filter{Eq("nope", 1)}}, 10) {
+		if !errors.Is(err, ErrUnknownField) {
+			t.Errorf("Iter() with bad filter = %v", err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package routing 在多个数据库之间路由：主从读写分离、按租户选择数据库或表
+//
+// 读写分离：写和事务走主库，读按轮询分到从库；写之后的一段时间内同一个会话的读也走主库，
+// 避免主从延迟导致“刚写进去却读不到”
+//
+//	db, err := routing.Open(routing.Config{
+//		Primary:  database.Config{Host: "10.0.0.1"},
+//		Replicas: []database.Config{{Host: "10.0.0.2"}, {Host: "10.0.0.3"}},
+//		Sticky:   database.Duration(2 * time.Second),
+//	})
+//	ctx = routing.WithSession(ctx, userID) // 读己之写按会话生效
+//	db.WithContext(ctx).Find(&users)       // 从库
+//
+// 多租户见 WithTenant、Tenants 和 TablePrefix
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+)
+
+// DefaultSticky 写之后读主库的默认时长
+const DefaultSticky = time.Second
+
+// Config 一主多从的连接配置
+type Config struct {
+	Primary database.Config `yaml:"primary" toml:"primary"`
+	// Replicas 从库，没有设置的 driver/user/password/name/params/log_level 沿用主库的
+	Replicas []database.Config `yaml:"replicas" toml:"replicas"`
+	// Sticky 写之后同一会话读主库的时长，默认 1s，负数表示不启用
+	Sticky database.Duration `yaml:"sticky" toml:"sticky"`
+}
+
+// Open 打开主库和所有从库，返回注册了 Resolver 的主库连接
+// 用完后用 Close 关闭，会同时关闭从库
+func Open(cfg Config) (*gorm.DB, error) {
+	primary, err := database.Open(cfg.Primary)
+	if err != nil {
+		return nil, err
+	}
+	var replicas []*gorm.DB
+	closeAll := func() {
+		for _, r := range replicas {
+			if sqlDB, err := r.DB(); err == nil {
+				sqlDB.Close()
+			}
+		}
+		if sqlDB, err := primary.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	for _, rc := range cfg.Replicas {
+		r, err := database.Open(inherit(cfg.Primary, rc))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		replicas = append(replicas, r)
+	}
+
+	sticky := time.Duration(cfg.Sticky)
+	if sticky == 0 {
+		sticky = DefaultSticky
+	}
+	res, err := NewResolver(sticky, replicas...)
+	if err == nil {
+		err = primary.Use(res)
+	}
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return primary, nil
+}
+
+// Close 关闭 db 以及注册在上面的 Resolver 的从库
+func Close(db *gorm.DB) error {
+	var errs []error
+	if p, ok := db.Config.Plugins[resolverName]; ok {
+		errs = append(errs, p.(*Resolver).Close())
+	}
+	if sqlDB, err := db.DB(); err != nil {
+		errs = append(errs, err)
+	} else {
+		errs = append(errs, sqlDB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// inherit 从库没有写的字段沿用主库
+func inherit(primary, r database.Config) database.Config {
+	if r.Driver == "" {
+		r.Driver = primary.Driver
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&r.User, &primary.User},
+		{&r.Password, &primary.Password},
+		{&r.Name, &primary.Name},
+		{&r.Params, &primary.Params},
+		{&r.LogLevel, &primary.LogLevel},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+	return r
+}
+
+type sessionKey struct{}
+type primaryKey struct{}
+
+// WithSession 设置读己之写的会话，一般是用户 ID 或请求方的标识
+// 没有设置会话的 context 共用一个会话：任何一次这样的写入之后，这些读都会走主库
+func WithSession(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, key)
+}
+
+// UsePrimary 这个 context 上的读全部走主库，用于对一致性要求高的查询
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func session(ctx context.Context) string {
+	if ctx != nil {
+		if k, ok := ctx.Value(sessionKey{}).(string); ok {
+			return k
+		}
+	}
+	return ""
+}
+
+const resolverName = "routing:resolver"
+
+// Resolver 读写分离插件
+//
+//   - Find/First/Count/Scan 等读操作轮询分到从库
+//   - Create/Update/Delete/Exec 和事务里的所有语句走主库
+//   - 带 FOR UPDATE 等锁子句的查询走主库
+//   - 同一会话写入之后 Sticky 时长内的读走主库
+type Resolver struct {
+	sticky  time.Duration
+	pools   []*sql.DB
+	primary gorm.ConnPool
+	next    atomic.Uint64
+	now     func() time.Time
+
+	mu     sync.Mutex
+	writes map[string]time.Time // 会话 -> 最近一次写入的时间
+}
+
+// NewResolver 创建插件，replicas 是已经打开的从库连接
+func NewResolver(sticky time.Duration, replicas ...*gorm.DB) (*Resolver, error) {
+	r := &Resolver{sticky: sticky, now: time.Now, writes: map[string]time.Time{}}
+	for _, db := range replicas {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		r.pools = append(r.pools, sqlDB)
+	}
+	return r, nil
+}
+
+func (r *Resolver) Name() string { return resolverName }
+
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Query().Before("gorm:query").Register("routing:read", r.read),
+		cb.Row().Before("gorm:row").Register("routing:read", r.read),
+		cb.Create().Before("gorm:create").Register("routing:write", r.write),
+		cb.Update().Before("gorm:update").Register("routing:write", r.write),
+		cb.Delete().Before("gorm:delete").Register("routing:write", r.write),
+		cb.Raw().Before("gorm:raw").Register("routing:write", r.write),
+		cb.Create().After("gorm:create").Register("routing:written", r.written),
+		cb.Update().After("gorm:update").Register("routing:written", r.written),
+		cb.Delete().After("gorm:delete").Register("routing:written", r.written),
+		cb.Raw().After("gorm:raw").Register("routing:written", r.written),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭所有从库
+func (r *Resolver) Close() error {
+	var errs []error
+	for _, p := range r.pools {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Resolver) read(db *gorm.DB) {
+	s := db.Statement
+	if db.Error != nil || len(r.pools) == 0 || !r.owns(s.ConnPool) {
+		return // 事务里的连接不是这些连接池，保持不变
+	}
+	s.ConnPool = r.primary
+	if _, ok := s.Clauses["FOR"]; ok {
+		return
+	}
+	if v, _ := s.Context.Value(primaryKey{}).(bool); v || r.isSticky(session(s.Context)) {
+		return
+	}
+	s.ConnPool = r.pools[(r.next.Add(1)-1)%uint64(len(r.pools))]
+}
+
+// write 共用的 Statement 上一次读可能被切到了从库，写之前切回主库
+func (r *Resolver) write(db *gorm.DB) {
+	if r.owns(db.Statement.ConnPool) {
+		db.Statement.ConnPool = r.primary
+	}
+}
+
+// owns 是否是主库或从库的连接池（而不是事务）
+func (r *Resolver) owns(p gorm.ConnPool) bool {
+	if p == r.primary {
+		return true
+	}
+	for _, pool := range r.pools {
+		if p == gorm.ConnPool(pool) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) written(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || r.sticky <= 0 {
+		return
+	}
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 顺便清理过期的会话，避免 map 无限增长
+	if len(r.writes) >= 1024 {
+		for k, t := range r.writes {
+			if now.Sub(t) >= r.sticky {
+				delete(r.writes, k)
+			}
+		}
+	}
+	r.writes[session(db.Statement.Context)] = now
+}
+
+func (r *Resolver) isSticky(key string) bool {
+	if r.sticky <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	t, ok := r.writes[key]
+	r.mu.Unlock()
+	return ok && r.now().Sub(t) < r.sticky
+}
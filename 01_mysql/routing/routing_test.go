@@ -0,0 +1,290 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mysql-demo/database"
+)
+
+// user 与课程里的 User3 一致
+type user struct {
+	gorm.Model
+	Name string
+	Age  int
+}
+
+func (user) TableName() string { return "user3" }
+
+// sqliteFile 在临时目录里建一个 sqlite 库代替一台数据库，id=1 的行的名字标记是哪一台
+func sqliteFile(t *testing.T, name string) database.Config {
+	t.Helper()
+	cfg := database.Config{Driver: database.DriverSQLite, DSN: filepath.Join(t.TempDir(), name+".db"), LogLevel: "silent"}
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&user{Name: name}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// which 读 id=1 的行，返回命中的是哪一台
+func which(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var u user
+	if err := db.First(&u, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.Name
+}
+
+func TestReadWriteSplit(t *testing.T) {
+	primary := sqliteFile(t, "primary")
+	r1, r2 := sqliteFile(t, "replica1"), sqliteFile(t, "replica2")
+
+	// 从库只写了 dsn，驱动和日志级别沿用主库
+	db, err := Open(Config{
+		Primary:  primary,
+		Replicas: []database.Config{{DSN: r1.DSN}, {DSN: r2.DSN}},
+		Sticky:   database.Duration(time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	res := db.Config.Plugins[resolverName].(*Resolver)
+	now := time.Now()
+	res.now = func() time.Time { return now }
+
+	// 读轮询
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, which(t, db))
+	}
+	if want := []string{"replica1", "replica2", "replica1", "replica2"}; !slices.Equal(got, want) {
+		t.Errorf("reads = %v, want %v", got, want)
+	}
+	var names []string
+	db.Raw("SELECT name FROM user3").Scan(&names)
+	if len(names) != 1 || names[0] != "replica1" {
+		t.Errorf("raw read = %v, want replica1", names)
+	}
+
+	// 写入走主库，之后同一会话读主库，其他会话不受影响
+	alice := WithSession(context.Background(), "alice")
+	bob := WithSession(context.Background(), "bob")
+	if err := db.WithContext(alice).Create(&user{Name: "new"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := which(t, db.WithContext(alice)); got != "primary" {
+		t.Errorf("alice read after write = %s, want primary", got)
+	}
+	if got := which(t, db.WithContext(bob)); got == "primary" {
+		t.Error("bob read should go to a replica")
+	}
+	now = now.Add(time.Minute)
+	if got := which(t, db.WithContext(alice)); got == "primary" {
+		t.Error("alice read after sticky window should go to a replica")
+	}
+
+	// 事务、显式指定主库、加锁的查询都读主库
+	db.Transaction(func(tx *gorm.DB) error {
+		if got := which(t, tx); got != "primary" {
+			t.Errorf("read in transaction = %s, want primary", got)
+		}
+		return nil
+	})
+	if got := which(t, db.WithContext(UsePrimary(context.Background()))); got != "primary" {
+		t.Errorf("UsePrimary read = %s, want primary", got)
+	}
+	var u user
+	db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&u, 1)
+	if u.Name != "primary" {
+		t.Errorf("locking read = %s, want primary", u.Name)
+	}
+
+	var n int64
+	db.WithContext(UsePrimary(context.Background())).Model(&user{}).Count(&n)
+	if n != 2 {
+		t.Errorf("primary rows = %d, want 2", n)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	cfgs := map[string]database.Config{"acme": sqliteFile(t, "acme"), "globex": sqliteFile(t, "globex")}
+	opened := 0
+	tenants := NewTenants(func(tenant string) (*gorm.DB, error) {
+		cfg, ok := cfgs[tenant]
+		if !ok {
+			return nil, ErrUnknownTenant
+		}
+		opened++
+		return database.Open(cfg)
+	})
+	defer tenants.Close()
+
+	acme := WithTenant(context.Background(), "acme")
+	db, err := tenants.DB(acme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&user{Name: "acme user"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for tenant, want := range map[string]int64{"acme": 2, "globex": 1} {
+		db, err := tenants.DB(WithTenant(context.Background(), tenant))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int64
+		db.Model(&user{}).Count(&n)
+		if n != want {
+			t.Errorf("%s rows = %d, want %d", tenant, n, want)
+		}
+	}
+	if opened != 2 {
+		t.Errorf("opened %d databases, want 2", opened)
+	}
+
+	if _, err := tenants.DB(context.Background()); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("DB(no tenant) = %v, want ErrNoTenant", err)
+	}
+	if _, err := tenants.DB(WithTenant(context.Background(), "initech")); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("DB(unknown) = %v, want ErrUnknownTenant", err)
+	}
+	if _, err := tenants.DB(WithTenant(context.Background(), "../acme")); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("DB(../acme) = %v, want ErrInvalidTenant", err)
+	}
+}
+
+// 一个租户打开得慢时不挡住其他租户，同一个租户并发请求只打开一次
+func TestTenantsOpenOnce(t *testing.T) {
+	release := make(chan struct{})
+	var opened atomic.Int32
+	tenants := NewTenants(func(tenant string) (*gorm.DB, error) {
+		if tenant == "slow" {
+			opened.Add(1)
+			<-release
+		}
+		return database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	})
+	defer tenants.Close()
+
+	slow := WithTenant(context.Background(), "slow")
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tenants.DB(slow); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for opened.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := tenants.DB(WithTenant(context.Background(), "acme")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(slow, 10*time.Millisecond)
+	defer cancel()
+	if _, err := tenants.DB(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DB(slow, timeout) = %v", err)
+	}
+	close(release)
+	wg.Wait()
+	if n := opened.Load(); n != 1 {
+		t.Errorf("opened slow %d times, want 1", n)
+	}
+}
+
+func TestTablePrefix(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := TablePrefix{Required: true}
+	if err := db.Use(p); err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"acme", "globex"} {
+		if err := db.Table(p.Table(tenant, "user3")).AutoMigrate(&user{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	acme := db.WithContext(WithTenant(context.Background(), "acme"))
+	globex := db.WithContext(WithTenant(context.Background(), "globex"))
+	if err := acme.Create(&[]user{{Name: "a", Age: 20}, {Name: "b", Age: 30}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := globex.Create(&user{Name: "g", Age: 40}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Model(&user{}).Where("age > ?", 25).Update("age", 31).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Delete(&user{}, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var us []user
+	acme.Find(&us)
+	if len(us) != 1 || us[0].Name != "b" || us[0].Age != 31 {
+		t.Errorf("acme users = %+v", us)
+	}
+	// 已经带前缀的表名不会重复加
+	var n int64
+	acme.Table("acme_user3").Where("deleted_at IS NULL").Count(&n)
+	if n != 1 {
+		t.Errorf("acme_user3 count = %d, want 1", n)
+	}
+	globex.Model(&user{}).Count(&n)
+	if n != 1 {
+		t.Errorf("globex count = %d, want 1", n)
+	}
+	// Table() 里的表名同样按租户改写，不会读到别的租户
+	us = nil
+	globex.Table("user3").Find(&us)
+	if len(us) != 1 || us[0].Name != "g" {
+		t.Errorf("globex Table(user3) = %+v", us)
+	}
+	if err := globex.Table("user3").Where("name = ?", "g").Update("age", 41).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"user3 AS u", "main.user3"} {
+		if err := globex.Table(table).Find(&us).Error; !errors.Is(err, ErrTableExpr) {
+			t.Errorf("Find with Table(%q) = %v, want ErrTableExpr", table, err)
+		}
+	}
+	if err := globex.Table("(?) AS u", db.Table("acme_user3")).Find(&us).Error; !errors.Is(err, ErrTableExpr) {
+		t.Errorf("Find with subquery = %v, want ErrTableExpr", err)
+	}
+
+	if err := db.Find(&us).Error; !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Find without tenant = %v, want ErrNoTenant", err)
+	}
+	// 租户 ID 会拼进表名，不能带空格、引号这类字符
+	for _, tenant := range []string{"acme_user3 WHERE 1=1; --", "a`b", "acme-1"} {
+		err := db.WithContext(WithTenant(context.Background(), tenant)).Find(&us).Error
+		if !errors.Is(err, ErrInvalidTenant) {
+			t.Errorf("Find with tenant %q = %v, want ErrInvalidTenant", tenant, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoTenant context 里没有租户
+	ErrNoTenant = errors.New("routing: no tenant in context")
+	// ErrUnknownTenant 租户不存在，Tenants 的 open 函数可以返回它
+	ErrUnknownTenant = errors.New("routing: unknown tenant")
+	// ErrInvalidTenant 租户 ID 含有字母、数字、下划线以外的字符
+	ErrInvalidTenant = errors.New("routing: invalid tenant")
+	// ErrTableExpr 有租户时 Table() 用了别名、子查询、库名这类没法加前缀的表达式
+	ErrTableExpr = errors.New("routing: cannot prefix table expression")
+)
+
+// tenantPattern 租户 ID 会拼进表名和库名，只允许字母、数字、下划线
+var tenantPattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+type tenantKey struct{}
+
+// WithTenant 把租户 ID 放进 context，一般在 HTTP 中间件里根据域名、请求头或登录信息设置
+// 租户 ID 只能包含字母、数字、下划线，否则用到它的 Tenants.DB 和 TablePrefix 返回 ErrInvalidTenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom 取出租户 ID
+func TenantFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	t, ok := ctx.Value(tenantKey{}).(string)
+	return t, ok && t != ""
+}
+
+// tenantOf 取出并检查租户 ID
+func tenantOf(ctx context.Context) (string, error) {
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, tenant)
+	}
+	return tenant, nil
+}
+
+// Tenants 每个租户一个数据库，第一次用到时调用 open 打开，之后复用
+//
+//	tenants := routing.NewTenants(func(tenant string) (*gorm.DB, error) {
+//		return database.Open(database.Config{Name: "shop_" + tenant})
+//	})
+//	db, err := tenants.DB(routing.WithTenant(ctx, "acme"))
+type Tenants struct {
+	open func(tenant string) (*gorm.DB, error)
+
+	mu  sync.Mutex
+	dbs map[string]*tenantDB
+}
+
+// tenantDB 一个租户的库；ready 关闭之后 db、err 才能读
+type tenantDB struct {
+	ready chan struct{}
+	db    *gorm.DB
+	err   error
+}
+
+// NewTenants 创建租户路由，open 返回 ErrUnknownTenant 表示没有这个租户
+func NewTenants(open func(tenant string) (*gorm.DB, error)) *Tenants {
+	return &Tenants{open: open, dbs: map[string]*tenantDB{}}
+}
+
+// DB 返回 ctx 里租户的数据库，已经绑定了 ctx
+// 同一个租户同时只打开一次，其他请求等它打开；打开（包括 ping 重试）时不影响别的租户
+// 打开失败不缓存，下次请求重新打开
+func (t *Tenants) DB(ctx context.Context) (*gorm.DB, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	e, ok := t.dbs[tenant]
+	if !ok {
+		e = &tenantDB{ready: make(chan struct{})}
+		t.dbs[tenant] = e
+	}
+	t.mu.Unlock()
+	if !ok {
+		t.load(tenant, e)
+	}
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if e.err != nil {
+		return nil, fmt.Errorf("routing: open tenant %q: %w", tenant, e.err)
+	}
+	return e.db.WithContext(ctx), nil
+}
+
+func (t *Tenants) load(tenant string, e *tenantDB) {
+	defer close(e.ready)
+	if e.db, e.err = t.open(tenant); e.err != nil {
+		t.mu.Lock()
+		if t.dbs[tenant] == e {
+			delete(t.dbs, tenant)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// Close 关闭已经打开的所有租户数据库，正在打开的等它打开后关闭
+func (t *Tenants) Close() error {
+	t.mu.Lock()
+	dbs := t.dbs
+	t.dbs = map[string]*tenantDB{}
+	t.mu.Unlock()
+	var errs []error
+	for _, e := range dbs {
+		<-e.ready
+		if e.db != nil {
+			errs = append(errs, Close(e.db))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TablePrefix 所有租户共用一个库，按租户给表名加前缀：租户 acme 的 user3 表是 acme_user3
+//
+//	db.Use(routing.TablePrefix{Required: true})
+//	db.WithContext(routing.WithTenant(ctx, "acme")).Find(&users)
+//	// SELECT * FROM acme_user3
+//
+// 只改写 gorm 根据模型或 Table("user3") 得到的表名；Table() 里带别名、子查询、库名时返回 ErrTableExpr，
+// 不会漏到别的租户的表；Raw/Exec 里的 SQL 和 Where 字符串里写死的表名不会改写
+type TablePrefix struct {
+	// Prefix 租户对应的前缀，默认 租户ID + "_"
+	Prefix func(tenant string) string
+	// Required 为 true 时 context 里没有租户会返回 ErrNoTenant；否则使用原表名
+	Required bool
+}
+
+func (TablePrefix) Name() string { return "routing:table_prefix" }
+
+func (p TablePrefix) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("routing:table_prefix", p.apply),
+		cb.Query().Before("*").Register("routing:table_prefix", p.apply),
+		cb.Update().Before("*").Register("routing:table_prefix", p.apply),
+		cb.Delete().Before("*").Register("routing:table_prefix", p.apply),
+		cb.Row().Before("*").Register("routing:table_prefix", p.apply),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Table 租户的表名，用于建表等不经过回调的操作：db.Table(p.Table("acme", "user3")).AutoMigrate(&User3{})
+func (p TablePrefix) Table(tenant, table string) string {
+	prefix := p.prefix(tenant)
+	if strings.HasPrefix(table, prefix) {
+		return table
+	}
+	return prefix + table
+}
+
+func (p TablePrefix) prefix(tenant string) string {
+	if p.Prefix != nil {
+		return p.Prefix(tenant)
+	}
+	return tenant + "_"
+}
+
+func (p TablePrefix) apply(db *gorm.DB) {
+	s := db.Statement
+	// Raw 的 SQL 已经写好了，不改写
+	if db.Error != nil || (s.Table == "" && s.TableExpr == nil) || s.SQL.Len() > 0 {
+		return
+	}
+	tenant, err := tenantOf(s.Context)
+	if errors.Is(err, ErrNoTenant) && !p.Required {
+		return
+	}
+	if err != nil {
+		db.AddError(fmt.Errorf("%w: table %s", err, s.Table))
+		return
+	}
+	// Table() 同时设置了 TableExpr，生成 SQL 时用的是它，只有单个表名才能跟着改写
+	if e := s.TableExpr; e != nil && (s.Table == "" || len(e.Vars) > 0 || e.SQL != s.Quote(s.Table)) {
+		db.AddError(fmt.Errorf("%w: %s", ErrTableExpr, e.SQL))
+		return
+	}
+	// 共用的 Statement 可能已经改写过
+	s.Table = p.Table(tenant, s.Table)
+	if s.TableExpr != nil {
+		s.TableExpr = &clause.Expr{SQL: s.Quote(s.Table)}
+	}
+}
@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Format   Format
+	Where    string // 原生条件，例如 "age > ?"；为空时导出全部
+	Args     []any
+	Unscoped bool // 包含软删除的记录
+	Batch    int  // 每批读取的行数，默认 DefaultBatch
+	// Progress 每写完一批调用一次，参数是累计导出的行数
+	Progress func(rows int64)
+}
+
+// Export 按主键顺序分批读取 model 对应的表写到 w，返回导出的行数
+// CSV 第一行是列名，NULL 写成空字符串；JSONL 每行一个对象，NULL 写成 null；时间都是 RFC 3339
+func Export(ctx context.Context, db *gorm.DB, model any, w io.Writer, o ExportOptions) (int64, error) {
+	s, err := parseSchema(db, model)
+	if err != nil {
+		return 0, err
+	}
+	cols := columns(s)
+	enc, err := newEncoder(o.Format, w, cols)
+	if err != nil {
+		return 0, err
+	}
+	if o.Batch <= 0 {
+		o.Batch = DefaultBatch
+	}
+
+	tx := db.WithContext(ctx).Model(model)
+	if o.Unscoped {
+		tx = tx.Unscoped()
+	}
+	if o.Where != "" {
+		tx = tx.Where(o.Where, o.Args...)
+	}
+	var total int64
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	err = tx.FindInBatches(rows.Interface(), o.Batch, func(_ *gorm.DB, _ int) error {
+		batch := rows.Elem()
+		values := make([]any, len(cols))
+		for i := 0; i < batch.Len(); i++ {
+			for j, f := range cols {
+				v, err := encode(ctx, f, batch.Index(i))
+				if err != nil {
+					return err
+				}
+				values[j] = v
+			}
+			if err := enc.encode(values); err != nil {
+				return err
+			}
+		}
+		if err := enc.flush(); err != nil {
+			return err
+		}
+		total += int64(batch.Len())
+		if o.Progress != nil {
+			o.Progress(total)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return total, err
+	}
+	return total, enc.flush()
+}
+
+type encoder interface {
+	encode(values []any) error
+	flush() error
+}
+
+func newEncoder(format Format, w io.Writer, cols []*schema.Field) (encoder, error) {
+	names := make([]string, len(cols))
+	for i, f := range cols {
+		names[i] = f.DBName
+	}
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		// 表头在第一批之前写，空表也有表头
+		return &csvEncoder{w: cw}, cw.Write(names)
+	case JSONL:
+		keys := make([][]byte, len(names))
+		for i, name := range names {
+			keys[i], _ = json.Marshal(name)
+		}
+		return &jsonlEncoder{w: bufio.NewWriter(w), keys: keys}, nil
+	}
+	_, err := ParseFormat(string(format))
+	return nil, err
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (e *csvEncoder) encode(values []any) error {
+	e.record = e.record[:0]
+	for _, v := range values {
+		e.record = append(e.record, text(v))
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlEncoder 按列的顺序写对象，不经过 map，输出稳定
+type jsonlEncoder struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (e *jsonlEncoder) encode(values []any) error {
+	e.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		e.w.Write(b)
+	}
+	e.w.WriteByte('}')
+	return e.w.WriteByte('\n')
+}
+
+func (e *jsonlEncoder) flush() error { return e.w.Flush() }
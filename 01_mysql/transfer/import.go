@@ -0,0 +1,303 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"mysql-demo/validate"
+)
+
+// Conflict 导入的行和已有的行主键（或 ConflictColumns）冲突时怎么办
+type Conflict string
+
+const (
+	ConflictError  Conflict = "error"  // 默认，记为失败行
+	ConflictIgnore Conflict = "ignore" // 跳过，保留已有的行
+	ConflictUpdate Conflict = "update" // 用导入的值覆盖已有的行（upsert）
+)
+
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case ConflictError, ConflictIgnore, ConflictUpdate:
+		return c, nil
+	case "":
+		return ConflictError, nil
+	}
+	return "", fmt.Errorf("transfer: unknown conflict mode %q, want error, ignore or update", s)
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Format     Format
+	Batch      int // 每批写入的行数，默认 DefaultBatch
+	OnConflict Conflict
+	// ConflictColumns 判断冲突的唯一键（列名或字段名），默认主键；只对 ConflictUpdate 生效
+	ConflictColumns []string
+	// Progress 每写完一批调用一次
+	Progress func(r Report)
+}
+
+// RowError 一行导入失败：解析、校验或写入出错
+type RowError struct {
+	Line int // 文件里的行号，从 1 开始；CSV 的第 1 行是表头
+	Err  error
+}
+
+func (e RowError) Error() string { return fmt.Sprintf("line %d: %v", e.Line, e.Err) }
+
+func (e RowError) Unwrap() error { return e.Err }
+
+// Report 导入结果
+type Report struct {
+	Read    int64 // 读到的数据行
+	Written int64 // 写入成功的行，ConflictIgnore 跳过的行也算
+	Failed  []RowError
+}
+
+// Import 从 r 读取行，校验后按批写入 model 对应的表
+//
+// 单行失败（格式不对、校验不过、违反唯一约束……）记在 Report.Failed 里，继续导入后面的行；
+// 一批写入失败时逐行重试，找出具体是哪几行。只有读文件出错、表头里有未知的列时返回 error
+func Import(ctx context.Context, db *gorm.DB, model any, r io.Reader, o ImportOptions) (Report, error) {
+	var report Report
+	s, err := parseSchema(db, model)
+	if err != nil {
+		return report, err
+	}
+	dec, err := newDecoder(o.Format, r, s)
+	if err != nil {
+		return report, err
+	}
+	if o.Batch <= 0 {
+		o.Batch = DefaultBatch
+	}
+	tx := db.WithContext(ctx).Omit(clause.Associations)
+	if c, err := onConflict(s, o); err != nil {
+		return report, err
+	} else if c != nil {
+		tx = tx.Clauses(*c)
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	batch := reflect.MakeSlice(reflect.SliceOf(s.ModelType), 0, o.Batch)
+	var lines []int
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		ptr := reflect.New(batch.Type())
+		ptr.Elem().Set(batch)
+		if err := tx.CreateInBatches(ptr.Interface(), o.Batch).Error; err == nil {
+			report.Written += int64(len(lines))
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		} else {
+			// 逐行重试，把失败定位到行
+			for i, line := range lines {
+				if err := tx.Create(ptr.Elem().Index(i).Addr().Interface()).Error; err != nil {
+					report.Failed = append(report.Failed, RowError{Line: line, Err: err})
+				} else {
+					report.Written++
+				}
+			}
+		}
+		batch, lines = batch.Slice(0, 0), lines[:0]
+		if o.Progress != nil {
+			o.Progress(report)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		line, values, err := dec.next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr RowError
+		if errors.As(err, &rowErr) {
+			report.Read++
+			report.Failed = append(report.Failed, rowErr)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Read++
+
+		rv := reflect.New(s.ModelType)
+		if err := fill(ctx, rv.Elem(), values); err == nil {
+			err = validate.Struct(rv.Interface())
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, RowError{Line: line, Err: err})
+			continue
+		}
+		batch, lines = reflect.Append(batch, rv.Elem()), append(lines, line)
+		if len(lines) == o.Batch {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	err = flush()
+	// 解析失败的行当时就记下了，写入失败的行要等到这一批写完，按行号排一下
+	slices.SortStableFunc(report.Failed, func(a, b RowError) int { return a.Line - b.Line })
+	return report, err
+}
+
+func onConflict(s *schema.Schema, o ImportOptions) (*clause.OnConflict, error) {
+	switch o.OnConflict {
+	case ConflictIgnore:
+		return &clause.OnConflict{DoNothing: true}, nil
+	case ConflictUpdate:
+		c := &clause.OnConflict{UpdateAll: true}
+		for _, name := range o.ConflictColumns {
+			f := s.LookUpField(name)
+			if f == nil || f.DBName == "" {
+				return nil, fmt.Errorf("transfer: unknown conflict column %q in %s", name, s.Table)
+			}
+			c.Columns = append(c.Columns, clause.Column{Name: f.DBName})
+		}
+		return c, nil
+	case ConflictError, "":
+		return nil, nil
+	}
+	_, err := ParseConflict(string(o.OnConflict))
+	return nil, err
+}
+
+// value 一列的原始值
+type value struct {
+	field *schema.Field
+	raw   any
+}
+
+func fill(ctx context.Context, rv reflect.Value, values []value) error {
+	for _, v := range values {
+		if err := decode(ctx, v.field, rv, v.raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decoder 逐行读取；单行的格式错误返回 RowError，读完返回 io.EOF
+type decoder interface {
+	next() (line int, values []value, err error)
+}
+
+func newDecoder(format Format, r io.Reader, s *schema.Schema) (decoder, error) {
+	switch format {
+	case CSV:
+		return newCSVDecoder(r, s)
+	case JSONL:
+		return &jsonlDecoder{r: bufio.NewReader(r), s: s}, nil
+	}
+	_, err := ParseFormat(string(format))
+	return nil, err
+}
+
+type csvDecoder struct {
+	r      *csv.Reader
+	fields []*schema.Field
+}
+
+func newCSVDecoder(r io.Reader, s *schema.Schema) (*csvDecoder, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("transfer: empty csv, want a header line")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transfer: read csv header: %w", err)
+	}
+	d := &csvDecoder{r: cr}
+	for _, name := range header {
+		f, err := lookup(s, name)
+		if err != nil {
+			return nil, err
+		}
+		d.fields = append(d.fields, f)
+	}
+	return d, nil
+}
+
+func (d *csvDecoder) next() (int, []value, error) {
+	record, err := d.r.Read()
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return perr.StartLine, nil, RowError{Line: perr.StartLine, Err: perr.Err}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	line, _ := d.r.FieldPos(0)
+	values := make([]value, len(record))
+	for i, raw := range record {
+		values[i] = value{d.fields[i], raw}
+	}
+	return line, values, nil
+}
+
+type jsonlDecoder struct {
+	r    *bufio.Reader
+	s    *schema.Schema
+	line int
+}
+
+func (d *jsonlDecoder) next() (int, []value, error) {
+	for {
+		b, err := d.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			return 0, nil, err
+		}
+		d.line++
+		if b = bytes.TrimSpace(b); len(b) == 0 {
+			continue
+		}
+		var obj map[string]any
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return d.line, nil, RowError{Line: d.line, Err: err}
+		}
+		values := make([]value, 0, len(obj))
+		for name, raw := range obj {
+			f, err := lookup(d.s, name)
+			if err != nil {
+				return d.line, nil, RowError{Line: d.line, Err: err}
+			}
+			switch raw.(type) {
+			case map[string]any, []any:
+				return d.line, nil, RowError{Line: d.line, Err: fmt.Errorf("%s: want a scalar value", name)}
+			}
+			values = append(values, value{f, raw})
+		}
+		return d.line, values, nil
+	}
+}
+
+// lookup 按列名或字段名找到字段，关联字段和 gorm:"-" 的字段不能导入
+func lookup(s *schema.Schema, name string) (*schema.Field, error) {
+	f := s.LookUpField(name)
+	if f == nil || f.DBName == "" || !f.Creatable {
+		return nil, fmt.Errorf("transfer: unknown column %q in %s", name, s.Table)
+	}
+	return f, nil
+}
@@ -0,0 +1,177 @@
+// Package transfer 按模型把表导出成 CSV / JSON Lines，或者从文件导入
+//
+//	n, err := transfer.Export(ctx, db, &model.User3{}, os.Stdout, transfer.ExportOptions{Format: transfer.CSV, Where: "age > ?", Args: []any{18}})
+//	report, err := transfer.Import(ctx, db, &model.User3{}, f, transfer.ImportOptions{Format: transfer.JSONL, OnConflict: transfer.ConflictUpdate})
+//
+// 列名就是 gorm 的列名（column 标签，默认蛇形），导入时表头也可以写结构体字段名；关联字段不导出。
+// 导出用 FindInBatches、导入用 CreateInBatches，内存里只保留一批。
+// 导入时单行的解析或校验失败记在 Report 里，不影响其他行
+package transfer
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Format 文件格式
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+// ParseFormat 解析命令行参数，也接受文件扩展名（.csv、.jsonl、.ndjson）
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return CSV, nil
+	case "jsonl", "ndjson":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("transfer: unknown format %q, want csv or jsonl", s)
+}
+
+// DefaultBatch 默认每批的行数
+const DefaultBatch = 500
+
+func parseSchema(db *gorm.DB, model any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// columns 表里的列，按结构体字段的顺序
+func columns(s *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, name := range s.DBNames {
+		if f := s.FieldsByDBName[name]; f.Readable {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// timeLayouts 导入时依次尝试的时间格式，导出用第一个
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// encode 取出字段的值，转换成 nil、time.Time 或基本类型
+func encode(ctx context.Context, f *schema.Field, rv reflect.Value) (any, error) {
+	v, _ := f.ValueOf(ctx, rv)
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.DBName, err)
+		}
+	}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, nil
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil, nil
+	}
+	switch val.Kind() {
+	case reflect.Bool:
+		return val.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), nil
+	case reflect.String:
+		return val.String(), nil
+	case reflect.Slice:
+		if b, ok := val.Interface().([]byte); ok {
+			return string(b), nil
+		}
+	}
+	if t, ok := val.Interface().(time.Time); ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported type %s", f.DBName, f.FieldType)
+}
+
+// text CSV 里的写法，NULL 写成空字符串
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(timeLayouts[0])
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// decode 把文件里的值转换成字段的类型后写入 rv；空字符串和 null 保持零值
+func decode(ctx context.Context, f *schema.Field, rv reflect.Value, raw any) error {
+	var s string
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case bool:
+		s = strconv.FormatBool(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v) // json.Number
+	}
+	if s == "" {
+		return nil
+	}
+	v, err := convert(f, s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.DBName, err)
+	}
+	return f.Set(ctx, rv, v)
+}
+
+func convert(f *schema.Field, s string) (any, error) {
+	t := f.IndirectFieldType
+	if t == timeType || t == deletedAtType {
+		for _, layout := range timeLayouts {
+			if v, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", s)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, t.Bits())
+	case reflect.String:
+		return s, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return []byte(s), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", f.FieldType)
+}
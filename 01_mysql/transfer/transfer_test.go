@@ -0,0 +1,199 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/fixtures/fixturestest"
+	"mysql-demo/model"
+	"mysql-demo/validate"
+)
+
+func export(t *testing.T, db *gorm.DB, m any, o ExportOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), db, m, &buf, o); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func load(t *testing.T, db *gorm.DB, m any, format Format, data string, o ImportOptions) Report {
+	t.Helper()
+	o.Format = format
+	r, err := Import(context.Background(), db, m, strings.NewReader(data), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// 导出再导入到空库，两边再导出一次应该完全一样
+func TestRoundTrip(t *testing.T) {
+	src, _ := fixturestest.Open(t, "users", "edge_ages", "relations")
+	for _, format := range []Format{CSV, JSONL} {
+		for _, m := range []any{&model.User3{}, &model.Profile{}, &model.Order{}, &model.Role{}} {
+			o := ExportOptions{Format: format, Unscoped: true, Batch: 7}
+			data := export(t, src, m, o)
+
+			dst, _ := fixturestest.Open(t)
+			if _, ok := m.(*model.User3); !ok {
+				// 子表有外键，先导入用户
+				load(t, dst, &model.User3{}, format, export(t, src, &model.User3{}, o), ImportOptions{})
+			}
+			r := load(t, dst, m, format, data, ImportOptions{Batch: 10})
+			if len(r.Failed) > 0 || r.Read != r.Written || r.Read == 0 {
+				t.Fatalf("%s %T: report = %+v", format, m, r)
+			}
+			if got := export(t, dst, m, o); got != data {
+				t.Errorf("%s %T: round trip differs\nexported:\n%s\nre-exported:\n%s", format, m, data, got)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	db, _ := fixturestest.Open(t, "edge_ages")
+	var progress []int64
+	got := export(t, db, &model.User3{}, ExportOptions{
+		Format:   CSV,
+		Where:    "age < ?",
+		Args:     []any{100},
+		Batch:    1,
+		Progress: func(n int64) { progress = append(progress, n) },
+	})
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	// 软删除的 30 岁用户默认不导出
+	if len(lines) != 2 || lines[0] != "id,created_at,updated_at,deleted_at,name,age,birthday,version" ||
+		!strings.Contains(lines[1], ",边界18,18,") {
+		t.Errorf("csv =\n%s", got)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 1 {
+		t.Errorf("progress = %v", progress)
+	}
+
+	got = export(t, db, &model.User3{}, ExportOptions{Format: JSONL, Where: "age = 18"})
+	if !strings.HasPrefix(got, `{"id":1,"created_at":"`) || !strings.Contains(got, `"deleted_at":null,"name":"边界18","age":18,`) {
+		t.Errorf("jsonl = %s", got)
+	}
+
+	if _, err := Export(context.Background(), db, &model.User3{}, &bytes.Buffer{}, ExportOptions{Format: "xml"}); err == nil {
+		t.Error("Export(xml) should fail")
+	}
+}
+
+// 单行的错误不影响其他行，报告里带行号
+func TestImportFailures(t *testing.T) {
+	db, _ := fixturestest.Open(t)
+	csv := strings.Join([]string{
+		"ID,Name,age,birthday", // 表头可以写字段名
+		"1,张三,20,2000-01-02",
+		"2,小孩,1,",   // 校验失败
+		"3,李四,abc,", // 类型不对
+		"4,王五",      // 列数不对
+		"1,重复,30,",  // 主键冲突
+		`5,"多行` + "\n" + `名字",40,`,
+		"6,赵六,50,1970-01-01T08:00:00+08:00",
+	}, "\n")
+	r := load(t, db, &model.User3{}, CSV, csv, ImportOptions{Batch: 2})
+	if r.Read != 7 || r.Written != 3 {
+		t.Errorf("report = %+v", r)
+	}
+	wantLines := []int{3, 4, 5, 6}
+	if len(r.Failed) != len(wantLines) {
+		t.Fatalf("failed = %v", r.Failed)
+	}
+	for i, fe := range r.Failed {
+		if fe.Line != wantLines[i] {
+			t.Errorf("failed[%d] = %v, want line %d", i, fe, wantLines[i])
+		}
+	}
+	if errs, ok := validate.As(r.Failed[0]); !ok || errs.Fields()["Age"] == "" {
+		t.Errorf("failed[0] = %v, want a validation error", r.Failed[0])
+	}
+
+	var u model.User3
+	if err := db.First(&u, 5).Error; err != nil || u.Name != "多行\n名字" || u.Version != 1 {
+		t.Errorf("user 5 = %+v, %v", u, err)
+	}
+	u = model.User3{}
+	if err := db.First(&u, 1).Error; err != nil || u.Birthday == nil || u.Birthday.Format("2006-01-02") != "2000-01-02" {
+		t.Errorf("user 1 = %+v, %v", u, err)
+	}
+
+	jsonl := `{"name":"a","age":20}
+
+{"name":"b","age":"21"}
+{"name":"c","age":20,"profile":{"bio":"x"}}
+{"name":"d","age":[20]}
+not json
+{"name":"e","age":20,"nickname":"x"}
+`
+	r = load(t, db, &model.User3{}, JSONL, jsonl, ImportOptions{})
+	if r.Read != 6 || r.Written != 2 || len(r.Failed) != 4 {
+		t.Fatalf("jsonl report = %+v", r)
+	}
+	for i, line := range []int{4, 5, 6, 7} {
+		if r.Failed[i].Line != line {
+			t.Errorf("jsonl failed[%d] = %v, want line %d", i, r.Failed[i], line)
+		}
+	}
+
+	if _, err := Import(context.Background(), db, &model.User3{}, strings.NewReader("id,nickname\n"), ImportOptions{Format: CSV}); err == nil ||
+		!strings.Contains(err.Error(), `unknown column "nickname"`) {
+		t.Errorf("unknown header = %v", err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, _ := fixturestest.Open(t, "relations")
+	var before model.Order
+	if err := db.First(&before).Error; err != nil {
+		t.Fatal(err)
+	}
+	data := "no,user_id,amount,status\n" + before.No + ",1,1,paid\nNEW0001,1,2,pending\n"
+
+	// 默认：冲突的行失败，其他行照常写入
+	r := load(t, db, &model.Order{}, CSV, data, ImportOptions{})
+	if r.Written != 1 || len(r.Failed) != 1 || r.Failed[0].Line != 2 {
+		t.Errorf("error mode: %+v", r)
+	}
+	r = load(t, db, &model.Order{}, CSV, data, ImportOptions{OnConflict: ConflictIgnore})
+	if r.Written != 2 || len(r.Failed) != 0 {
+		t.Errorf("ignore mode: %+v", r)
+	}
+	var got model.Order
+	db.First(&got, before.ID)
+	if got.Amount != before.Amount {
+		t.Errorf("ignore mode changed the row: %+v", got)
+	}
+
+	r = load(t, db, &model.Order{}, CSV, data, ImportOptions{OnConflict: ConflictUpdate, ConflictColumns: []string{"No"}})
+	if r.Written != 2 || len(r.Failed) != 0 {
+		t.Errorf("update mode: %+v", r)
+	}
+	db.First(&got, before.ID)
+	if got.Amount != 1 || got.Status != "paid" || got.UserID != 1 {
+		t.Errorf("after upsert: %+v", got)
+	}
+	var n int64
+	db.Model(&model.Order{}).Where("no = ?", "NEW0001").Count(&n)
+	if n != 1 {
+		t.Errorf("NEW0001 rows = %d", n)
+	}
+
+	_, err := Import(context.Background(), db, &model.Order{}, strings.NewReader(data), ImportOptions{Format: CSV, OnConflict: "merge"})
+	if err == nil {
+		t.Error("unknown conflict mode should fail")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Import(ctx, db, &model.Order{}, strings.NewReader(data), ImportOptions{Format: CSV}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled import = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Plugin 在 gorm 创建和更新之前按标签校验，校验失败时不会执行 SQL
+//
+//	db.Use(validate.Plugin{})
+//
+// 与 BeforeCreate 钩子不同，map 创建、批量创建和 Updates 也会校验：
+//   - 创建：struct 校验全部字段，map 缺少的字段按零值校验
+//   - 更新：只校验会被写入的字段（Select 的字段、struct 的非零字段、map 的 key）
+type Plugin struct{}
+
+func (Plugin) Name() string { return "validate" }
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:before_create").Register("validate:create", func(tx *gorm.DB) {
+		run(tx, false)
+	}); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:before_update").Register("validate:update", func(tx *gorm.DB) {
+		run(tx, true)
+	})
+}
+
+func run(db *gorm.DB, update bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.Dest == nil {
+		return
+	}
+	if err := validateDest(stmt, reflect.ValueOf(stmt.Dest), update, ""); err != nil {
+		db.AddError(err)
+	}
+}
+
+// validateDest 按 Dest 的形态分别校验：struct、map、以及它们的切片
+func validateDest(stmt *gorm.Statement, v reflect.Value, update bool, prefix string) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	var err error
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() != stmt.Schema.ModelType {
+			return nil
+		}
+		err = Fields(v.Interface(), writtenFields(stmt, v, update))
+	case reflect.Map:
+		values, ok := v.Interface().(map[string]any)
+		if !ok {
+			return nil
+		}
+		err = Map(reflect.New(stmt.Schema.ModelType).Interface(), byFieldName(stmt.Schema, values), update)
+	case reflect.Slice, reflect.Array:
+		var all Errors
+		for i := 0; i < v.Len(); i++ {
+			e := validateDest(stmt, v.Index(i), update, fmt.Sprintf("%s[%d].", prefix, i))
+			if errs, ok := e.(Errors); ok {
+				all = append(all, errs...)
+			} else if e != nil {
+				return e
+			}
+		}
+		if len(all) > 0 {
+			return all
+		}
+		return nil
+	default:
+		return nil
+	}
+	// 批量时字段名带上下标，例如 [2].Age
+	if errs, ok := err.(Errors); ok && prefix != "" {
+		for i := range errs {
+			errs[i].Field = prefix + errs[i].Field
+		}
+	}
+	return err
+}
+
+// byFieldName 把 map 的 key 统一成结构体字段名，key 也可以是列名
+// gorm.Expr 之类的表达式在数据库里计算，跳过校验
+func byFieldName(s *schema.Schema, values map[string]any) map[string]any {
+	out := make(map[string]any, len(values))
+	for k, v := range values {
+		if _, ok := v.(clause.Expression); ok {
+			continue
+		}
+		if f := s.LookUpField(k); f != nil {
+			k = f.Name
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// writtenFields 创建时校验全部字段；更新时只校验会写入数据库的字段
+func writtenFields(stmt *gorm.Statement, v reflect.Value, update bool) func(string) bool {
+	omit := names(stmt.Schema, stmt.Omits)
+	if !update {
+		if len(omit) == 0 {
+			return nil
+		}
+		return func(name string) bool { return !omit[name] }
+	}
+	selected := names(stmt.Schema, stmt.Selects)
+	return func(name string) bool {
+		if omit[name] {
+			return false
+		}
+		if len(selected) > 0 {
+			return selected["*"] || selected[name]
+		}
+		f := stmt.Schema.LookUpField(name)
+		if f == nil {
+			return false
+		}
+		_, zero := f.ValueOf(stmt.Context, v)
+		return !zero
+	}
+}
+
+func names(s *schema.Schema, cols []string) map[string]bool {
+	m := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		if c == "*" {
+			m["*"] = true
+			continue
+		}
+		if f := s.LookUpField(strings.TrimSpace(c)); f != nil {
+			m[f.Name] = true
+		}
+	}
+	return m
+}
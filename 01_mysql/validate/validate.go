@@ -0,0 +1,335 @@
+// Package validate 基于结构体标签的字段校验
+//
+//	type User3 struct {
+//		gorm.Model
+//		Name string `validate:"notblank,max=50"`
+//		Age  int    `validate:"min=18,max=150"`
+//	}
+//
+// 规则用逗号分隔：
+//
+//	required   不能是零值（指针不能为 nil）
+//	notblank   字符串去掉空白后不能为空
+//	min=N      数字不小于 N；字符串、切片长度不小于 N
+//	max=N      数字不大于 N；字符串、切片长度不大于 N
+//	oneof=a b  只能是列出的值之一，用空格分隔
+//	email      简单的邮箱格式检查
+//
+// 除了 required，其余规则遇到 nil 指针时跳过，所以可选字段用指针类型
+// 注册 Plugin 之后，gorm 的 struct、map、批量创建和更新都会按同样的规则校验
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError 一个字段的校验失败
+type FieldError struct {
+	Field   string `json:"field"` // 结构体字段名
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// Errors 汇总所有字段的校验失败，API 层可以用 errors.As 取出后返回 400
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Fields 字段名到错误信息，同一字段多条错误时只保留第一条，便于直接写进 JSON 响应
+func (e Errors) Fields() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, fe := range e {
+		if _, ok := m[fe.Field]; !ok {
+			m[fe.Field] = fe.Message
+		}
+	}
+	return m
+}
+
+// As 从错误链里取出 Errors
+func As(err error) (Errors, bool) {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
+
+// rule 一条解析好的规则
+type rule struct {
+	name  string
+	param string
+	num   float64
+	set   []string
+}
+
+// field 带规则的字段
+type field struct {
+	name  string
+	index []int
+	rules []rule
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+// fieldsOf 解析并缓存某个结构体类型上的规则，包括内嵌结构体（例如 gorm.Model）
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if v, ok := cache.Load(t); ok {
+		return v.([]field), nil
+	}
+	var fields []field
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			idx := append(index[:len(index):len(index)], i)
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := walk(sf.Type, idx); err != nil {
+					return err
+				}
+				continue
+			}
+			tag, ok := sf.Tag.Lookup("validate")
+			if !ok || tag == "" || tag == "-" || !sf.IsExported() {
+				continue
+			}
+			rules, err := parseRules(tag)
+			if err != nil {
+				return fmt.Errorf("validate: %s.%s: %w", t.Name(), sf.Name, err)
+			}
+			fields = append(fields, field{name: sf.Name, index: idx, rules: rules})
+		}
+		return nil
+	}
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+	cache.Store(t, fields)
+	return fields, nil
+}
+
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "required", "notblank", "email":
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad %s param %q", name, param)
+			}
+			r.num = n
+		case "oneof":
+			r.set = strings.Fields(param)
+			if len(r.set) == 0 {
+				return nil, errors.New("oneof needs at least one value")
+			}
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Struct 校验结构体（或结构体指针）的所有字段
+func Struct(v any) error {
+	return Fields(v, nil)
+}
+
+// Fields 只校验 only 里列出的字段（结构体字段名），only 为 nil 时校验全部
+// 用于部分更新：没有写入的字段不需要满足规则
+func Fields(v any, only func(name string) bool) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: want struct, got %T", v)
+	}
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	for _, f := range fields {
+		if only != nil && !only(f.name) {
+			continue
+		}
+		errs = append(errs, check(f, rv.FieldByIndex(f.index))...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Map 按模型 model 的规则校验 map，key 是结构体字段名
+// partial 为 true 时只校验 map 里出现的字段（更新），否则缺少的字段按零值校验（创建）
+func Map(model any, values map[string]any, partial bool) error {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: want struct model, got %T", model)
+	}
+	fields, err := fieldsOf(t)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	for _, f := range fields {
+		v, ok := values[f.name]
+		if !ok && partial {
+			continue
+		}
+		fv := reflect.New(t.FieldByIndex(f.index).Type).Elem()
+		if ok && v != nil {
+			rv := reflect.ValueOf(v)
+			if !assign(fv, rv) {
+				errs = append(errs, FieldError{Field: f.name, Rule: "type", Message: fmt.Sprintf("cannot use %T as %s", v, fv.Type())})
+				continue
+			}
+		}
+		errs = append(errs, check(f, fv)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// assign 把 map 里的值放进字段类型的变量里，允许指针/非指针和数字类型之间的转换
+func assign(dst, src reflect.Value) bool {
+	if dst.Kind() == reflect.Ptr {
+		if src.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				return true
+			}
+			src = src.Elem()
+		}
+		p := reflect.New(dst.Type().Elem())
+		if !assign(p.Elem(), src) {
+			return false
+		}
+		dst.Set(p)
+		return true
+	}
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			return true
+		}
+		src = src.Elem()
+	}
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Type().ConvertibleTo(dst.Type()) && isNumber(src.Kind()) == isNumber(dst.Kind()):
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return false
+	}
+	return true
+}
+
+var emailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// check 对一个字段值执行所有规则
+func check(f field, v reflect.Value) Errors {
+	var errs Errors
+	fail := func(r rule, format string, args ...any) {
+		errs = append(errs, FieldError{Field: f.name, Rule: r.name, Param: r.param, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, r := range f.rules {
+		if r.name == "required" {
+			if v.IsZero() {
+				fail(r, "is required")
+			}
+			continue
+		}
+		// 可选字段为 nil 时跳过其他规则
+		v := v
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+		switch r.name {
+		case "notblank":
+			if v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "" {
+				fail(r, "must not be blank")
+			}
+		case "min", "max":
+			n, isLen, ok := measure(v)
+			if !ok {
+				continue
+			}
+			if r.name == "min" && n < r.num {
+				if isLen {
+					fail(r, "length must be at least %s", r.param)
+				} else {
+					fail(r, "must be at least %s", r.param)
+				}
+			}
+			if r.name == "max" && n > r.num {
+				if isLen {
+					fail(r, "length must be at most %s", r.param)
+				} else {
+					fail(r, "must be at most %s", r.param)
+				}
+			}
+		case "oneof":
+			s := fmt.Sprint(v.Interface())
+			found := false
+			for _, want := range r.set {
+				found = found || s == want
+			}
+			if !found {
+				fail(r, "must be one of %s", strings.Join(r.set, ", "))
+			}
+		case "email":
+			if v.Kind() == reflect.String && v.String() != "" && !emailRe.MatchString(v.String()) {
+				fail(r, "must be a valid email address")
+			}
+		}
+	}
+	return errs
+}
+
+// measure 数字返回数值，字符串、切片、map 返回长度
+func measure(v reflect.Value) (n float64, isLen, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
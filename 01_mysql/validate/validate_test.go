@@ -0,0 +1,172 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mysql-demo/database"
+)
+
+// user 与课程里的 User3 一致
+type user struct {
+	gorm.Model
+	Name     string `validate:"notblank,max=10"`
+	Age      int    `validate:"min=18,max=150"`
+	Birthday *time.Time
+	Email    *string `validate:"email"`
+}
+
+func (user) TableName() string { return "user3" }
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// fields 把错误转成 字段 -> 规则，方便断言
+func fields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	errs, ok := As(err)
+	if !ok {
+		t.Fatalf("error %v is not validate.Errors", err)
+	}
+	m := map[string]string{}
+	for _, e := range errs {
+		m[e.Field] = e.Rule
+	}
+	return m
+}
+
+func count(db *gorm.DB) int64 {
+	var n int64
+	db.Model(&user{}).Count(&n)
+	return n
+}
+
+func TestStruct(t *testing.T) {
+	bad := "not-an-email"
+	err := Struct(&user{Name: "  ", Age: 200, Email: &bad})
+	got := fields(t, err)
+	if len(got) != 3 || got["Name"] != "notblank" || got["Age"] != "max" || got["Email"] != "email" {
+		t.Errorf("Struct() fields = %v", got)
+	}
+	if err := Struct(user{Name: "张三", Age: 18}); err != nil {
+		t.Errorf("Struct(valid) = %v", err)
+	}
+	// 字符串长度按字符算，不是字节
+	if err := Struct(user{Name: "张三李四王五赵六孙七", Age: 20}); err != nil {
+		t.Errorf("10 CJK chars = %v", err)
+	}
+
+	type bad1 struct {
+		X int `validate:"min=abc"`
+	}
+	if err := Struct(bad1{}); err == nil || !strings.Contains(err.Error(), "bad min param") {
+		t.Errorf("bad tag = %v", err)
+	}
+}
+
+func TestErrorsJSON(t *testing.T) {
+	err := Struct(user{Name: "", Age: 1})
+	errs, _ := As(fmt.Errorf("create: %w", err))
+	b, _ := json.Marshal(errs)
+	if !strings.Contains(string(b), `"field":"Age","rule":"min","param":"18","message":"must be at least 18"`) {
+		t.Errorf("json = %s", b)
+	}
+	if m := errs.Fields(); m["Name"] != "must not be blank" {
+		t.Errorf("Fields() = %v", m)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	db := openDB(t)
+
+	if err := db.Create(&user{Name: "张三", Age: 20}).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Create(&user{Name: "小孩", Age: 10}).Error
+	if got := fields(t, err); got["Age"] != "min" {
+		t.Errorf("struct create = %v", got)
+	}
+
+	// map 创建不会触发 BeforeCreate 钩子，但会触发校验；缺少的字段按零值校验
+	err = db.Model(&user{}).Create(map[string]any{"Name": "map"}).Error
+	if got := fields(t, err); got["Age"] != "min" {
+		t.Errorf("map create = %v", got)
+	}
+	// key 用列名也可以，数值类型会转换
+	if err := db.Model(&user{}).Create(map[string]any{"name": "map", "age": int64(30)}).Error; err != nil {
+		t.Errorf("map create with column names = %v", err)
+	}
+
+	// 批量创建：汇总所有行的错误，字段名带下标，一行都不写入
+	before := count(db)
+	err = db.Create([]*user{{Name: "a", Age: 20}, {Name: "", Age: 20}, {Name: "c", Age: 5}}).Error
+	got := fields(t, err)
+	if len(got) != 2 || got["[1].Name"] != "notblank" || got["[2].Age"] != "min" {
+		t.Errorf("batch create = %v", got)
+	}
+	err = db.Model(&user{}).Create([]map[string]any{{"Name": "ok", "Age": 20}, {"Name": "x", "Age": 999}}).Error
+	if got := fields(t, err); got["[1].Age"] != "max" {
+		t.Errorf("map batch create = %v", got)
+	}
+	if n := count(db); n != before {
+		t.Errorf("rows after failed batches = %d, want %d", n, before)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	db := openDB(t)
+	u := user{Name: "张三", Age: 20}
+	db.Create(&u)
+
+	// struct 更新只校验非零字段，Name 为空不会写入所以不报错
+	if err := db.Model(&u).Updates(user{Age: 30}).Error; err != nil {
+		t.Errorf("partial struct update = %v", err)
+	}
+	if err := db.Model(&u).Updates(user{Age: 300}).Error; fields(t, err)["Age"] != "max" {
+		t.Errorf("struct update = %v", err)
+	}
+	// Select 的字段零值也会写入，所以也要校验
+	err := db.Model(&u).Select("Name").Updates(user{}).Error
+	if got := fields(t, err); len(got) != 1 || got["Name"] != "notblank" {
+		t.Errorf("selected update = %v", got)
+	}
+	// map 和 Update 只校验出现的 key
+	if err := db.Model(&u).Updates(map[string]any{"age": 17}).Error; fields(t, err)["Age"] != "min" {
+		t.Errorf("map update = %v", err)
+	}
+	if err := db.Model(&u).Update("Name", " ").Error; fields(t, err)["Name"] != "notblank" {
+		t.Errorf("Update() = %v", err)
+	}
+	// 表达式在数据库里计算，跳过
+	if err := db.Model(&u).Update("age", gorm.Expr("age + 1")).Error; err != nil {
+		t.Errorf("expr update = %v", err)
+	}
+	// Save 写入全部字段
+	u.Name = ""
+	if err := db.Save(&u).Error; fields(t, err)["Name"] != "notblank" {
+		t.Errorf("Save() = %v", err)
+	}
+
+	var got user
+	db.First(&got, u.ID)
+	if got.Name != "张三" || got.Age != 31 {
+		t.Errorf("row = %+v", got)
+	}
+}
@@ -0,0 +1,64 @@
+// Package auth 登录、JWT 签发和校验、刷新令牌轮换
+//
+// 访问令牌（access）有效期短，每个请求都带上，只校验签名不查存储；
+// 刷新令牌（refresh）有效期长，只能用一次：换新令牌时旧的作废，
+// 已经用过的刷新令牌再次出现说明被盗用，同一次登录签发的整条链（family）都会被吊销
+//
+// 这个包不依赖 gin，HTTP 接口和中间件在 handler 包
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrBadCredentials = errors.New("auth: invalid username or password")
+	ErrUnknownUser    = errors.New("auth: unknown user")
+	ErrInvalidToken   = errors.New("auth: invalid token")
+	ErrExpiredToken   = errors.New("auth: token expired")
+	ErrRevokedToken   = errors.New("auth: token revoked")
+	// ErrTokenReused 刷新令牌被重复使用，整条链已经被吊销，用户需要重新登录
+	ErrTokenReused = errors.New("auth: refresh token reused")
+)
+
+// 令牌类型，放在 typ 声明里，防止把刷新令牌当访问令牌用
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+// Principal 登录的用户
+type Principal struct {
+	Subject string   // 用户名
+	Roles   []string // 角色，例如 admin
+}
+
+// Claims 令牌里的声明
+type Claims struct {
+	jwt.RegisteredClaims
+	Type   string   `json:"typ"`
+	Roles  []string `json:"roles,omitempty"`
+	Family string   `json:"fam,omitempty"` // 刷新令牌所属的登录
+}
+
+// HasRole 拥有 roles 中任意一个角色
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, r := range roles {
+		if slices.Contains(c.Roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// newID 随机 ID，用作 jti 和 family
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var admin = Principal{Subject: "admin", Roles: []string{"admin"}}
+
+// clock 可以拨动的时间，同时给 Tokens 和 MemoryStore 用
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newHS256(t *testing.T) (*Tokens, *clock) {
+	t.Helper()
+	tokens, err := NewHS256([]byte(strings.Repeat("k", MinSecretLen)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return withClock(tokens)
+}
+
+func withClock(tokens *Tokens) (*Tokens, *clock) {
+	c := &clock{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tokens.now = c.now
+	store := NewMemoryStore()
+	store.now = c.now
+	tokens.Store = store
+	return tokens, c
+}
+
+func lookup(ctx context.Context, subject string) (Principal, error) {
+	if subject != admin.Subject {
+		return Principal{}, ErrUnknownUser
+	}
+	return admin, nil
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	tokens, clock := newHS256(t)
+	pair, err := tokens.Issue(ctx, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 {
+		t.Errorf("pair = %+v", pair)
+	}
+	claims, err := tokens.Verify(pair.AccessToken)
+	if err != nil || claims.Subject != "admin" || !claims.HasRole("viewer", "admin") || claims.HasRole("viewer") {
+		t.Fatalf("Verify() = %+v, %v", claims, err)
+	}
+
+	// 刷新令牌不能当访问令牌用
+	if _, err := tokens.Verify(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(refresh) = %v", err)
+	}
+
+	// 篡改：把载荷里的角色改掉，签名不变
+	parts := strings.Split(pair.AccessToken, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), `"admin"]`, `"root"]`, 1))
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if _, err := tokens.Verify(forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(tampered payload) = %v", err)
+	}
+	if _, err := tokens.Verify(pair.AccessToken[:len(pair.AccessToken)-2]); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(truncated signature) = %v", err)
+	}
+
+	// alg=none 和别的密钥签名的令牌
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	other, _ := NewHS256([]byte(strings.Repeat("x", MinSecretLen)))
+	other.now = clock.now
+	otherPair, _ := other.Issue(ctx, admin)
+	for name, raw := range map[string]string{"none": none, "other key": otherPair.AccessToken, "garbage": "a.b.c"} {
+		if _, err := tokens.Verify(raw); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify(%s) = %v", name, err)
+		}
+	}
+
+	other, _ = NewHS256([]byte(strings.Repeat("k", MinSecretLen)))
+	other.Issuer, other.now = "someone-else", clock.now
+	otherPair, _ = other.Issue(ctx, admin)
+	if _, err := tokens.Verify(otherPair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(other issuer) = %v", err)
+	}
+
+	clock.t = clock.t.Add(tokens.AccessTTL + time.Second)
+	if _, err := tokens.Verify(pair.AccessToken); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify(expired) = %v", err)
+	}
+}
+
+func TestRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	tokens, err := LoadRS256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, clock := withClock(tokens)
+
+	pair, _ := tokens.Issue(context.Background(), admin)
+	if claims, err := tokens.Verify(pair.AccessToken); err != nil || claims.Subject != "admin" {
+		t.Fatalf("Verify() = %+v, %v", claims, err)
+	}
+
+	// 算法混淆：拿公钥当 HMAC 密钥签名
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	hs, _ := NewHS256(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	hs.now = clock.now
+	forged, _ := hs.Issue(context.Background(), admin)
+	if _, err := tokens.Verify(forged.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify(HS256 with public key) = %v", err)
+	}
+
+	if _, err := LoadRS256(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("LoadRS256(missing) = nil error")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	tokens, clock := newHS256(t)
+	first, _ := tokens.Issue(ctx, admin)
+
+	second, err := tokens.Refresh(ctx, first.RefreshToken, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token not rotated")
+	}
+	if _, err := tokens.Verify(second.AccessToken); err != nil {
+		t.Errorf("Verify(refreshed access) = %v", err)
+	}
+	if _, err := tokens.Refresh(ctx, first.AccessToken, lookup); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh(access token) = %v", err)
+	}
+
+	// 旧的刷新令牌又出现：判定为盗用，整条链吊销，连合法持有的新令牌也不能用了
+	if _, err := tokens.Refresh(ctx, first.RefreshToken, lookup); !errors.Is(err, ErrTokenReused) {
+		t.Errorf("Refresh(reused) = %v", err)
+	}
+	if _, err := tokens.Refresh(ctx, second.RefreshToken, lookup); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("Refresh(after reuse) = %v", err)
+	}
+
+	// 退出登录只影响自己这条链
+	a, _ := tokens.Issue(ctx, admin)
+	b, _ := tokens.Issue(ctx, admin)
+	if err := tokens.Revoke(ctx, a.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Refresh(ctx, a.RefreshToken, lookup); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("Refresh(logged out) = %v", err)
+	}
+	b, err = tokens.Refresh(ctx, b.RefreshToken, lookup)
+	if err != nil {
+		t.Errorf("Refresh(other session) = %v", err)
+	}
+
+	// 用户被删除后不能再刷新
+	gone, _ := tokens.Issue(ctx, Principal{Subject: "bob"})
+	if _, err := tokens.Refresh(ctx, gone.RefreshToken, lookup); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("Refresh(deleted user) = %v", err)
+	}
+
+	clock.t = clock.t.Add(tokens.RefreshTTL + time.Second)
+	if _, err := tokens.Refresh(ctx, b.RefreshToken, lookup); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Refresh(expired) = %v", err)
+	}
+}
+
+func TestStaticUsers(t *testing.T) {
+	ctx := context.Background()
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewStaticUsers([]StaticUser{{Name: "admin", PasswordHash: hash, Roles: []string{"admin"}}})
+
+	p, err := users.Authenticate(ctx, "admin", "s3cret")
+	if err != nil || p.Subject != "admin" || p.Roles[0] != "admin" {
+		t.Errorf("Authenticate() = %+v, %v", p, err)
+	}
+	for _, tc := range [][2]string{{"admin", "wrong"}, {"nobody", "s3cret"}} {
+		if _, err := users.Authenticate(ctx, tc[0], tc[1]); !errors.Is(err, ErrBadCredentials) {
+			t.Errorf("Authenticate(%s, %s) = %v", tc[0], tc[1], err)
+		}
+	}
+	if _, err := users.Lookup(ctx, "nobody"); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("Lookup(nobody) = %v", err)
+	}
+}
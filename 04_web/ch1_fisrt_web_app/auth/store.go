@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RefreshToken 服务端记录的刷新令牌
+type RefreshToken struct {
+	ID        string // jti
+	Family    string
+	Subject   string
+	ExpiresAt time.Time
+}
+
+// RefreshStore 保存刷新令牌的状态；多实例部署时换成 Redis 或数据库实现
+type RefreshStore interface {
+	Save(ctx context.Context, t RefreshToken) error
+	// Use 把令牌标记为已使用并返回它
+	// 不存在或已吊销返回 ErrRevokedToken；已经用过返回 ErrTokenReused，并吊销整条链
+	Use(ctx context.Context, id string) (RefreshToken, error)
+	RevokeFamily(ctx context.Context, family string) error
+}
+
+// MemoryStore 进程内的 RefreshStore，重启后所有刷新令牌失效
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]*memoryToken
+	now    func() time.Time
+}
+
+type memoryToken struct {
+	RefreshToken
+	used bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: map[string]*memoryToken{}, now: time.Now}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 顺便清理过期的，避免一直增长
+	now := s.now()
+	for id, v := range s.tokens {
+		if now.After(v.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[t.ID] = &memoryToken{RefreshToken: t}
+	return nil
+}
+
+func (s *MemoryStore) Use(ctx context.Context, id string) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.tokens[id]
+	if !ok {
+		return RefreshToken{}, ErrRevokedToken
+	}
+	if v.used {
+		s.revoke(v.Family)
+		return RefreshToken{}, ErrTokenReused
+	}
+	v.used = true
+	return v.RefreshToken, nil
+}
+
+func (s *MemoryStore) RevokeFamily(ctx context.Context, family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoke(family)
+	return nil
+}
+
+func (s *MemoryStore) revoke(family string) {
+	for id, v := range s.tokens {
+		if v.Family == family {
+			delete(s.tokens, id)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens 签发和校验令牌
+type Tokens struct {
+	Issuer     string        // iss，默认 first
+	AccessTTL  time.Duration // 访问令牌有效期，默认 15m
+	RefreshTTL time.Duration // 刷新令牌有效期，默认 7 天
+	Store      RefreshStore  // 刷新令牌的状态，默认 NewMemoryStore()
+
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	now       func() time.Time
+}
+
+// Pair 登录和刷新返回的一对令牌
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // 访问令牌的有效秒数
+}
+
+// MinSecretLen HS256 密钥的最小长度
+const MinSecretLen = 32
+
+// NewHS256 用共享密钥签名，签发和校验都在本服务时用
+func NewHS256(secret []byte) (*Tokens, error) {
+	if len(secret) < MinSecretLen {
+		return nil, fmt.Errorf("auth: HS256 secret must be at least %d bytes", MinSecretLen)
+	}
+	return newTokens(jwt.SigningMethodHS256, secret, secret), nil
+}
+
+// NewRS256 用 RSA 私钥签名，其他服务只需要公钥就能校验
+func NewRS256(key *rsa.PrivateKey) *Tokens {
+	return newTokens(jwt.SigningMethodRS256, key, &key.PublicKey)
+}
+
+// LoadRS256 从 PEM 文件读取 RSA 私钥（PKCS#1 或 PKCS#8）
+//
+//	openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:2048 -out jwt.pem
+func LoadRS256(path string) (*Tokens, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read private key: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse private key %s: %w", path, err)
+	}
+	return NewRS256(key), nil
+}
+
+func newTokens(method jwt.SigningMethod, signKey, verifyKey any) *Tokens {
+	return &Tokens{
+		Issuer:     "first",
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 7 * 24 * time.Hour,
+		Store:      NewMemoryStore(),
+		method:     method,
+		signKey:    signKey,
+		verifyKey:  verifyKey,
+		now:        time.Now,
+	}
+}
+
+// Issue 登录成功后签发，开始一条新的刷新链
+func (t *Tokens) Issue(ctx context.Context, p Principal) (Pair, error) {
+	return t.issue(ctx, p, newID())
+}
+
+func (t *Tokens) issue(ctx context.Context, p Principal, family string) (Pair, error) {
+	now := t.now()
+	access, err := t.sign(Claims{
+		RegisteredClaims: t.registered(p.Subject, now, t.AccessTTL),
+		Type:             TypeAccess,
+		Roles:            p.Roles,
+	})
+	if err != nil {
+		return Pair{}, err
+	}
+	rc := t.registered(p.Subject, now, t.RefreshTTL)
+	refresh, err := t.sign(Claims{RegisteredClaims: rc, Type: TypeRefresh, Family: family})
+	if err != nil {
+		return Pair{}, err
+	}
+	rt := RefreshToken{ID: rc.ID, Family: family, Subject: p.Subject, ExpiresAt: rc.ExpiresAt.Time}
+	if err := t.Store.Save(ctx, rt); err != nil {
+		return Pair{}, err
+	}
+	return Pair{AccessToken: access, RefreshToken: refresh, TokenType: "Bearer", ExpiresIn: int(t.AccessTTL / time.Second)}, nil
+}
+
+func (t *Tokens) registered(subject string, now time.Time, ttl time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ID:        newID(),
+		Issuer:    t.Issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+func (t *Tokens) sign(c Claims) (string, error) {
+	s, err := jwt.NewWithClaims(t.method, c).SignedString(t.signKey)
+	if err != nil {
+		return "", fmt.Errorf("auth: sign: %w", err)
+	}
+	return s, nil
+}
+
+// Verify 校验访问令牌：签名、算法、签发者、有效期和类型
+func (t *Tokens) Verify(raw string) (*Claims, error) {
+	return t.parse(raw, TypeAccess)
+}
+
+func (t *Tokens) parse(raw, typ string) (*Claims, error) {
+	var c Claims
+	_, err := jwt.ParseWithClaims(raw, &c, func(*jwt.Token) (any, error) { return t.verifyKey, nil },
+		// 只接受配置的算法，防止 alg=none 或者拿 RSA 公钥当 HMAC 密钥
+		jwt.WithValidMethods([]string{t.method.Alg()}),
+		jwt.WithIssuer(t.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(t.now),
+	)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrExpiredToken
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	case c.Type != typ:
+		return nil, fmt.Errorf("%w: want %s token, got %q", ErrInvalidToken, typ, c.Type)
+	}
+	return &c, nil
+}
+
+// Refresh 用刷新令牌换一对新令牌，旧的刷新令牌作废
+// lookup 重新读取用户，角色变更和删除用户在下一次刷新时生效
+func (t *Tokens) Refresh(ctx context.Context, raw string, lookup func(ctx context.Context, subject string) (Principal, error)) (Pair, error) {
+	c, err := t.parse(raw, TypeRefresh)
+	if err != nil {
+		return Pair{}, err
+	}
+	rt, err := t.Store.Use(ctx, c.ID)
+	if err != nil {
+		return Pair{}, err
+	}
+	p, err := lookup(ctx, rt.Subject)
+	if err != nil {
+		t.Store.RevokeFamily(ctx, rt.Family)
+		return Pair{}, err
+	}
+	return t.issue(ctx, p, rt.Family)
+}
+
+// Revoke 退出登录：吊销刷新令牌所在的整条链
+// 已经签发的访问令牌不会失效，要等它自然过期，所以 AccessTTL 要短
+func (t *Tokens) Revoke(ctx context.Context, raw string) error {
+	c, err := t.parse(raw, TypeRefresh)
+	if err != nil {
+		return err
+	}
+	return t.Store.RevokeFamily(ctx, c.Family)
+}
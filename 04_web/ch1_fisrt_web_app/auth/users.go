@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Users 校验用户名密码、按用户名查用户
+type Users interface {
+	// Authenticate 用户不存在和密码错误都返回 ErrBadCredentials，不让调用方区分
+	Authenticate(ctx context.Context, name, password string) (Principal, error)
+	// Lookup 用户不存在返回 ErrUnknownUser
+	Lookup(ctx context.Context, name string) (Principal, error)
+}
+
+// HashPassword bcrypt 哈希，结果可以直接写进配置文件
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+// CheckPassword 密码和哈希不匹配时返回 ErrBadCredentials
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrBadCredentials
+	}
+	return err
+}
+
+// StaticUser 配置文件里的用户
+type StaticUser struct {
+	Name         string   `yaml:"name"`
+	PasswordHash string   `yaml:"password_hash"` // go run ./cmd/hashpw 生成
+	Roles        []string `yaml:"roles"`
+}
+
+// StaticUsers 固定的用户列表，适合管理后台这类用户很少的场景
+type StaticUsers map[string]StaticUser
+
+func NewStaticUsers(users []StaticUser) StaticUsers {
+	m := make(StaticUsers, len(users))
+	for _, u := range users {
+		m[u.Name] = u
+	}
+	return m
+}
+
+// dummyHash 用户不存在时也比较一次，响应时间不暴露用户是否存在
+var dummyHash, _ = HashPassword("dummy password")
+
+func (s StaticUsers) Authenticate(ctx context.Context, name, password string) (Principal, error) {
+	u, ok := s[name]
+	if !ok {
+		CheckPassword(dummyHash, password)
+		return Principal{}, ErrBadCredentials
+	}
+	if err := CheckPassword(u.PasswordHash, password); err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: u.Name, Roles: u.Roles}, nil
+}
+
+func (s StaticUsers) Lookup(ctx context.Context, name string) (Principal, error) {
+	u, ok := s[name]
+	if !ok {
+		return Principal{}, ErrUnknownUser
+	}
+	return Principal{Subject: u.Name, Roles: u.Roles}, nil
+}
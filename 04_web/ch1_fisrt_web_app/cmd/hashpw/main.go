@@ -0,0 +1,37 @@
+// hashpw 生成 bcrypt 密码哈希，填到配置文件 auth.users[].password_hash
+//
+//	go run ./cmd/hashpw            从标准输入读取密码，不会留在 shell 历史里
+//	go run ./cmd/hashpw 'secret'
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"first/auth"
+)
+
+func main() {
+	var password string
+	if len(os.Args) > 1 {
+		password = os.Args[1]
+	} else {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatal(err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		log.Fatal("hashpw: empty password")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(hash)
+}
@@ -0,0 +1,324 @@
+// Package config 服务配置
+// 优先级：环境变量 > 配置文件 > 默认值
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+
+	"first/auth"
+	"first/ratelimit"
+)
+
+// ConfigEnv 指定配置文件路径的环境变量
+const ConfigEnv = "APP_CONFIG"
+
+// Duration 让配置文件里可以写 "30s"、"5m" 这样的时长
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config 服务配置
+type Config struct {
+	Mode   string `yaml:"mode"` // gin 模式：debug | release | test，默认 debug
+	Server Server `yaml:"server"`
+	Auth   Auth   `yaml:"auth"`
+
+	RateLimit RateLimit `yaml:"rate_limit"`
+
+	// Migrate 启动时执行 01_mysql 的数据库迁移，本地开发用；数据库连接本身用 DB_* 环境变量配置
+	Migrate bool `yaml:"migrate"`
+}
+
+// Server HTTP 服务器配置
+type Server struct {
+	// Addr 监听地址，默认 :8080；没有配置时和 r.Run() 一样读取 PORT 环境变量
+	Addr string `yaml:"addr"`
+	// TrustedProxies 前面的反向代理（IP 或 CIDR），只有来自它们的请求才按 X-Forwarded-For 取客户端 IP；
+	// 默认不信任任何代理，客户端 IP 就是连接的地址，否则谁都能伪造这个头绕过按 IP 限流
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout"` // 读取请求头超时，默认 5s
+	ReadTimeout       Duration `yaml:"read_timeout"`        // 读取整个请求超时，默认 15s
+	WriteTimeout      Duration `yaml:"write_timeout"`       // 写响应超时，默认 30s
+	IdleTimeout       Duration `yaml:"idle_timeout"`        // keep-alive 空闲连接超时，默认 60s
+
+	// ShutdownTimeout 收到 SIGTERM 后等待在途请求完成的最长时间，默认 10s
+	ShutdownTimeout Duration `yaml:"shutdown_timeout"`
+	// ReadinessDelay /readyz 返回 503 之后、停止接收新请求之前，等负载均衡摘除流量的时间，默认 0
+	ReadinessDelay Duration `yaml:"readiness_delay"`
+}
+
+// Auth 登录和 JWT 配置
+type Auth struct {
+	// Algorithm 签名算法：HS256（共享密钥）| RS256（RSA 私钥签名，公钥校验），默认 HS256
+	Algorithm string `yaml:"algorithm"`
+	// Secret HS256 密钥，至少 32 字节；debug 模式下为空时启动时随机生成，重启后令牌全部失效
+	Secret string `yaml:"secret"`
+	// PrivateKeyFile RS256 的 PEM 私钥文件
+	PrivateKeyFile string `yaml:"private_key_file"`
+
+	AccessTTL  Duration `yaml:"access_ttl"`  // 访问令牌有效期，默认 15m
+	RefreshTTL Duration `yaml:"refresh_ttl"` // 刷新令牌有效期，默认 168h
+
+	Users []auth.StaticUser `yaml:"users"`
+}
+
+// RateLimit 限流配置
+type RateLimit struct {
+	Store string `yaml:"store"` // 计数存在哪里：memory | redis，默认 memory；多实例部署要用 redis
+	Redis Redis  `yaml:"redis"`
+
+	Auth Rule `yaml:"auth"` // /auth/*，防止暴力破解密码
+	API  Rule `yaml:"api"`  // /api/v1 下需要登录的业务接口
+
+	// APIKeys 认可的 API key，key 为 api_key 时只有这些 key 单独计数，其他请求按 IP
+	APIKeys []string `yaml:"api_keys"`
+}
+
+// Redis 连接配置，Redis 协议兼容的服务都可以（Valkey、KeyDB 等）
+type Redis struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// Rule 一个路由分组的限流规则，limit 为 0 表示不限流
+type Rule struct {
+	Algorithm string   `yaml:"algorithm"` // token_bucket | sliding_window
+	Limit     int      `yaml:"limit"`     // window 内允许的请求数
+	Window    Duration `yaml:"window"`
+	Burst     int      `yaml:"burst"` // 令牌桶容量，默认等于 limit
+	Key       string   `yaml:"key"`   // 按什么限流：ip | api_key（X-API-Key 请求头，要在 api_keys 里）| user | route，默认 ip
+}
+
+// Rate 转换成 ratelimit 包的规则
+func (r Rule) Rate() ratelimit.Limit {
+	return ratelimit.Limit{Algorithm: ratelimit.Algorithm(r.Algorithm), Limit: r.Limit, Window: time.Duration(r.Window), Burst: r.Burst}
+}
+
+// Default 返回默认配置
+func Default() Config {
+	return Config{
+		Mode: gin.DebugMode,
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(15 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(10 * time.Second),
+		},
+		Auth: Auth{
+			Algorithm:  "HS256",
+			AccessTTL:  Duration(15 * time.Minute),
+			RefreshTTL: Duration(7 * 24 * time.Hour),
+		},
+		RateLimit: RateLimit{
+			Store: "memory",
+			Redis: Redis{Addr: "127.0.0.1:6379"},
+			Auth:  Rule{Algorithm: string(ratelimit.SlidingWindow), Limit: 10, Window: Duration(time.Minute), Key: "ip"},
+			API:   Rule{Algorithm: string(ratelimit.TokenBucket), Limit: 20, Window: Duration(time.Second), Burst: 40, Key: "user"},
+		},
+	}
+}
+
+// Load 加载配置：默认值 -> 配置文件 -> 环境变量
+// path 为空时读取环境变量 APP_CONFIG，仍为空则不读文件
+func Load(path string) (Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookup func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		path, _ = lookup(ConfigEnv)
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	if err := applyEnv(&cfg, lookup); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config: unsupported format %q, want .yaml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 用环境变量覆盖配置，变量名为 APP_ 加上大写的 yaml 字段名，例如 APP_SHUTDOWN_TIMEOUT
+// auth 下容易重名的字段加上 AUTH_，例如 APP_AUTH_SECRET
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	if port, ok := lookup("PORT"); ok && port != "" {
+		cfg.Server.Addr = ":" + port
+	}
+	strs := map[string]*string{
+		"APP_MODE": &cfg.Mode,
+		"APP_ADDR": &cfg.Server.Addr,
+
+		"APP_AUTH_ALGORITHM":        &cfg.Auth.Algorithm,
+		"APP_AUTH_SECRET":           &cfg.Auth.Secret,
+		"APP_AUTH_PRIVATE_KEY_FILE": &cfg.Auth.PrivateKeyFile,
+
+		"APP_RATE_LIMIT_STORE": &cfg.RateLimit.Store,
+		"APP_REDIS_ADDR":       &cfg.RateLimit.Redis.Addr,
+		"APP_REDIS_PASSWORD":   &cfg.RateLimit.Redis.Password,
+	}
+	for key, p := range strs {
+		if v, ok := lookup(key); ok {
+			*p = v
+		}
+	}
+
+	if v, ok := lookup("APP_TRUSTED_PROXIES"); ok {
+		cfg.Server.TrustedProxies = strings.Split(v, ",")
+	}
+	if v, ok := lookup("APP_RATE_LIMIT_API_KEYS"); ok {
+		cfg.RateLimit.APIKeys = strings.Split(v, ",")
+	}
+
+	if v, ok := lookup("APP_MIGRATE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: APP_MIGRATE: %w", err)
+		}
+		cfg.Migrate = b
+	}
+
+	durs := map[string]*Duration{
+		"APP_READ_HEADER_TIMEOUT": &cfg.Server.ReadHeaderTimeout,
+		"APP_READ_TIMEOUT":        &cfg.Server.ReadTimeout,
+		"APP_WRITE_TIMEOUT":       &cfg.Server.WriteTimeout,
+		"APP_IDLE_TIMEOUT":        &cfg.Server.IdleTimeout,
+		"APP_SHUTDOWN_TIMEOUT":    &cfg.Server.ShutdownTimeout,
+		"APP_READINESS_DELAY":     &cfg.Server.ReadinessDelay,
+		"APP_ACCESS_TTL":          &cfg.Auth.AccessTTL,
+		"APP_REFRESH_TTL":         &cfg.Auth.RefreshTTL,
+	}
+	for key, p := range durs {
+		if v, ok := lookup(key); ok {
+			if err := p.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("config: %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 检查配置
+func (c Config) Validate() error {
+	switch c.Mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		return fmt.Errorf("config: unknown mode %q, want debug, release or test", c.Mode)
+	}
+	if c.Server.Addr == "" {
+		return fmt.Errorf("config: server.addr is empty")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("config: server.shutdown_timeout must be positive")
+	}
+	for _, p := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(p); err != nil {
+			if _, err := netip.ParseAddr(p); err != nil {
+				return fmt.Errorf("config: server.trusted_proxies: %q is neither an IP nor a CIDR", p)
+			}
+		}
+	}
+	if err := c.Auth.validate(c.Mode); err != nil {
+		return err
+	}
+	return c.RateLimit.validate()
+}
+
+func (r RateLimit) validate() error {
+	switch r.Store {
+	case "memory":
+	case "redis":
+		if r.Redis.Addr == "" {
+			return fmt.Errorf("config: rate_limit.redis.addr is required for the redis store")
+		}
+	default:
+		return fmt.Errorf("config: unknown rate_limit.store %q, want memory or redis", r.Store)
+	}
+	for name, rule := range map[string]Rule{"auth": r.Auth, "api": r.API} {
+		if rule.Limit == 0 {
+			continue
+		}
+		if err := rule.Rate().Validate(); err != nil {
+			return fmt.Errorf("config: rate_limit.%s: %w", name, err)
+		}
+		switch rule.Key {
+		case "ip", "route":
+		case "api_key":
+			if len(r.APIKeys) == 0 {
+				return fmt.Errorf("config: rate_limit.%s: key api_key needs rate_limit.api_keys", name)
+			}
+		case "user":
+			// /auth 下的请求还没有登录，按 user 限流实际上全部按 IP
+			if name == "auth" {
+				return fmt.Errorf("config: rate_limit.auth: key user is not available before login, want ip, api_key or route")
+			}
+		default:
+			return fmt.Errorf("config: rate_limit.%s: unknown key %q, want ip, api_key, user or route", name, rule.Key)
+		}
+	}
+	return nil
+}
+
+func (a Auth) validate(mode string) error {
+	switch a.Algorithm {
+	case "HS256":
+		if a.Secret == "" && mode == gin.ReleaseMode {
+			return fmt.Errorf("config: auth.secret is required in release mode")
+		}
+		if a.Secret != "" && len(a.Secret) < auth.MinSecretLen {
+			return fmt.Errorf("config: auth.secret must be at least %d bytes", auth.MinSecretLen)
+		}
+	case "RS256":
+		if a.PrivateKeyFile == "" {
+			return fmt.Errorf("config: auth.private_key_file is required for RS256")
+		}
+	default:
+		return fmt.Errorf("config: unknown auth.algorithm %q, want HS256 or RS256", a.Algorithm)
+	}
+	if a.AccessTTL <= 0 || a.RefreshTTL <= 0 {
+		return fmt.Errorf("config: auth.access_ttl and auth.refresh_ttl must be positive")
+	}
+	return nil
+}
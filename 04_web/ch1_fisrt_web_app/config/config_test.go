@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil || !reflect.DeepEqual(cfg, Default()) {
+		t.Fatalf("defaults = %+v, %v", cfg, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	os.WriteFile(path, []byte(`mode: release
+server:
+  addr: ":9000"
+  shutdown_timeout: 30s
+auth:
+  secret: 0123456789abcdef0123456789abcdef
+  users:
+    - name: admin
+      password_hash: $2a$10$hash
+      roles: [admin]
+`), 0o644)
+	cfg, err = load("", env(map[string]string{ConfigEnv: path, "APP_READINESS_DELAY": "2s", "APP_MIGRATE": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cfg.Server
+	if cfg.Mode != "release" || s.Addr != ":9000" || s.ShutdownTimeout != Duration(30*time.Second) ||
+		s.ReadinessDelay != Duration(2*time.Second) || s.ReadTimeout != Default().Server.ReadTimeout || !cfg.Migrate {
+		t.Errorf("file + env = %+v", cfg)
+	}
+	if a := cfg.Auth; a.Algorithm != "HS256" || len(a.Users) != 1 || a.Users[0].Roles[0] != "admin" || a.Users[0].PasswordHash != "$2a$10$hash" {
+		t.Errorf("auth = %+v", a)
+	}
+
+	// 环境变量优先于文件，APP_ADDR 优先于 PORT
+	cfg, _ = load(path, env(map[string]string{"PORT": "3000"}))
+	if cfg.Server.Addr != ":3000" {
+		t.Errorf("PORT: addr = %q", cfg.Server.Addr)
+	}
+	cfg, _ = load(path, env(map[string]string{"PORT": "3000", "APP_ADDR": "127.0.0.1:4000"}))
+	if cfg.Server.Addr != "127.0.0.1:4000" {
+		t.Errorf("APP_ADDR: addr = %q", cfg.Server.Addr)
+	}
+
+	for _, tc := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"APP_MODE": "prod"}, `unknown mode "prod"`},
+		{map[string]string{"APP_SHUTDOWN_TIMEOUT": "soon"}, "APP_SHUTDOWN_TIMEOUT"},
+		{map[string]string{"APP_MIGRATE": "maybe"}, "APP_MIGRATE"},
+		{map[string]string{"APP_MODE": "release"}, "auth.secret is required"},
+		{map[string]string{"APP_AUTH_SECRET": "short"}, "at least 32 bytes"},
+		{map[string]string{"APP_AUTH_ALGORITHM": "RS256"}, "private_key_file is required"},
+		{map[string]string{"APP_AUTH_ALGORITHM": "none"}, `unknown auth.algorithm "none"`},
+		{map[string]string{"APP_ACCESS_TTL": "0s"}, "access_ttl"},
+		{map[string]string{"APP_RATE_LIMIT_STORE": "etcd"}, `unknown rate_limit.store "etcd"`},
+		{map[string]string{"APP_RATE_LIMIT_STORE": "redis", "APP_REDIS_ADDR": ""}, "rate_limit.redis.addr is required"},
+		{map[string]string{"APP_SHUTDOWN_TIMEOUT": "0s"}, "shutdown_timeout must be positive"},
+		{map[string]string{"APP_TRUSTED_PROXIES": "10.0.0.0/8,proxy"}, `"proxy" is neither an IP nor a CIDR`},
+		{map[string]string{ConfigEnv: "app.toml"}, "read"},
+	} {
+		if _, err := load("", env(tc.env)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("load(%v) = %v, want %q", tc.env, err, tc.want)
+		}
+	}
+}
+
+func TestExample(t *testing.T) {
+	cfg, err := load("../config.example.yaml", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Auth.Users) != 1 || cfg.Auth.AccessTTL != Default().Auth.AccessTTL {
+		t.Errorf("example auth = %+v", cfg.Auth)
+	}
+	if !reflect.DeepEqual(cfg.RateLimit, Default().RateLimit) {
+		t.Errorf("example rate_limit = %+v, want defaults %+v", cfg.RateLimit, Default().RateLimit)
+	}
+
+	// 规则里没写的字段保留默认值，limit: 0 关闭
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	os.WriteFile(path, []byte("rate_limit:\n  auth:\n    limit: 3\n  api:\n    limit: 0\n    key: nobody\n"), 0o644)
+	cfg, err = load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := cfg.RateLimit.Auth; a.Limit != 3 || a.Window != Duration(time.Minute) || a.Algorithm != "sliding_window" {
+		t.Errorf("merged auth rule = %+v", a)
+	}
+	os.WriteFile(path, []byte("rate_limit:\n  api:\n    key: nobody\n"), 0o644)
+	if _, err := load(path, env(nil)); err == nil || !strings.Contains(err.Error(), `rate_limit.api: unknown key "nobody"`) {
+		t.Errorf("unknown key: %v", err)
+	}
+
+	// /auth 下还没有登录用户；按 api_key 限流要列出认可的 key
+	for yml, want := range map[string]string{
+		"rate_limit:\n  auth:\n    key: user\n":   "rate_limit.auth: key user is not available before login",
+		"rate_limit:\n  api:\n    key: api_key\n": "rate_limit.api: key api_key needs rate_limit.api_keys",
+	} {
+		os.WriteFile(path, []byte(yml), 0o644)
+		if _, err := load(path, env(nil)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: %v, want %q", yml, err, want)
+		}
+	}
+	os.WriteFile(path, []byte("rate_limit:\n  api:\n    key: api_key\n  api_keys: [k1]\n"), 0o644)
+	cfg, err = load(path, env(map[string]string{"APP_RATE_LIMIT_API_KEYS": "k2,k3"}))
+	if err != nil || !reflect.DeepEqual(cfg.RateLimit.APIKeys, []string{"k2", "k3"}) {
+		t.Errorf("api_keys = %v, %v", cfg.RateLimit.APIKeys, err)
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"first/auth"
+	"first/openapi"
+)
+
+// Auth 登录接口和鉴权中间件
+//
+//	POST /auth/login    {"username": "admin", "password": "..."}  -> 一对令牌
+//	POST /auth/refresh  {"refresh_token": "..."}                  -> 新的一对令牌，旧的刷新令牌作废
+//	POST /auth/logout   {"refresh_token": "..."}                  -> 204
+//	GET  /auth/me       当前用户，需要 Authorization: Bearer <access_token>
+type Auth struct {
+	Tokens *auth.Tokens
+	Users  auth.Users
+}
+
+func NewAuth(tokens *auth.Tokens, users auth.Users) *Auth {
+	return &Auth{Tokens: tokens, Users: users}
+}
+
+func (h *Auth) Register(r *openapi.Group) {
+	tags := []string{"auth"}
+	r.POST("/auth/login", openapi.Operation{Summary: "登录", Tags: tags, Body: loginRequest{}, Response: Data[auth.Pair]{}}, h.Login)
+	r.POST("/auth/refresh", openapi.Operation{Summary: "刷新令牌", Description: "旧的刷新令牌作废", Tags: tags, Body: refreshRequest{}, Response: Data[auth.Pair]{}}, h.Refresh)
+	r.POST("/auth/logout", openapi.Operation{Summary: "退出", Tags: tags, Body: refreshRequest{}, Status: http.StatusNoContent}, h.Logout)
+	r.GET("/auth/me", openapi.Operation{Summary: "当前用户", Tags: tags, Response: Data[Me]{}, Secured: true}, h.Authenticate, h.Me)
+}
+
+type loginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func (h *Auth) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	p, err := h.Users.Authenticate(ctx, req.Username, req.Password)
+	if err != nil {
+		Fail(c, authError(err))
+		return
+	}
+	pair, err := h.Tokens.Issue(ctx, p)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	OK(c, http.StatusOK, pair)
+}
+
+func (h *Auth) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	pair, err := h.Tokens.Refresh(c.Request.Context(), req.RefreshToken, h.Users.Lookup)
+	if err != nil {
+		Fail(c, authError(err))
+		return
+	}
+	OK(c, http.StatusOK, pair)
+}
+
+func (h *Auth) Logout(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	if err := h.Tokens.Revoke(c.Request.Context(), req.RefreshToken); err != nil {
+		Fail(c, authError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Me 当前用户，expires_at 是访问令牌过期的 Unix 时间（秒）
+type Me struct {
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	ExpiresAt int64    `json:"expires_at"`
+}
+
+func (h *Auth) Me(c *gin.Context) {
+	claims := ClaimsFrom(c)
+	OK(c, http.StatusOK, Me{Username: claims.Subject, Roles: claims.Roles, ExpiresAt: claims.ExpiresAt.Unix()})
+}
+
+const claimsKey = "auth.claims"
+
+// Authenticate 中间件：校验 Authorization: Bearer 里的访问令牌，声明放进 gin.Context
+func (h *Auth) Authenticate(c *gin.Context) {
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="first"`)
+		Fail(c, Unauthorized("missing bearer token"))
+		return
+	}
+	claims, err := h.Tokens.Verify(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="first", error="invalid_token"`)
+		Fail(c, authError(err))
+		return
+	}
+	c.Set(claimsKey, claims)
+	c.Next()
+}
+
+// ClaimsFrom 取出 Authenticate 放进去的声明，没有经过 Authenticate 时返回 nil
+func ClaimsFrom(c *gin.Context) *auth.Claims {
+	claims, _ := c.Get(claimsKey)
+	v, _ := claims.(*auth.Claims)
+	return v
+}
+
+// RequireRole 中间件：拥有任意一个角色才能访问，要放在 Authenticate 之后
+//
+//	admin := v1.Group("", a.Authenticate, handler.RequireRole("admin"))
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ClaimsFrom(c)
+		if claims == nil {
+			Fail(c, Unauthorized("missing bearer token"))
+			return
+		}
+		if !claims.HasRole(roles...) {
+			Fail(c, Forbidden("requires role %s", strings.Join(roles, " or ")))
+			return
+		}
+		c.Next()
+	}
+}
+
+// authError auth 包的错误都是 401，message 说明原因，方便客户端决定是刷新还是重新登录
+func authError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrBadCredentials):
+		return Unauthorized("invalid username or password")
+	case errors.Is(err, auth.ErrExpiredToken):
+		return Unauthorized("token expired")
+	case errors.Is(err, auth.ErrTokenReused):
+		return Unauthorized("refresh token reused, please log in again")
+	case errors.Is(err, auth.ErrRevokedToken), errors.Is(err, auth.ErrUnknownUser):
+		return Unauthorized("token revoked")
+	case errors.Is(err, auth.ErrInvalidToken):
+		return Unauthorized("invalid token")
+	}
+	return err
+}
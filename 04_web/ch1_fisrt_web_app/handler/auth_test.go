@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"first/auth"
+)
+
+func authRouter(t *testing.T) (*gin.Engine, *Auth) {
+	t.Helper()
+	tokens, err := auth.NewHS256([]byte(strings.Repeat("k", auth.MinSecretLen)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := auth.HashPassword("s3cret")
+	a := NewAuth(tokens, auth.NewStaticUsers([]auth.StaticUser{
+		{Name: "admin", PasswordHash: hash, Roles: []string{"admin"}},
+		{Name: "alice", PasswordHash: hash},
+	}))
+	r := gin.New()
+	a.Register(documented(r.Group("")))
+	api := r.Group("/api", a.Authenticate)
+	api.GET("/anyone", func(c *gin.Context) { OK(c, http.StatusOK, ClaimsFrom(c).Subject) })
+	api.GET("/admin", RequireRole("admin"), func(c *gin.Context) { OK(c, http.StatusOK, "ok") })
+	return r, a
+}
+
+// bearer 带令牌的 GET
+func bearer(t *testing.T, r http.Handler, path, token string) (int, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	r.ServeHTTP(w, req)
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v, body %q", path, err, w.Body)
+	}
+	return w.Code, body
+}
+
+func login(t *testing.T, r http.Handler, name string) auth.Pair {
+	t.Helper()
+	code, _, body := send(t, r, "POST", "/auth/login", `{"username": "`+name+`", "password": "s3cret"}`)
+	data, _ := body["data"].(map[string]any)
+	if code != http.StatusOK || data["token_type"] != "Bearer" {
+		t.Fatalf("login %s = %d %v", name, code, body)
+	}
+	return auth.Pair{AccessToken: data["access_token"].(string), RefreshToken: data["refresh_token"].(string)}
+}
+
+func TestLogin(t *testing.T) {
+	r, _ := authRouter(t)
+	for _, body := range []string{`{"username": "admin", "password": "wrong"}`, `{"username": "nobody", "password": "s3cret"}`} {
+		code, _, resp := send(t, r, "POST", "/auth/login", body)
+		if e := errorOf(resp); code != http.StatusUnauthorized || e["code"] != CodeUnauthenticated || e["message"] != "invalid username or password" {
+			t.Errorf("login %s = %d %v", body, code, resp)
+		}
+	}
+	if code, _, _ := send(t, r, "POST", "/auth/login", `{"username": "admin"}`); code != http.StatusBadRequest {
+		t.Errorf("login without password = %d", code)
+	}
+
+	pair := login(t, r, "admin")
+	code, body := bearer(t, r, "/auth/me", pair.AccessToken)
+	if me, _ := body["data"].(map[string]any); code != http.StatusOK || me["username"] != "admin" {
+		t.Errorf("/auth/me = %d %v", code, body)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	r, a := authRouter(t)
+	admin, alice := login(t, r, "admin"), login(t, r, "alice")
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+		message     string
+	}{
+		{"/api/anyone", "", http.StatusUnauthorized, "missing bearer token"},
+		{"/api/anyone", "not-a-jwt", http.StatusUnauthorized, "invalid token"},
+		{"/api/anyone", alice.RefreshToken, http.StatusUnauthorized, "invalid token"},
+		{"/api/anyone", alice.AccessToken[:len(alice.AccessToken)-4] + "AAAA", http.StatusUnauthorized, "invalid token"},
+		{"/api/anyone", alice.AccessToken, http.StatusOK, ""},
+		{"/api/admin", alice.AccessToken, http.StatusForbidden, "requires role admin"},
+		{"/api/admin", admin.AccessToken, http.StatusOK, ""},
+	} {
+		code, body := bearer(t, r, tc.path, tc.token)
+		if code != tc.want || (tc.message != "" && errorOf(body)["message"] != tc.message) {
+			t.Errorf("GET %s with %.10q = %d %v, want %d %s", tc.path, tc.token, code, body, tc.want, tc.message)
+		}
+	}
+
+	// 已经过期的访问令牌
+	a.Tokens.AccessTTL = -time.Second
+	expired, _ := a.Tokens.Issue(context.Background(), auth.Principal{Subject: "alice"})
+	if code, body := bearer(t, r, "/api/anyone", expired.AccessToken); code != http.StatusUnauthorized || errorOf(body)["message"] != "token expired" {
+		t.Errorf("expired token = %d %v", code, body)
+	}
+}
+
+func TestRefreshLogout(t *testing.T) {
+	r, _ := authRouter(t)
+	first := login(t, r, "alice")
+
+	code, _, body := send(t, r, "POST", "/auth/refresh", `{"refresh_token": "`+first.RefreshToken+`"}`)
+	data, _ := body["data"].(map[string]any)
+	if code != http.StatusOK || data["refresh_token"] == first.RefreshToken {
+		t.Fatalf("refresh = %d %v", code, body)
+	}
+	second := data["refresh_token"].(string)
+
+	code, _, body = send(t, r, "POST", "/auth/refresh", `{"refresh_token": "`+first.RefreshToken+`"}`)
+	if code != http.StatusUnauthorized || errorOf(body)["message"] != "refresh token reused, please log in again" {
+		t.Errorf("reuse = %d %v", code, body)
+	}
+	if code, _, body := send(t, r, "POST", "/auth/refresh", `{"refresh_token": "`+second+`"}`); code != http.StatusUnauthorized || errorOf(body)["message"] != "token revoked" {
+		t.Errorf("refresh after reuse = %d %v", code, body)
+	}
+
+	again := login(t, r, "alice")
+	if code, _, _ := send(t, r, "POST", "/auth/logout", `{"refresh_token": "`+again.RefreshToken+`"}`); code != http.StatusNoContent {
+		t.Errorf("logout = %d", code)
+	}
+	if code, _, _ := send(t, r, "POST", "/auth/refresh", `{"refresh_token": "`+again.RefreshToken+`"}`); code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout = %d", code)
+	}
+}
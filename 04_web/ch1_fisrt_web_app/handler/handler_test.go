@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"first/openapi"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+// documented 和 server 里一样：请求先按文档校验，失败时返回统一的错误格式
+func documented(g *gin.RouterGroup) *openapi.Group {
+	spec := openapi.New(openapi.Info{Title: "test", Version: "test"})
+	spec.Error = ErrorResponse{}
+	spec.OnError(Fail)
+	return spec.Group(g)
+}
+
+// do 对 engine 发一个请求，返回状态码和解析后的 JSON
+func do(t *testing.T, r http.Handler, method, path string) (int, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: %v, body %q", method, path, err, w.Body)
+	}
+	return w.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	h := NewHealth()
+	var dbErr error
+	h.AddCheck("db", func(ctx context.Context) error { return dbErr })
+	h.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.Timeout = 10 * time.Millisecond
+	r := gin.New()
+	h.Register(r)
+
+	if code, _ := do(t, r, "GET", "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d", code)
+	}
+	// 还没开始监听
+	if code, body := do(t, r, "GET", "/readyz"); code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("/readyz before ready = %d %v", code, body)
+	}
+
+	h.SetReady(true)
+	code, body := do(t, r, "GET", "/readyz")
+	checks, _ := body["checks"].(map[string]any)
+	if code != http.StatusServiceUnavailable || checks["db"] != "ok" || checks["slow"] != "context deadline exceeded" {
+		t.Errorf("/readyz with slow check = %d %v", code, body)
+	}
+
+	h.checks = h.checks[:1]
+	if code, body := do(t, r, "GET", "/readyz"); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("/readyz = %d %v", code, body)
+	}
+	dbErr = errors.New("connection refused")
+	if code, body := do(t, r, "GET", "/readyz"); code != http.StatusServiceUnavailable || body["checks"].(map[string]any)["db"] != "connection refused" {
+		t.Errorf("/readyz with db down = %d %v", code, body)
+	}
+	// 存活不受依赖影响
+	if code, _ := do(t, r, "GET", "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz with db down = %d", code)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &System{Version: "1.2.3", Started: started, Now: func() time.Time { return started.Add(90 * time.Minute) }}
+	r := gin.New()
+	r.GET("/", h.Home)
+	h.Register(documented(r.Group("/api/v1")))
+
+	if code, body := do(t, r, "GET", "/"); code != http.StatusOK || body["message"] != "home" {
+		t.Errorf("/ = %d %v", code, body)
+	}
+	if code, body := do(t, r, "GET", "/api/v1/ping"); code != http.StatusOK || body["message"] != "pong" {
+		t.Errorf("/api/v1/ping = %d %v", code, body)
+	}
+	if code, body := do(t, r, "GET", "/api/v1/version"); code != http.StatusOK || body["version"] != "1.2.3" || body["uptime"] != "1h30m0s" {
+		t.Errorf("/api/v1/version = %d %v", code, body)
+	}
+}
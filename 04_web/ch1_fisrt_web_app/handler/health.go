@@ -0,0 +1,75 @@
+// Package handler HTTP 处理器
+//
+// 每组接口是一个结构体，依赖通过字段或构造函数注入，Register 把路由挂到传入的分组上，
+// 业务接口的分组是 openapi.Group，注册路由的同时写进文档：
+//
+//	v1 := spec.Group(r.Group("/api/v1"))
+//	(&handler.System{Version: "1.0.0"}).Register(v1)
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Health 存活（/healthz）和就绪（/readyz）探针
+//
+// 存活只说明进程还在响应；就绪表示可以接流量：服务器启动完成、没有在关闭，并且所有检查都通过
+type Health struct {
+	ready   atomic.Bool
+	checks  []check
+	Timeout time.Duration // 单个检查的超时，默认 2s
+}
+
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewHealth 创建后处于未就绪状态，由 server 在开始监听后设置为就绪
+func NewHealth() *Health {
+	return &Health{Timeout: 2 * time.Second}
+}
+
+// AddCheck 添加就绪检查，例如 ping 数据库；只在启动时调用
+func (h *Health) AddCheck(name string, fn func(ctx context.Context) error) {
+	h.checks = append(h.checks, check{name, fn})
+}
+
+func (h *Health) SetReady(ready bool) { h.ready.Store(ready) }
+
+func (h *Health) Register(r gin.IRoutes) {
+	r.GET("/healthz", h.Live)
+	r.GET("/readyz", h.Ready)
+}
+
+func (h *Health) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Health) Ready(c *gin.Context) {
+	if !h.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable"})
+		return
+	}
+	status, results := http.StatusOK, make(map[string]string, len(h.checks))
+	for _, ck := range h.checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), h.Timeout)
+		err := ck.fn(ctx)
+		cancel()
+		if err != nil {
+			status, results[ck.name] = http.StatusServiceUnavailable, err.Error()
+			continue
+		}
+		results[ck.name] = "ok"
+	}
+	body := gin.H{"status": "ok", "checks": results}
+	if status != http.StatusOK {
+		body["status"] = "unavailable"
+	}
+	c.JSON(status, body)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"first/ratelimit"
+)
+
+// KeyFunc 决定按什么限流，返回的 key 相同的请求共享一个额度
+type KeyFunc func(c *gin.Context) string
+
+// ByIP 按客户端 IP；在反向代理后面时要配置 gin 的 TrustedProxies，否则所有请求都是代理的 IP
+func ByIP(c *gin.Context) string { return "ip:" + c.ClientIP() }
+
+// ByRoute 按路由，所有客户端共享，用来保护下游
+func ByRoute(c *gin.Context) string { return "route:" + c.Request.Method + " " + c.FullPath() }
+
+// ByAPIKey 按请求头里的 API key，没有带或者 known 不认可时按 IP
+// 请求头是客户端随便填的，不校验的话每次换一个 key 就能拿到新的额度
+func ByAPIKey(header string, known func(key string) bool) KeyFunc {
+	return func(c *gin.Context) string {
+		if k := c.GetHeader(header); k != "" && known(k) {
+			return "key:" + k
+		}
+		return ByIP(c)
+	}
+}
+
+// ByUser 按登录用户，要放在 Authenticate 之后；没有登录时按 IP
+func ByUser(c *gin.Context) string {
+	if claims := ClaimsFrom(c); claims != nil {
+		return "user:" + claims.Subject
+	}
+	return ByIP(c)
+}
+
+// RateLimiter 限流中间件，计数放在 Store 里
+type RateLimiter struct {
+	Store ratelimit.Store
+	now   func() time.Time
+}
+
+func NewRateLimiter(store ratelimit.Store) *RateLimiter {
+	return &RateLimiter{Store: store, now: time.Now}
+}
+
+// Limit 返回一个限流中间件，name 区分不同的规则，同一个客户端在不同规则下的额度互不影响
+//
+//	api := v1.Group("", limiter.Limit("api", ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Limit: 20, Window: time.Second}, handler.ByIP))
+//
+// 响应头：X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset（秒），超过限制时 429 并带 Retry-After（秒）
+// Store 出错时（例如 Redis 不可用）放行并记录日志，限流不应该让整个服务不可用
+func (l *RateLimiter) Limit(name string, limit ratelimit.Limit, key KeyFunc) gin.HandlerFunc {
+	if err := limit.Validate(); err != nil {
+		panic(err)
+	}
+	return func(c *gin.Context) {
+		r, err := l.Store.Allow(c.Request.Context(), name+":"+key(c), limit, l.now())
+		if err != nil {
+			log.Printf("ratelimit: %s: %v", name, err)
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(r.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(r.Remaining))
+		h.Set("X-RateLimit-Reset", seconds(r.Reset))
+		if !r.Allowed {
+			h.Set("Retry-After", seconds(r.RetryAfter))
+			Fail(c, TooManyRequests("rate limit exceeded, retry in %ss", seconds(r.RetryAfter)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds 向上取整的秒数，至少 1 秒：Retry-After 只能是整数秒，向下取整会让客户端过早重试
+func seconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
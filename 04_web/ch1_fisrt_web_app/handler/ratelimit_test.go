@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"first/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(ratelimit.NewMemory())
+	l.now = func() time.Time { return now }
+	limit := ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Limit: 2, Window: time.Minute}
+
+	r := gin.New()
+	r.GET("/ip", l.Limit("ip", limit, ByIP), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/key", l.Limit("key", limit, ByAPIKey("X-API-Key", func(k string) bool { return k == "k1" })), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/down", NewRateLimiter(failingStore{}).Limit("down", limit, ByIP), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(path, ip, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/ip", "10.0.0.1", "")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "1" || w.Header().Get("X-RateLimit-Reset") != "120" {
+		t.Errorf("first = %d %v", w.Code, w.Header())
+	}
+	get("/ip", "10.0.0.1", "")
+	w = get("/ip", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "90" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("third = %d %v", w.Code, w.Header())
+	}
+	if w.Body.String() != `{"error":{"code":"resource_exhausted","message":"rate limit exceeded, retry in 90s"}}` {
+		t.Errorf("body = %s", w.Body)
+	}
+	// 别的 IP、别的规则有自己的额度
+	if w := get("/ip", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Errorf("other ip = %d", w.Code)
+	}
+	if w := get("/key", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Errorf("other rule = %d", w.Code)
+	}
+
+	// 同一个 API key 换 IP 也共享额度
+	get("/key", "10.0.0.3", "k1")
+	get("/key", "10.0.0.4", "k1")
+	if w := get("/key", "10.0.0.5", "k1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("api key from another ip = %d", w.Code)
+	}
+	// 不认识的 key 按 IP，换 key 拿不到新的额度
+	get("/key", "10.0.0.6", "x1")
+	get("/key", "10.0.0.6", "x2")
+	if w := get("/key", "10.0.0.6", "x3"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("unknown api keys from one ip = %d", w.Code)
+	}
+
+	// 过了 Retry-After 可以继续
+	now = now.Add(90 * time.Second)
+	if w := get("/ip", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Errorf("after retry-after = %d", w.Code)
+	}
+
+	// 存储不可用时放行
+	if w := get("/down", "10.0.0.1", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("store down = %d %v", w.Code, w.Header())
+	}
+}
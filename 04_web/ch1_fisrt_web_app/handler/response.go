@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm/schema"
+
+	"first/openapi"
+
+	"mysql-demo/repository"
+	"mysql-demo/validate"
+)
+
+// 业务接口的响应格式：
+//
+//	成功  {"data": ..., "meta": {...}}       meta 只有列表接口有
+//	失败  {"error": {"code": "not_found", "message": "user 7 not found", "fields": {"age": "must be at least 18"}}}
+//
+// code 是给程序判断的，message 是给人看的；fields 只在参数校验失败时出现，key 是 JSON 字段名
+
+// 错误码
+const (
+	CodeInvalidArgument   = "invalid_argument"   // 400，请求体太大时 413
+	CodeUnauthenticated   = "unauthenticated"    // 401
+	CodePermissionDenied  = "permission_denied"  // 403
+	CodeNotFound          = "not_found"          // 404
+	CodeResourceExhausted = "resource_exhausted" // 429
+	CodeInternal          = "internal"           // 500
+)
+
+// Error 错误响应的 error 部分，也可以直接作为 error 传给 Fail
+type Error struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// NotFound 404 错误
+func NotFound(format string, args ...any) *Error {
+	return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest 400 错误
+func BadRequest(format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidArgument, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unauthorized 401 错误：没有登录或令牌无效
+func Unauthorized(format string, args ...any) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthenticated, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden 403 错误：已登录但没有权限
+func Forbidden(format string, args ...any) *Error {
+	return &Error{Status: http.StatusForbidden, Code: CodePermissionDenied, Message: fmt.Sprintf(format, args...)}
+}
+
+// TooManyRequests 429 错误：超过限流
+func TooManyRequests(format string, args ...any) *Error {
+	return &Error{Status: http.StatusTooManyRequests, Code: CodeResourceExhausted, Message: fmt.Sprintf(format, args...)}
+}
+
+// Meta 列表接口的分页信息
+type Meta struct {
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+	Total int64 `json:"total"`
+	Pages int   `json:"pages"`
+}
+
+// Data 成功响应的结构，注册路由时作为 openapi.Operation 的 Response，例如 Data[User]{}
+type Data[T any] struct {
+	Data T `json:"data"`
+}
+
+// Page 列表响应的结构
+type Page[T any] struct {
+	Data []T  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// ErrorResponse 错误响应的结构
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// OK 成功响应
+func OK[T any](c *gin.Context, status int, data T) {
+	c.JSON(status, Data[T]{data})
+}
+
+// List 列表响应
+func List[T any](c *gin.Context, data []T, meta Meta) {
+	c.JSON(http.StatusOK, Page[T]{data, meta})
+}
+
+// Fail 把 err 转换成错误响应：参数绑定和校验失败是 400，记录不存在是 404，
+// 其他错误是 500，日志里记录原始错误，响应里不暴露细节
+func Fail(c *gin.Context, err error) {
+	e := toError(err)
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("handler: %s %s: %v", c.Request.Method, c.FullPath(), err)
+	}
+	c.AbortWithStatusJSON(e.Status, ErrorResponse{e})
+}
+
+func toError(err error) *Error {
+	var (
+		e        *Error
+		verrs    validator.ValidationErrors
+		syntax   *json.SyntaxError
+		typeErr  *json.UnmarshalTypeError
+		modelErr validate.Errors
+		numErr   *strconv.NumError
+		specErr  *openapi.ValidationError
+		tooLarge *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.As(err, &verrs):
+		fields := make(map[string]string, len(verrs))
+		for _, fe := range verrs {
+			fields[fe.Field()] = message(fe)
+		}
+		return &Error{Status: http.StatusBadRequest, Code: CodeInvalidArgument, Message: "invalid request", Fields: fields}
+	case errors.As(err, &specErr):
+		// 请求不符合 OpenAPI 文档，在处理器之前由 openapi 包校验
+		return &Error{Status: http.StatusBadRequest, Code: CodeInvalidArgument, Message: "invalid request", Fields: specErr.Fields}
+	case errors.As(err, &modelErr):
+		// 请求通过了 binding，但没有通过模型上的 validate 标签
+		fields := make(map[string]string, len(modelErr))
+		for name, msg := range modelErr.Fields() {
+			fields[naming.ColumnName("", name)] = msg
+		}
+		return &Error{Status: http.StatusBadRequest, Code: CodeInvalidArgument, Message: "invalid request", Fields: fields}
+	case errors.As(err, &tooLarge):
+		return &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeInvalidArgument, Message: fmt.Sprintf("request body larger than %d bytes", tooLarge.Limit)}
+	case errors.As(err, &syntax), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest("malformed JSON body")
+	case errors.As(err, &typeErr):
+		return &Error{Status: http.StatusBadRequest, Code: CodeInvalidArgument, Message: "invalid request",
+			Fields: map[string]string{typeErr.Field: "must be " + typeErr.Type.String()}}
+	case errors.As(err, &numErr):
+		return BadRequest("invalid number %q", numErr.Num)
+	case errors.Is(err, repository.ErrNotFound):
+		return NotFound("not found")
+	}
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}
+}
+
+var naming = schema.NamingStrategy{}
+
+// message 校验失败的说明，和 validate 包的措辞一致
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters"
+		}
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters"
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "failed on " + fe.Tag()
+}
+
+// 校验错误里的字段名用 json / form / uri 标签里的名字，而不是结构体字段名
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
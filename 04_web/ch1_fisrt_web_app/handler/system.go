@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"first/openapi"
+)
+
+// System 首页、ping 和版本信息，就是原来 main.go 里的两个接口
+type System struct {
+	Version string    // 构建时用 -ldflags "-X main.version=..." 注入
+	Started time.Time // 进程启动时间
+	Now     func() time.Time
+}
+
+func (h *System) Register(r *openapi.Group) {
+	tags := []string{"system"}
+	r.GET("/ping", openapi.Operation{Summary: "ping", Tags: tags, Response: Message{}}, h.Ping)
+	r.GET("/version", openapi.Operation{Summary: "版本信息", Tags: tags, Response: VersionInfo{}}, h.Info)
+}
+
+// Message 只有一句话的响应
+type Message struct {
+	Message string `json:"message"`
+}
+
+// VersionInfo GET /version 的响应
+type VersionInfo struct {
+	Version string    `json:"version"`
+	Started time.Time `json:"started"`
+	Uptime  string    `json:"uptime"`
+}
+
+func (h *System) Home(c *gin.Context) {
+	c.JSON(http.StatusOK, Message{"home"})
+}
+
+func (h *System) Ping(c *gin.Context) {
+	c.JSON(http.StatusOK, Message{"pong"})
+}
+
+func (h *System) Info(c *gin.Context) {
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	c.JSON(http.StatusOK, VersionInfo{
+		Version: h.Version,
+		Started: h.Started,
+		Uptime:  now().Sub(h.Started).Round(time.Second).String(),
+	})
+}
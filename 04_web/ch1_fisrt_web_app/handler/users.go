@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"first/openapi"
+
+	"mysql-demo/model"
+	"mysql-demo/query"
+	"mysql-demo/repository"
+)
+
+// Users 用户接口，数据在 01_mysql 的 user3 表（model.User3）
+//
+//	GET    /users              列表：?q=张&min_age=18&max_age=30&deleted=with|only&sort=-age,name&page=1&size=20
+//	GET    /users/:id
+//	POST   /users              {"name": "张三", "age": 20, "birthday": "2000-01-02T00:00:00Z"}
+//	PATCH  /users/:id          只更新请求里出现的字段
+//	DELETE /users/:id          软删除
+//	POST   /users/:id/restore  恢复软删除
+type Users struct {
+	repo *repository.Repository[model.User3]
+	q    query.User3Query
+}
+
+func NewUsers(db *gorm.DB) *Users {
+	return &Users{repo: repository.New[model.User3](db), q: query.Use(db).User3}
+}
+
+// Register 注册路由并写进文档，write 是写接口额外的中间件，例如 RequireRole("admin")
+func (h *Users) Register(r *openapi.Group, write ...gin.HandlerFunc) {
+	w := func(h gin.HandlerFunc) []gin.HandlerFunc { return append(slices.Clip(write), h) }
+	tags := []string{"users"}
+	r.GET("/users", openapi.Operation{Summary: "用户列表", Tags: tags, Params: listUsersRequest{}, Response: Page[User]{}}, h.List)
+	r.GET("/users/:id", openapi.Operation{Summary: "查询用户", Tags: tags, Params: idRequest{}, Response: Data[User]{}}, h.Get)
+	r.POST("/users", openapi.Operation{Summary: "创建用户", Tags: tags, Body: createUserRequest{}, Response: Data[User]{}, Status: http.StatusCreated}, w(h.Create)...)
+	r.PATCH("/users/:id", openapi.Operation{Summary: "修改用户", Description: "只更新请求里出现的字段，birthday 传 null 表示清空", Tags: tags, Params: idRequest{}, Body: patchUserRequest{}, Response: Data[User]{}}, w(h.Patch)...)
+	r.DELETE("/users/:id", openapi.Operation{Summary: "删除用户", Description: "软删除", Tags: tags, Params: idRequest{}, Status: http.StatusNoContent}, w(h.Delete)...)
+	r.POST("/users/:id/restore", openapi.Operation{Summary: "恢复软删除的用户", Tags: tags, Params: idRequest{}, Response: Data[User]{}}, w(h.Restore)...)
+}
+
+// User 接口返回的用户，不直接返回 model.User3：字段名用蛇形，也不带关联字段
+type User struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Age       int        `json:"age"`
+	Birthday  *time.Time `json:"birthday"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func toUser(u *model.User3) User {
+	out := User{
+		ID:        u.ID,
+		Name:      u.Name,
+		Age:       u.Age,
+		Birthday:  u.Birthday,
+		Version:   int64(u.Version),
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+	if u.DeletedAt.Valid {
+		out.DeletedAt = &u.DeletedAt.Time
+	}
+	return out
+}
+
+type listUsersRequest struct {
+	Q       string `form:"q" binding:"max=50"` // 名字包含
+	MinAge  *int   `form:"min_age" binding:"omitempty,gte=0"`
+	MaxAge  *int   `form:"max_age" binding:"omitempty,gte=0"`
+	Deleted string `form:"deleted" binding:"omitempty,oneof=with only"`
+	Sort    string `form:"sort"` // 逗号分隔，- 开头表示降序
+	Page    int    `form:"page" binding:"omitempty,gte=1"`
+	Size    int    `form:"size" binding:"omitempty,gte=1,lte=100"`
+}
+
+type idRequest struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type createUserRequest struct {
+	Name     string     `json:"name" binding:"required,max=50"`
+	Age      int        `json:"age" binding:"required,gte=18,lte=150"`
+	Birthday *time.Time `json:"birthday"`
+}
+
+// patchUserRequest 指针为 nil 表示请求里没有这个字段或者是 null；
+// 可以为空的字段（birthday）传 null 表示清空，要用 nullFields 区分
+type patchUserRequest struct {
+	Name     *string    `json:"name" binding:"omitempty,min=1,max=50"`
+	Age      *int       `json:"age" binding:"omitempty,gte=18,lte=150"`
+	Birthday *time.Time `json:"birthday"`
+}
+
+// sortable 可以排序的字段，没有列出来的字段排序会返回 400
+func (h *Users) sortable() map[string]query.Expr {
+	return map[string]query.Expr{
+		"id":         h.q.ID,
+		"name":       h.q.Name,
+		"age":        h.q.Age,
+		"birthday":   h.q.Birthday,
+		"created_at": h.q.CreatedAt,
+		"updated_at": h.q.UpdatedAt,
+	}
+}
+
+func (h *Users) List(c *gin.Context) {
+	var req listUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	order, err := h.order(req.Sort)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	page, size := max(req.Page, 1), req.Size
+	if size == 0 {
+		size = repository.DefaultPageSize
+	}
+
+	u := h.q
+	var conds []query.Cond
+	if req.Q != "" {
+		conds = append(conds, u.Name.Contains(req.Q))
+	}
+	if req.MinAge != nil {
+		conds = append(conds, u.Age.Gte(*req.MinAge))
+	}
+	if req.MaxAge != nil {
+		conds = append(conds, u.Age.Lte(*req.MaxAge))
+	}
+	t := u.Table
+	switch req.Deleted {
+	case "with":
+		t = t.Unscoped()
+	case "only":
+		t = t.Unscoped()
+		conds = append(conds, u.DeletedAt.NotNull())
+	}
+	if len(conds) > 0 {
+		t = t.Where(conds...)
+	}
+
+	ctx := c.Request.Context()
+	total, err := t.Count(ctx)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	rows, err := t.Order(order...).Limit(size).Offset((page - 1) * size).Find(ctx)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	items := make([]User, len(rows))
+	for i := range rows {
+		items[i] = toUser(&rows[i])
+	}
+	List(c, items, Meta{Page: page, Size: size, Total: total, Pages: int((total + int64(size) - 1) / int64(size))})
+}
+
+// order 解析 sort=-age,name，最后补上 id 保证分页稳定
+func (h *Users) order(sort string) ([]clause.OrderByColumn, error) {
+	fields := h.sortable()
+	var cols []clause.OrderByColumn
+	byID := false
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		f, ok := fields[name]
+		if !ok {
+			return nil, BadRequest("cannot sort by %q", name)
+		}
+		byID = byID || name == "id"
+		cols = append(cols, clause.OrderByColumn{Column: f.Column(), Desc: desc})
+	}
+	if !byID {
+		cols = append(cols, h.q.ID.Asc())
+	}
+	return cols, nil
+}
+
+func (h *Users) Get(c *gin.Context) {
+	var id idRequest
+	if err := c.ShouldBindUri(&id); err != nil {
+		Fail(c, err)
+		return
+	}
+	u, err := h.repo.Get(c.Request.Context(), id.ID)
+	if err != nil {
+		Fail(c, notFound(err, id.ID))
+		return
+	}
+	OK(c, http.StatusOK, toUser(u))
+}
+
+func (h *Users) Create(c *gin.Context) {
+	var req createUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	u := &model.User3{Name: req.Name, Age: req.Age, Birthday: req.Birthday}
+	if err := h.repo.Create(c.Request.Context(), u); err != nil {
+		Fail(c, err)
+		return
+	}
+	c.Header("Location", c.FullPath()+"/"+strconv.FormatUint(uint64(u.ID), 10))
+	OK(c, http.StatusCreated, toUser(u))
+}
+
+func (h *Users) Patch(c *gin.Context) {
+	var id idRequest
+	var req patchUserRequest
+	if err := c.ShouldBindUri(&id); err != nil {
+		Fail(c, err)
+		return
+	}
+	// 请求体留在 context 里，nullFields 还要再解析一次
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		Fail(c, err)
+		return
+	}
+	values := map[string]any{}
+	if req.Name != nil {
+		values["Name"] = *req.Name
+	}
+	if req.Age != nil {
+		values["Age"] = *req.Age
+	}
+	if req.Birthday != nil {
+		values["Birthday"] = *req.Birthday
+	} else if nullFields(c)["birthday"] {
+		values["Birthday"] = nil
+	}
+	if len(values) == 0 {
+		Fail(c, BadRequest("nothing to update"))
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.repo.UpdateMap(ctx, id.ID, values); err != nil {
+		Fail(c, notFound(err, id.ID))
+		return
+	}
+	u, err := h.repo.Get(ctx, id.ID)
+	if err != nil {
+		Fail(c, notFound(err, id.ID))
+		return
+	}
+	OK(c, http.StatusOK, toUser(u))
+}
+
+// nullFields ShouldBindBodyWithJSON 之后，请求体里值为 null 的字段
+func nullFields(c *gin.Context) map[string]bool {
+	body, _ := c.Get(gin.BodyBytesKey)
+	b, _ := body.([]byte)
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(b, &raw) != nil {
+		return nil
+	}
+	nulls := map[string]bool{}
+	for k, v := range raw {
+		if string(v) == "null" {
+			nulls[k] = true
+		}
+	}
+	return nulls
+}
+
+func (h *Users) Delete(c *gin.Context) {
+	var id idRequest
+	if err := c.ShouldBindUri(&id); err != nil {
+		Fail(c, err)
+		return
+	}
+	if err := h.repo.Delete(c.Request.Context(), id.ID); err != nil {
+		Fail(c, notFound(err, id.ID))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Users) Restore(c *gin.Context) {
+	var id idRequest
+	if err := c.ShouldBindUri(&id); err != nil {
+		Fail(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.repo.Restore(ctx, id.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			err = NotFound("deleted user %d not found", id.ID)
+		}
+		Fail(c, err)
+		return
+	}
+	u, err := h.repo.Get(ctx, id.ID)
+	if err != nil {
+		Fail(c, err)
+		return
+	}
+	OK(c, http.StatusOK, toUser(u))
+}
+
+// notFound 把 repository.ErrNotFound 换成带 id 的 404
+func notFound(err error, id uint) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return NotFound("user %d not found", id)
+	}
+	return err
+}
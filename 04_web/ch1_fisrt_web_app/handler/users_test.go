@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"mysql-demo/fixtures/fixturestest"
+)
+
+// usersRouter 内存 SQLite + edge_ages 夹具：边界18、边界150，以及软删除的边界30
+func usersRouter(t *testing.T) *gin.Engine {
+	db, _ := fixturestest.Open(t, "edge_ages")
+	r := gin.New()
+	NewUsers(db).Register(documented(r.Group("/api/v1")))
+	return r
+}
+
+// send 带 JSON 请求体的 do，另外返回响应头
+func send(t *testing.T, r http.Handler, method, path, body string) (int, http.Header, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	var out map[string]any
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s %s: %v, body %q", method, path, err, w.Body)
+		}
+	}
+	return w.Code, w.Header(), out
+}
+
+// names 列表响应里的名字，按返回顺序
+func names(body map[string]any) []string {
+	var out []string
+	for _, u := range body["data"].([]any) {
+		out = append(out, u.(map[string]any)["name"].(string))
+	}
+	return out
+}
+
+func errorOf(body map[string]any) map[string]any {
+	e, _ := body["error"].(map[string]any)
+	return e
+}
+
+func TestListUsers(t *testing.T) {
+	r := usersRouter(t)
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"", "边界18,边界150"},
+		{"?sort=-age", "边界150,边界18"},
+		{"?min_age=100", "边界150"},
+		{"?max_age=100&deleted=with", "边界18,边界30"},
+		{"?deleted=only", "边界30"},
+		{"?q=150", "边界150"},
+		{"?deleted=with&sort=-age&size=2&page=2", "边界18"},
+		{"?page=9", ""},
+	} {
+		code, body := do(t, r, "GET", "/api/v1/users"+tc.query)
+		if got := strings.Join(names(body), ","); code != http.StatusOK || got != tc.want {
+			t.Errorf("GET %s = %d %s, want %s", tc.query, code, got, tc.want)
+		}
+	}
+
+	_, body := do(t, r, "GET", "/api/v1/users?deleted=with&size=2")
+	meta := body["meta"].(map[string]any)
+	if meta["total"] != 3.0 || meta["pages"] != 2.0 || meta["page"] != 1.0 || meta["size"] != 2.0 {
+		t.Errorf("meta = %v", meta)
+	}
+
+	for query, field := range map[string]string{
+		"?size=101":      "size",
+		"?deleted=all":   "deleted",
+		"?min_age=-1":    "min_age",
+		"?sort=password": "",
+		"?min_age=abc":   "",
+	} {
+		code, body := do(t, r, "GET", "/api/v1/users"+query)
+		e := errorOf(body)
+		if code != http.StatusBadRequest || e["code"] != CodeInvalidArgument {
+			t.Errorf("GET %s = %d %v", query, code, body)
+			continue
+		}
+		if fields, _ := e["fields"].(map[string]any); field != "" && fields[field] == nil {
+			t.Errorf("GET %s fields = %v, want %s", query, fields, field)
+		}
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	r := usersRouter(t)
+	code, body := do(t, r, "GET", "/api/v1/users/1")
+	u := body["data"].(map[string]any)
+	if code != http.StatusOK || u["name"] != "边界18" || u["age"] != 18.0 || u["created_at"] == nil {
+		t.Errorf("GET /users/1 = %d %v", code, body)
+	}
+	if _, ok := u["deleted_at"]; ok {
+		t.Errorf("deleted_at present for live user: %v", u)
+	}
+
+	// 软删除的用户和不存在的用户一样是 404
+	for _, path := range []string{"/api/v1/users/3", "/api/v1/users/99"} {
+		code, body := do(t, r, "GET", path)
+		if e := errorOf(body); code != http.StatusNotFound || e["code"] != CodeNotFound {
+			t.Errorf("GET %s = %d %v", path, code, body)
+		}
+	}
+	if code, body := do(t, r, "GET", "/api/v1/users/abc"); code != http.StatusBadRequest {
+		t.Errorf("GET /users/abc = %d %v", code, body)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	r := usersRouter(t)
+	code, header, body := send(t, r, "POST", "/api/v1/users", `{"name": "张三", "age": 20, "birthday": "2004-05-06T00:00:00Z"}`)
+	u, _ := body["data"].(map[string]any)
+	if code != http.StatusCreated || u["id"] != 4.0 || u["birthday"] != "2004-05-06T00:00:00Z" {
+		t.Fatalf("POST /users = %d %v", code, body)
+	}
+	if loc := header.Get("Location"); loc != "/api/v1/users/4" {
+		t.Errorf("Location = %q", loc)
+	}
+	if code, body := do(t, r, "GET", "/api/v1/users/4"); code != http.StatusOK || body["data"].(map[string]any)["name"] != "张三" {
+		t.Errorf("GET created = %d %v", code, body)
+	}
+
+	for _, tc := range []struct {
+		body   string
+		fields map[string]string
+	}{
+		{`{"age": 17}`, map[string]string{"name": "is required", "age": "must be at least 18"}},
+		{`{"name": "` + strings.Repeat("长", 51) + `", "age": 20}`, map[string]string{"name": "must be at most 50 characters"}},
+		{`{"name": "张三", "age": "20"}`, map[string]string{"age": "must be integer"}},
+		{`{"name": "张三", "age": 20, "nickname": "三"}`, map[string]string{"nickname": "unknown field"}},
+		{`{"name": "张三", "age": 20`, nil},
+		{``, nil},
+	} {
+		code, _, body := send(t, r, "POST", "/api/v1/users", tc.body)
+		e := errorOf(body)
+		if code != http.StatusBadRequest || e["code"] != CodeInvalidArgument {
+			t.Errorf("POST %s = %d %v", tc.body, code, body)
+			continue
+		}
+		fields, _ := e["fields"].(map[string]any)
+		if len(fields) != len(tc.fields) {
+			t.Errorf("POST %s fields = %v, want %v", tc.body, fields, tc.fields)
+		}
+		for k, v := range tc.fields {
+			if fields[k] != v {
+				t.Errorf("POST %s fields[%s] = %v, want %q", tc.body, k, fields[k], v)
+			}
+		}
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	r := usersRouter(t)
+	code, _, body := send(t, r, "PATCH", "/api/v1/users/1", `{"age": 19}`)
+	u, _ := body["data"].(map[string]any)
+	if code != http.StatusOK || u["age"] != 19.0 || u["name"] != "边界18" {
+		t.Errorf("PATCH age = %d %v", code, body)
+	}
+	if u["birthday"] == nil {
+		t.Fatalf("PATCH age cleared birthday: %v", body)
+	}
+	// 没传的字段不动，传 null 才清空
+	code, _, body = send(t, r, "PATCH", "/api/v1/users/1", `{"birthday": null}`)
+	if u, _ := body["data"].(map[string]any); code != http.StatusOK || u["birthday"] != nil || u["age"] != 19.0 {
+		t.Errorf("PATCH birthday null = %d %v", code, body)
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/v1/users/1", `{}`, http.StatusBadRequest},
+		{"/api/v1/users/1", `{"age": 200}`, http.StatusBadRequest},
+		{"/api/v1/users/1", `{"name": ""}`, http.StatusBadRequest},
+		{"/api/v1/users/3", `{"age": 40}`, http.StatusNotFound}, // 软删除的不能改
+		{"/api/v1/users/99", `{"age": 40}`, http.StatusNotFound},
+	} {
+		if code, _, body := send(t, r, "PATCH", tc.path, tc.body); code != tc.want {
+			t.Errorf("PATCH %s %s = %d %v, want %d", tc.path, tc.body, code, body, tc.want)
+		}
+	}
+}
+
+func TestDeleteRestoreUser(t *testing.T) {
+	r := usersRouter(t)
+	if code, _, body := send(t, r, "DELETE", "/api/v1/users/1", ""); code != http.StatusNoContent || body != nil {
+		t.Errorf("DELETE = %d %v", code, body)
+	}
+	if code, _, _ := send(t, r, "DELETE", "/api/v1/users/1", ""); code != http.StatusNotFound {
+		t.Errorf("DELETE twice = %d", code)
+	}
+	if code, _ := do(t, r, "GET", "/api/v1/users/1"); code != http.StatusNotFound {
+		t.Errorf("GET deleted = %d", code)
+	}
+	_, body := do(t, r, "GET", "/api/v1/users?deleted=only")
+	if got := strings.Join(names(body), ","); got != "边界18,边界30" {
+		t.Errorf("deleted users = %s", got)
+	}
+
+	code, _, body := send(t, r, "POST", "/api/v1/users/1/restore", "")
+	if u, _ := body["data"].(map[string]any); code != http.StatusOK || u["name"] != "边界18" || u["deleted_at"] != nil {
+		t.Errorf("restore = %d %v", code, body)
+	}
+	// 没有被删除的用户不能恢复
+	code, _, body = send(t, r, "POST", "/api/v1/users/2/restore", "")
+	if e := errorOf(body); code != http.StatusNotFound || e["message"] != "deleted user 2 not found" {
+		t.Errorf("restore live user = %d %v", code, body)
+	}
+}
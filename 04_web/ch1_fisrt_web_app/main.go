@@ -1,25 +1,115 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"first/auth"
+	"first/config"
+	"first/handler"
+	"first/server"
+
+	"mysql-demo/database"
+	"mysql-demo/migrations"
+	"mysql-demo/validate"
 )
 
+// version 构建时注入：go build -ldflags "-X main.version=1.0.0"
+var version = "dev"
+
+// go run .                                  监听 :8080，连接本机 MySQL（01_mysql 的默认配置）
+// APP_CONFIG=config.example.yaml go run .   从配置文件读取
+// APP_ADDR=:9090 APP_MODE=release go run .  环境变量覆盖配置文件
+// DB_DRIVER=sqlite DB_DSN=app.db APP_MIGRATE=true go run .   不装 MySQL，用 SQLite 文件
+//
+// curl localhost:8080/api/v1/ping
+// curl localhost:8080/readyz
+// curl -d '{"username":"admin","password":"admin"}' localhost:8080/auth/login
+// curl -H "Authorization: Bearer $ACCESS_TOKEN" localhost:8080/api/v1/users?sort=-age
 func main() {
-	r := gin.Default()
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	gin.SetMode(cfg.Mode)
 
-	r.GET("/", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "home",
-		})
-	})
+	dbCfg, err := database.Load("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(dbCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+	if err := db.Use(validate.Plugin{}); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Migrate {
+		if err := migrations.Up(db); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	r.GET("/ping", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "pong",
-		})
+	tokens, err := newTokens(cfg.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(cfg.Auth.Users) == 0 {
+		log.Print("auth: no users configured, nobody can log in; see config.example.yaml")
+	}
+
+	limits, closeLimits, err := server.NewLimits(cfg.RateLimit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeLimits()
+
+	health := handler.NewHealth()
+	health.AddCheck("db", sqlDB.PingContext)
+	r := server.NewRouter(server.Handlers{
+		Health: health,
+		System: &handler.System{Version: version, Started: time.Now()},
+		Users:  handler.NewUsers(db),
+		Auth:   handler.NewAuth(tokens, auth.NewStaticUsers(cfg.Auth.Users)),
+		Limits: limits,
+
+		TrustedProxies: cfg.Server.TrustedProxies,
 	})
+	if err := server.Run(context.Background(), cfg.Server, r, health); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	r.Run() // listen and serve on 0.0.0.0:8080
+// newTokens 按配置创建令牌签发器；debug 模式没有配置密钥时随机生成一个
+func newTokens(cfg config.Auth) (*auth.Tokens, error) {
+	var (
+		tokens *auth.Tokens
+		err    error
+	)
+	switch cfg.Algorithm {
+	case "RS256":
+		tokens, err = auth.LoadRS256(cfg.PrivateKeyFile)
+	default:
+		secret := cfg.Secret
+		if secret == "" {
+			b := make([]byte, auth.MinSecretLen)
+			rand.Read(b)
+			secret = hex.EncodeToString(b)
+			log.Print("auth: no secret configured, using a random one; tokens will not survive a restart")
+		}
+		tokens, err = auth.NewHS256([]byte(secret))
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens.AccessTTL = time.Duration(cfg.AccessTTL)
+	tokens.RefreshTTL = time.Duration(cfg.RefreshTTL)
+	return tokens, nil
 }
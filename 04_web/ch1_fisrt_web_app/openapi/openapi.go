@@ -0,0 +1,273 @@
+// Package openapi 从注册路由时给出的请求、响应结构体生成 OpenAPI 3.1 文档，并按文档校验请求
+//
+// 文档和校验都来自处理器真正使用的结构体，改了结构体文档跟着变：
+//
+//	spec := openapi.New(openapi.Info{Title: "first", Version: "1.0.0"})
+//	api := spec.Group(r.Group("/api/v1"))
+//	api.GET("/users/:id", openapi.Operation{Summary: "查询用户", Params: idRequest{}, Response: User{}}, h.Get)
+//
+//	r.GET("/openapi.json", spec.Serve)
+//	r.GET("/docs/*any", openapi.UI("/openapi.json"))
+//
+// 结构体标签的含义：
+//
+//	uri:"id"       路径参数          form:"page"   查询参数          json:"name"   请求体、响应的字段
+//	binding:"..."  required、min、max、gte、lte、gt、lt、oneof 翻译成 JSON Schema 的约束
+//
+// 请求体不允许出现结构体里没有的字段；同一个结构体不要既当请求又当响应
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Info 文档的基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation 一个接口的说明
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Params      any // 路径参数（uri 标签）和查询参数（form 标签）所在的结构体
+	Body        any // JSON 请求体
+	Response    any // 成功时的响应体，nil 表示没有响应体
+	Status      int // 成功的状态码，默认 200
+	Secured     bool
+}
+
+// Spec 文档，注册路由时填充；启动之后只读
+type Spec struct {
+	Info  Info
+	Error any // 错误响应体，作为每个接口的 default 响应
+
+	gen     generator
+	paths   map[string]map[string]*operation // path -> method
+	ops     map[string]*compiled             // "GET /api/v1/users/:id" -> 校验用的 Schema
+	onError func(c *gin.Context, err error)
+}
+
+func New(info Info) *Spec {
+	return &Spec{
+		Info:  info,
+		gen:   generator{components: map[string]*Schema{}, types: map[string]component{}},
+		paths: map[string]map[string]*operation{},
+		ops:   map[string]*compiled{},
+	}
+}
+
+// Group 在 gin 的路由分组上注册带文档的路由
+type Group struct {
+	spec    *Spec
+	routes  *gin.RouterGroup
+	Tags    []string // 分组里接口的默认标签
+	Secured bool     // 分组里的接口都需要登录
+}
+
+func (s *Spec) Group(g *gin.RouterGroup) *Group {
+	return &Group{spec: s, routes: g}
+}
+
+// Routes gin 的路由分组，用来加不需要写进文档的路由或者中间件
+func (g *Group) Routes() *gin.RouterGroup { return g.routes }
+
+func (g *Group) GET(path string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodGet, path, op, handlers...)
+}
+
+func (g *Group) POST(path string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPost, path, op, handlers...)
+}
+
+func (g *Group) PATCH(path string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPatch, path, op, handlers...)
+}
+
+func (g *Group) DELETE(path string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodDelete, path, op, handlers...)
+}
+
+// Handle 注册路由并写进文档；最后一个处理器之前插入请求校验，
+// 所以分组上的中间件（登录、限流）先执行，通过之后才校验参数
+func (g *Group) Handle(method, path string, op Operation, handlers ...gin.HandlerFunc) {
+	if len(op.Tags) == 0 {
+		op.Tags = g.Tags
+	}
+	op.Secured = op.Secured || g.Secured
+	full := joinPath(g.routes.BasePath(), path)
+	c := g.spec.add(method, full, op)
+
+	n := len(handlers)
+	chain := append(handlers[:n-1:n-1], c.validate, handlers[n-1])
+	g.routes.Handle(method, path, chain...)
+}
+
+func joinPath(base, path string) string {
+	p := strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+var pathParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// add 生成操作的文档；gin 的 /users/:id 在文档里是 /users/{id}
+func (s *Spec) add(method, path string, op Operation) *compiled {
+	o := &operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		OperationID: operationID(method, path),
+		Responses:   map[string]*response{},
+	}
+	c := &compiled{spec: s, query: map[string]*Schema{}, path: map[string]*Schema{}}
+	if op.Params != nil {
+		c.params(s, reflect.TypeOf(op.Params), o)
+	}
+	for _, name := range pathParam.FindAllStringSubmatch(path, -1) {
+		if _, ok := c.path[name[1]]; !ok {
+			panic("openapi: " + method + " " + path + ": path parameter " + name[1] + " is not declared in Params")
+		}
+	}
+	if op.Body != nil {
+		c.body = s.gen.schema(reflect.TypeOf(op.Body), asRequest)
+		o.RequestBody = &requestBody{Required: true, Content: jsonContent(c.body)}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	r := &response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		c.response = s.gen.schema(reflect.TypeOf(op.Response), asResponse)
+		r.Content = jsonContent(c.response)
+	}
+	c.status = status
+	o.Responses[strconv.Itoa(status)] = r
+	if s.Error != nil {
+		o.Responses["default"] = &response{Description: "错误", Content: jsonContent(s.gen.schema(reflect.TypeOf(s.Error), asResponse))}
+	}
+	if op.Secured {
+		o.Security = []map[string][]string{{"bearer": {}}}
+	}
+
+	p := pathParam.ReplaceAllString(path, "{$1}")
+	if s.paths[p] == nil {
+		s.paths[p] = map[string]*operation{}
+	}
+	s.paths[p][strings.ToLower(method)] = o
+	s.ops[method+" "+path] = c
+	return c
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimLeft(part, ":*")
+		if part == "" || part == "api" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Document 生成 OpenAPI 文档
+func (s *Spec) Document() *Document {
+	d := &Document{
+		OpenAPI: "3.1.0",
+		Info:    s.Info,
+		Paths:   s.paths,
+		Components: components{
+			Schemas: s.gen.components,
+			SecuritySchemes: map[string]securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	return d
+}
+
+// Serve GET /openapi.json
+func (s *Spec) Serve(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Document())
+}
+
+// Routes 文档里的所有路由，gin 的格式，例如 "GET /api/v1/users/:id"，按字母排序
+func (s *Spec) Routes() []string {
+	var out []string
+	for k := range s.ops {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Document OpenAPI 文档，字段按规范命名
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []*parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path | query
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func jsonContent(s *Schema) map[string]*mediaType {
+	return map[string]*mediaType{"application/json": {Schema: s}}
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+type Base struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Item struct {
+	Base
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Note  *string  `json:"note,omitempty"`
+	Child *Item    `json:"child,omitempty"`
+}
+
+type itemRequest struct {
+	Name  string            `json:"name" binding:"required,max=5"`
+	Kind  string            `json:"kind" binding:"omitempty,oneof=a b"`
+	Count *int              `json:"count" binding:"omitempty,gte=1,lte=10"`
+	Score float64           `json:"score" binding:"gt=0"`
+	Attrs map[string]string `json:"attrs"`
+	When  *time.Time        `json:"when"`
+	Skip  string            `json:"-"`
+}
+
+type itemParams struct {
+	ID    uint   `uri:"id" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,gte=1,lte=100"`
+	Q     string `form:"q" binding:"required"`
+	Raw   *bool  `form:"raw"`
+}
+
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+type failure struct {
+	Message string `json:"message"`
+}
+
+func newTestSpec(t *testing.T) (*Spec, *gin.Engine) {
+	t.Helper()
+	spec := New(Info{Title: "test", Version: "1"})
+	spec.Error = failure{}
+	r := gin.New()
+	g := spec.Group(r.Group("/api"))
+	g.Tags = []string{"items"}
+	g.Secured = true
+	g.POST("/items/:id", Operation{Summary: "修改", Params: itemParams{}, Body: itemRequest{}, Response: envelope[Item]{}},
+		func(c *gin.Context) {
+			// 校验之后处理器还能读到请求体
+			var req itemRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusInternalServerError, failure{err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, envelope[Item]{Item{Name: req.Name}})
+		})
+	g.DELETE("/items/:id", Operation{Params: itemParams{}, Status: http.StatusNoContent}, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return spec, r
+}
+
+// roundTrip 文档序列化后再解析，按 JSON 的样子比较
+func roundTrip(t *testing.T, v any) map[string]any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestDocument(t *testing.T) {
+	spec, _ := newTestSpec(t)
+	doc := roundTrip(t, spec.Document())
+
+	op := doc["paths"].(map[string]any)["/api/items/{id}"].(map[string]any)["post"].(map[string]any)
+	if op["operationId"] != "postItemsId" || op["tags"].([]any)[0] != "items" || op["security"] == nil {
+		t.Errorf("operation = %v", op)
+	}
+	params := roundTrip(t, map[string]any{"p": op["parameters"]})["p"].([]any)
+	want := []string{"id path true integer", "limit query false integer", "q query true string", "raw query false boolean"}
+	for i, p := range params {
+		p := p.(map[string]any)
+		got := strings.Join([]string{p["name"].(string), p["in"].(string), boolString(p["required"]), p["schema"].(map[string]any)["type"].(string)}, " ")
+		if i >= len(want) || got != want[i] {
+			t.Errorf("parameter %d = %q", i, got)
+		}
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	req := schemas["itemRequest"].(map[string]any)
+	props := req["properties"].(map[string]any)
+	if !reflect.DeepEqual(req["required"], []any{"name"}) || req["additionalProperties"] != false {
+		t.Errorf("itemRequest = %v", req)
+	}
+	for name, want := range map[string]string{
+		"name":  `{"maxLength":5,"type":"string"}`,
+		"kind":  `{"enum":["a","b"],"type":"string"}`,
+		"count": `{"format":"int64","maximum":10,"minimum":1,"type":["integer","null"]}`,
+		"score": `{"exclusiveMinimum":0,"type":"number"}`,
+		"attrs": `{"additionalProperties":{"type":"string"},"type":["object","null"]}`,
+		"when":  `{"format":"date-time","type":["string","null"]}`,
+	} {
+		if got, _ := json.Marshal(props[name]); string(got) != want {
+			t.Errorf("itemRequest.%s = %s, want %s", name, got, want)
+		}
+	}
+	if _, ok := props["Skip"]; ok {
+		t.Error(`json:"-" field documented`)
+	}
+
+	// 嵌入的字段展开；响应里没有 omitempty 的字段必有；自引用用 $ref
+	item := schemas["Item"].(map[string]any)
+	if !reflect.DeepEqual(item["required"], []any{"id", "created_at", "name", "tags"}) {
+		t.Errorf("Item.required = %v", item["required"])
+	}
+	child, _ := json.Marshal(item["properties"].(map[string]any)["child"])
+	if string(child) != `{"anyOf":[{"$ref":"#/components/schemas/Item"},{"type":"null"}]}` {
+		t.Errorf("Item.child = %s", child)
+	}
+	// 泛型实例内联，不生成组件
+	for name := range schemas {
+		if strings.Contains(name, "[") {
+			t.Errorf("component %s", name)
+		}
+	}
+	if got := spec.Routes(); !reflect.DeepEqual(got, []string{"DELETE /api/items/:id", "POST /api/items/:id"}) {
+		t.Errorf("Routes() = %v", got)
+	}
+}
+
+func boolString(v any) string {
+	if v == true {
+		return "true"
+	}
+	return "false"
+}
+
+func TestValidate(t *testing.T) {
+	_, r := newTestSpec(t)
+	for _, tc := range []struct {
+		path, body string
+		want       int
+		fields     map[string]string
+	}{
+		{"/api/items/1?q=x", `{"name": "abc", "score": 1.5, "when": "2024-01-02T03:04:05Z", "attrs": {"k": "v"}}`, http.StatusOK, nil},
+		{"/api/items/1?q=x", `{"name": "五个字的名", "score": 1, "count": null}`, http.StatusOK, nil},
+		{"/api/items/x?limit=0&raw=maybe", `{"name": "abc", "score": 1}`, http.StatusBadRequest, map[string]string{
+			"id": "must be integer", "limit": "must be at least 1", "q": "is required", "raw": "must be boolean",
+		}},
+		{"/api/items/1?q=x", `{"name": "六个字的名字", "kind": "c", "count": 1.5, "score": 0, "extra": 1}`, http.StatusBadRequest, map[string]string{
+			"name": "must be at most 5 characters", "kind": "must be one of a, b", "count": "must be integer",
+			"score": "must be greater than 0", "extra": "unknown field",
+		}},
+		{"/api/items/1?q=x", `{"score": 1, "when": "yesterday", "attrs": {"k": 1}}`, http.StatusBadRequest, map[string]string{
+			"name": "is required", "when": "must be an RFC 3339 date-time", "attrs.k": "must be string",
+		}},
+		{"/api/items/1?q=x", `[]`, http.StatusBadRequest, map[string]string{"body": "must be object"}},
+		{"/api/items/1?q=x", `{"name": `, http.StatusBadRequest, nil},
+		{"/api/items/1?q=x", `{"name": "` + strings.Repeat("a", MaxBodySize) + `"}`, http.StatusRequestEntityTooLarge, nil},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body)))
+		if w.Code != tc.want {
+			t.Errorf("POST %s %s = %d %s", tc.path, tc.body, w.Code, w.Body)
+			continue
+		}
+		for k, v := range tc.fields {
+			if !strings.Contains(w.Body.String(), k+" "+v) {
+				t.Errorf("POST %s %s = %s, want %s %s", tc.path, tc.body, w.Body, k, v)
+			}
+		}
+	}
+}
+
+func TestOnError(t *testing.T) {
+	spec, r := newTestSpec(t)
+	var got error
+	spec.OnError(func(c *gin.Context, err error) {
+		got = err
+		c.AbortWithStatus(http.StatusTeapot)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/items/1", strings.NewReader(`{"name": "a", "score": 1}`)))
+	var verr *ValidationError
+	if w.Code != http.StatusTeapot || !errors.As(got, &verr) || verr.Fields["q"] != "is required" {
+		t.Errorf("OnError got %d %v", w.Code, got)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec, _ := newTestSpec(t)
+	route := "POST /api/items/:id"
+	for _, tc := range []struct {
+		status int
+		body   string
+		ok     bool
+	}{
+		{200, `{"data": {"id": 1, "created_at": "2024-01-02T03:04:05Z", "name": "a", "tags": []}}`, true},
+		{200, `{"data": {"id": 1, "created_at": "2024-01-02T03:04:05Z", "name": "a", "tags": null, "child": {"id": 2, "created_at": "2024-01-02T03:04:05Z", "name": "b", "tags": ["x"]}}}`, true},
+		{200, `{"data": {"id": 1, "created_at": "2024-01-02T03:04:05Z", "name": "a"}}`, false},                       // 少了 tags
+		{200, `{"data": {"id": 1, "created_at": "2024-01-02T03:04:05Z", "name": "a", "tags": [], "age": 1}}`, false}, // 文档里没有 age
+		{400, `{"message": "bad"}`, true},
+		{400, `{"error": "bad"}`, false},
+	} {
+		if err := spec.ValidateResponse(route, tc.status, []byte(tc.body)); (err == nil) != tc.ok {
+			t.Errorf("ValidateResponse(%d, %s) = %v", tc.status, tc.body, err)
+		}
+	}
+	if err := spec.ValidateResponse("DELETE /api/items/:id", http.StatusNoContent, nil); err != nil {
+		t.Errorf("204: %v", err)
+	}
+	if err := spec.ValidateResponse("GET /api/items", http.StatusOK, nil); err == nil {
+		t.Error("undocumented route: nil error")
+	}
+}
+
+func TestPanics(t *testing.T) {
+	type User struct {
+		Name string `json:"name"`
+	}
+	for name, register := range map[string]func(g *Group){
+		"undeclared path param": func(g *Group) {
+			g.GET("/users/:id", Operation{}, func(*gin.Context) {})
+		},
+		"request and response": func(g *Group) {
+			g.POST("/users", Operation{Body: User{}, Response: User{}}, func(*gin.Context) {})
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			register(New(Info{}).Group(gin.New().Group("/")))
+		}()
+	}
+}
+
+func TestUI(t *testing.T) {
+	r := gin.New()
+	r.GET("/docs/*any", UI("/openapi.json"))
+	for path, want := range map[string]string{
+		"/docs/":                     `url: "/openapi.json"`,
+		"/docs/swagger-ui-bundle.js": "SwaggerUIBundle",
+		"/docs/swagger-ui.css":       ".swagger-ui",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s = %d, body missing %q", path, w.Code, want)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/docs/oauth2-redirect.html", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET other file = %d", w.Code)
+	}
+}
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema JSON Schema（OpenAPI 3.1 使用 JSON Schema 2020-12），只包含用到的关键字
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // "string"，可为 null 时是 ["string", "null"]
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false 或 *Schema
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// types 允许的类型，Type 为空表示任意类型
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func (s *Schema) nullable() *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	if t, ok := s.Type.(string); ok {
+		s.Type = []string{t, "null"}
+	}
+	return s
+}
+
+// direction 同一个结构体在请求和响应里的 required 规则不同：
+// 请求里 binding:"required" 的字段必填；响应里没有 omitempty 的字段一定会出现
+type direction int
+
+const (
+	asRequest direction = iota
+	asResponse
+)
+
+// generator 从 Go 类型生成 Schema，有名字的结构体放进 components，用 $ref 引用
+type generator struct {
+	components map[string]*Schema
+	types      map[string]component // 检查组件重名
+}
+
+type component struct {
+	t   reflect.Type
+	dir direction
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *generator) schema(t reflect.Type, dir direction) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return g.schema(t.Elem(), dir).nullable()
+	case t.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()):
+		return &Schema{} // 自定义序列化，不知道具体格式
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: intFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: intFormat(t), Minimum: ptr(0.0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	// nil 的切片和 map 序列化成 null
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return (&Schema{Type: "string", Format: "byte"}).nullable()
+		}
+		return (&Schema{Type: "array", Items: g.schema(t.Elem(), dir)}).nullable()
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem(), dir)}
+	case reflect.Map:
+		return (&Schema{Type: "object", AdditionalProperties: g.schema(t.Elem(), dir)}).nullable()
+	case reflect.Struct:
+		return g.object(t, dir)
+	}
+	return &Schema{}
+}
+
+func intFormat(t reflect.Type) string {
+	if t.Size() == 8 {
+		return "int64"
+	}
+	return "int32"
+}
+
+// object 有名字的结构体生成组件；泛型实例（例如 handler.Data[User]）直接内联
+func (g *generator) object(t reflect.Type, dir direction) *Schema {
+	name := t.Name()
+	if name == "" || strings.Contains(name, "[") {
+		return g.inline(t, dir)
+	}
+	if prev, ok := g.types[name]; ok {
+		if prev.t != t {
+			panic("openapi: two types named " + name + ": " + prev.t.PkgPath() + " and " + t.PkgPath())
+		}
+		if prev.dir != dir {
+			panic("openapi: " + name + " is used in both requests and responses, declare separate types")
+		}
+	} else {
+		g.types[name] = component{t, dir}
+		g.components[name] = nil // 先占位，自引用的结构体不会无限递归
+		g.components[name] = g.inline(t, dir)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *generator) inline(t reflect.Type, dir direction) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	g.fields(s, t, dir)
+	return s
+}
+
+func (g *generator) fields(s *Schema, t reflect.Type, dir direction) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, omitempty, ok := fieldName(f, "json")
+		if !ok {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(s, f.Type, dir) // 嵌入的结构体字段展开
+			continue
+		}
+		fs := g.schema(f.Type, dir)
+		required := applyBinding(fs, f)
+		if dir == asResponse {
+			required = !omitempty
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// fieldName 按 tag 取字段名，没有 tag 时用字段名；"-" 跳过
+func fieldName(f reflect.StructField, tag string) (name string, omitempty, ok bool) {
+	v, opts, _ := strings.Cut(f.Tag.Get(tag), ",")
+	if v == "-" {
+		return "", false, false
+	}
+	if v == "" {
+		v = f.Name
+	}
+	return v, strings.Contains(opts, "omitempty"), true
+}
+
+// applyBinding 把 binding 标签里的校验规则翻译成 Schema 关键字，返回是否必填
+// 只支持 handler 里用到的规则：required、min、max、gte、lte、gt、lt、oneof
+func applyBinding(s *Schema, f reflect.StructField) (required bool) {
+	target := s
+	if len(s.AnyOf) > 0 {
+		target = s.AnyOf[0]
+	}
+	str := slices.Contains(target.types(), "string")
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		key, param, _ := strings.Cut(rule, "=")
+		n, err := strconv.ParseFloat(param, 64)
+		switch {
+		case key == "required":
+			required = true
+		case key == "oneof":
+			for _, v := range strings.Fields(param) {
+				if str {
+					target.Enum = append(target.Enum, v)
+				} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+					target.Enum = append(target.Enum, n)
+				}
+			}
+		case err != nil:
+		case str && (key == "min" || key == "gte"):
+			target.MinLength = ptr(int(n))
+		case str && (key == "max" || key == "lte"):
+			target.MaxLength = ptr(int(n))
+		case key == "min" || key == "gte":
+			target.Minimum = ptr(n)
+		case key == "max" || key == "lte":
+			target.Maximum = ptr(n)
+		case key == "gt":
+			target.ExclusiveMinimum = ptr(n)
+		case key == "lt":
+			target.ExclusiveMaximum = ptr(n)
+		}
+	}
+	return required
+}
+
+func ptr[T any](v T) *T { return &v }
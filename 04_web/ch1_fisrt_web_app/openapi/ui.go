@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// ui/index.html 编译时嵌入二进制，Swagger UI 的 js、css 来自 swaggo/files 里嵌入的 dist 目录，
+// 部署时不需要额外的静态文件
+//
+//go:embed ui/index.html
+var ui embed.FS
+
+var page = template.Must(template.ParseFS(ui, "ui/index.html"))
+
+// UI Swagger UI 页面，specURL 是文档的地址；路由要带通配符：
+//
+//	r.GET("/docs/*any", openapi.UI("/openapi.json"))
+func UI(specURL string) gin.HandlerFunc {
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, specURL); err != nil {
+		panic(err)
+	}
+	index := buf.Bytes()
+	assets := http.FileServerFS(swaggerFiles.FS)
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(c.Param("any"), "/")
+		switch name {
+		case "":
+			c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+		case "swagger-ui.css", "swagger-ui-bundle.js", "swagger-ui-standalone-preset.js":
+			c.Request.URL.Path = "/" + name
+			assets.ServeHTTP(c.Writer, c.Request)
+		default:
+			c.AbortWithStatus(http.StatusNotFound)
+		}
+	}
+}
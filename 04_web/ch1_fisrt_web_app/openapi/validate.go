@@ -0,0 +1,350 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidationError 请求（或者测试里的响应）不符合文档，Fields 的 key 是参数名或请求体里的字段路径，例如 age、items[0].name
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	keys := slices.Sorted(maps.Keys(e.Fields))
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + " " + e.Fields[k]
+	}
+	return "openapi: validation failed: " + strings.Join(parts, "; ")
+}
+
+// MaxBodySize 校验时读取的请求体上限，超过时交给 OnError 的是 *http.MaxBytesError
+const MaxBodySize = 1 << 20
+
+// OnError 校验失败时的处理，默认返回 400（请求体太大时 413）和错误信息；一般设置成统一的错误响应
+func (s *Spec) OnError(fn func(c *gin.Context, err error)) { s.onError = fn }
+
+func (s *Spec) fail(c *gin.Context, err error) {
+	if s.onError != nil {
+		s.onError(c, err)
+		return
+	}
+	status := http.StatusBadRequest
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+}
+
+// compiled 一个操作校验用到的 Schema
+type compiled struct {
+	spec     *Spec
+	path     map[string]*Schema
+	query    map[string]*Schema
+	required []string // 必填的查询参数
+	body     *Schema
+	response *Schema
+	status   int
+}
+
+// params 从 uri、form 标签生成路径参数和查询参数
+func (c *compiled) params(s *Spec, t reflect.Type, o *operation) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			c.params(s, f.Type, o)
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem() // 参数不存在就是 nil，文档里不需要 null
+		}
+		for _, in := range []string{"uri", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(in), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			schema := s.gen.schema(ft, asRequest)
+			required := applyBinding(schema, f)
+			p := &parameter{Name: name, In: "query", Required: required, Schema: schema}
+			if in == "uri" {
+				p.In, p.Required = "path", true
+				c.path[name] = schema
+			} else {
+				c.query[name] = schema
+				if required {
+					c.required = append(c.required, name)
+				}
+			}
+			o.Parameters = append(o.Parameters, p)
+		}
+	}
+}
+
+// validate 中间件：检查路径参数、查询参数和 JSON 请求体，通过后把请求体放回去给 ShouldBindJSON 用
+func (c *compiled) validate(ctx *gin.Context) {
+	v := &validator{spec: c.spec, fields: map[string]string{}}
+	for name, s := range c.path {
+		v.param(s, ctx.Param(name), name)
+	}
+	query := ctx.Request.URL.Query()
+	for _, name := range c.required {
+		if !query.Has(name) {
+			v.fields[name] = "is required"
+		}
+	}
+	for name, s := range c.query {
+		if query.Has(name) {
+			v.param(s, query.Get(name), name)
+		}
+	}
+	if c.body != nil {
+		b, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MaxBodySize))
+		if err != nil {
+			c.spec.fail(ctx, err)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(b))
+		x, err := decode(b)
+		if err != nil {
+			c.spec.fail(ctx, err)
+			return
+		}
+		v.check(c.body, x, "")
+	}
+	if len(v.fields) > 0 {
+		c.spec.fail(ctx, &ValidationError{Fields: v.fields})
+		return
+	}
+	ctx.Next()
+}
+
+// decode 数字保留为 json.Number，才能区分整数和小数
+func decode(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var x any
+	if err := dec.Decode(&x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// ValidateResponse 检查响应体是否符合文档：成功状态码对照 Response，其他状态码对照 Error
+// route 是 gin 的路由格式，例如 "GET /api/v1/users/:id"；测试里用来发现处理器返回的结构和文档不一致
+func (s *Spec) ValidateResponse(route string, status int, body []byte) error {
+	c, ok := s.ops[route]
+	if !ok {
+		return fmt.Errorf("openapi: %s is not documented", route)
+	}
+	schema := c.response
+	if status != c.status {
+		if s.Error == nil {
+			return nil
+		}
+		schema = s.gen.schema(reflect.TypeOf(s.Error), asResponse)
+	}
+	if schema == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("openapi: %s %d: want empty body, got %s", route, status, body)
+		}
+		return nil
+	}
+	x, err := decode(body)
+	if err != nil {
+		return fmt.Errorf("openapi: %s %d: %w", route, status, err)
+	}
+	v := &validator{spec: s, fields: map[string]string{}}
+	v.check(schema, x, "")
+	if len(v.fields) > 0 {
+		return fmt.Errorf("%s %d: %w", route, status, &ValidationError{Fields: v.fields})
+	}
+	return nil
+}
+
+type validator struct {
+	spec   *Spec
+	fields map[string]string
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "body"
+	}
+	if _, ok := v.fields[path]; !ok {
+		v.fields[path] = fmt.Sprintf(format, args...)
+	}
+}
+
+// param 路径参数和查询参数都是字符串，按 Schema 的类型转换后再检查
+func (v *validator) param(s *Schema, raw, name string) {
+	var x any = raw
+	switch t := s.types(); {
+	case slices.Contains(t, "integer"), slices.Contains(t, "number"):
+		x = json.Number(raw)
+	case slices.Contains(t, "boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			v.fail(name, "must be boolean")
+			return
+		}
+		x = b
+	}
+	v.check(s, x, name)
+}
+
+func (v *validator) check(s *Schema, x any, path string) {
+	if s.Ref != "" {
+		s = v.spec.gen.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if len(s.AnyOf) > 0 {
+		for _, sub := range s.AnyOf {
+			try := &validator{spec: v.spec, fields: map[string]string{}}
+			if try.check(sub, x, path); len(try.fields) == 0 {
+				return
+			}
+		}
+		// 都不匹配时报第一个分支的错误，一般第一个是实际的类型，第二个是 null
+		v.check(s.AnyOf[0], x, path)
+		return
+	}
+	types := s.types()
+	if x == nil {
+		if len(types) > 0 && !slices.Contains(types, "null") {
+			v.fail(path, "must not be null")
+		}
+		return
+	}
+	switch x := x.(type) {
+	case json.Number:
+		v.number(s, types, x, path)
+	case string:
+		if !allows(types, "string") {
+			v.fail(path, "must be %s", types[0])
+			return
+		}
+		v.string(s, x, path)
+	case bool:
+		if !allows(types, "boolean") {
+			v.fail(path, "must be %s", types[0])
+		}
+	case []any:
+		if !allows(types, "array") {
+			v.fail(path, "must be %s", types[0])
+			return
+		}
+		if s.Items != nil {
+			for i, item := range x {
+				v.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]any:
+		if !allows(types, "object") {
+			v.fail(path, "must be %s", types[0])
+			return
+		}
+		v.object(s, x, path)
+	}
+}
+
+func allows(types []string, t string) bool {
+	return len(types) == 0 || slices.Contains(types, t)
+}
+
+func (v *validator) number(s *Schema, types []string, x json.Number, path string) {
+	f, err := x.Float64()
+	switch {
+	case err != nil:
+		v.fail(path, "must be %s", types[0])
+		return
+	case allows(types, "number"):
+	case slices.Contains(types, "integer"):
+		if _, err := strconv.ParseInt(string(x), 10, 64); err != nil {
+			v.fail(path, "must be integer")
+			return
+		}
+	default:
+		v.fail(path, "must be %s", types[0])
+		return
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, any(f)) {
+		v.fail(path, "must be one of %s", enum(s.Enum))
+	}
+	switch {
+	case s.Minimum != nil && f < *s.Minimum:
+		v.fail(path, "must be at least %s", num(*s.Minimum))
+	case s.Maximum != nil && f > *s.Maximum:
+		v.fail(path, "must be at most %s", num(*s.Maximum))
+	case s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum:
+		v.fail(path, "must be greater than %s", num(*s.ExclusiveMinimum))
+	case s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum:
+		v.fail(path, "must be less than %s", num(*s.ExclusiveMaximum))
+	}
+}
+
+func (v *validator) string(s *Schema, x, path string) {
+	n := utf8.RuneCountInString(x)
+	switch {
+	case len(s.Enum) > 0 && !slices.Contains(s.Enum, any(x)):
+		v.fail(path, "must be one of %s", enum(s.Enum))
+	case s.MinLength != nil && n < *s.MinLength:
+		v.fail(path, "must be at least %d characters", *s.MinLength)
+	case s.MaxLength != nil && n > *s.MaxLength:
+		v.fail(path, "must be at most %d characters", *s.MaxLength)
+	case s.Format == "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, x); err != nil {
+			v.fail(path, "must be an RFC 3339 date-time")
+		}
+	}
+}
+
+func (v *validator) object(s *Schema, x map[string]any, path string) {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, name := range s.Required {
+		if _, ok := x[name]; !ok {
+			v.fail(prefix+name, "is required")
+		}
+	}
+	for name, value := range x {
+		if ps, ok := s.Properties[name]; ok {
+			v.check(ps, value, prefix+name)
+			continue
+		}
+		switch extra := s.AdditionalProperties.(type) {
+		case bool:
+			if !extra {
+				v.fail(prefix+name, "unknown field")
+			}
+		case *Schema:
+			v.check(extra, value, prefix+name)
+		}
+	}
+}
+
+func num(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+func enum(values []any) string {
+	parts := make([]string, len(values))
+	for i, e := range values {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory 进程内的 Store，只在单实例部署或测试时使用
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+}
+
+type entry struct {
+	bucket
+	window
+	expires time.Time // 过了这个时间状态和新建的一样，可以删除
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: map[string]*entry{}}
+}
+
+// sweepInterval 多久清理一次过期的 key，避免按 IP 限流时 map 一直增长
+const sweepInterval = time.Minute
+
+func (m *Memory) Allow(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	if err := l.Validate(); err != nil {
+		return Result{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.swept) > sweepInterval {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.swept = now
+	}
+
+	e, ok := m.entries[key]
+	if !ok {
+		e = &entry{}
+		m.entries[key] = e
+	}
+	var r Result
+	if l.Algorithm == TokenBucket {
+		r = e.take(l, now)
+		e.expires = now.Add(r.Reset)
+	} else {
+		r = e.hit(l, now)
+		e.expires = e.start.Add(2 * l.Window) // 下一个窗口还要用到这个窗口的计数
+	}
+	return r, nil
+}
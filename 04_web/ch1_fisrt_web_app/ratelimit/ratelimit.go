@@ -0,0 +1,175 @@
+// Package ratelimit 限流算法和计数存储
+//
+// gobyexample/42_rate_limiting.go 用 time.Tick 和带缓冲的 channel 在单个进程里限流，
+// 请求会排队等待；HTTP 服务里不能让请求一直等，超过限制直接拒绝，并告诉客户端多久后重试。
+// 多个实例共享同一个限额时，计数要放在 Redis 里
+//
+// 两种算法：
+//
+//	TokenBucket    令牌桶：每 Window/Limit 补一个令牌，最多攒 Burst 个，允许短时间突发（对应 burstyLimiter）
+//	SlidingWindow  滑动窗口：任意长度为 Window 的时间段内最多 Limit 个请求，按上一个窗口的计数加权估算
+//
+// 这个包不依赖 gin，中间件在 handler 包
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Algorithm 限流算法
+type Algorithm string
+
+const (
+	TokenBucket   Algorithm = "token_bucket"
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Limit 一条限流规则
+type Limit struct {
+	Algorithm Algorithm
+	Limit     int           // Window 内允许的请求数
+	Window    time.Duration // 例如 time.Second、time.Minute
+	Burst     int           // 令牌桶容量，默认等于 Limit；滑动窗口不使用
+}
+
+func (l Limit) Validate() error {
+	switch l.Algorithm {
+	case TokenBucket, SlidingWindow:
+	default:
+		return fmt.Errorf("ratelimit: unknown algorithm %q, want %s or %s", l.Algorithm, TokenBucket, SlidingWindow)
+	}
+	if l.Limit <= 0 || l.Window <= 0 {
+		return fmt.Errorf("ratelimit: limit and window must be positive")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("ratelimit: burst must not be negative")
+	}
+	return nil
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Limit
+}
+
+// Result 一次检查的结果，用来设置 X-RateLimit-* 和 Retry-After 响应头
+type Result struct {
+	Allowed    bool
+	Limit      int           // 令牌桶是 Burst，滑动窗口是 Limit
+	Remaining  int           // 还能发多少个请求
+	RetryAfter time.Duration // 被拒绝时，多久之后可以重试
+	Reset      time.Duration // 多久之后额度完全恢复：令牌桶加满，滑动窗口里已有的请求都不再计入
+}
+
+// Store 保存计数，Allow 检查并消耗一次额度，要保证并发安全和原子性
+// now 由调用方传入，多实例共享 Redis 时各实例的时钟需要同步（NTP）
+type Store interface {
+	Allow(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+// bucket 令牌桶状态：上次计算时的令牌数和时间
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take 补充令牌并尝试取一个
+func (b *bucket) take(l Limit, now time.Time) Result {
+	burst := float64(l.burst())
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+float64(elapsed)*rate(l))
+	}
+	// 时钟回拨时不补充，也不把 last 往回挪
+	if now.After(b.last) {
+		b.last = now
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return fill(l, b.tokens, allowed)
+}
+
+// rate 每纳秒补充的令牌数
+func rate(l Limit) float64 { return float64(l.Limit) / float64(l.Window) }
+
+// fill 按取令牌之后剩下的令牌数计算结果，Memory 和 Redis 共用
+func fill(l Limit, tokens float64, allowed bool) Result {
+	burst := l.burst()
+	r := Result{Allowed: allowed, Limit: burst, Remaining: int(tokens)}
+	if !allowed {
+		r.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate(l)))
+	}
+	r.Reset = time.Duration(math.Ceil((float64(burst) - tokens) / rate(l)))
+	return r
+}
+
+// window 滑动窗口状态：当前固定窗口的开始时间和计数，以及上一个窗口的计数
+type window struct {
+	start      time.Time
+	curr, prev int
+}
+
+// hit 估算最近 Window 内的请求数 = 上一个窗口的计数 × 还在范围内的比例 + 当前窗口的计数
+func (w *window) hit(l Limit, now time.Time) Result {
+	start := now.Truncate(l.Window)
+	switch {
+	case start.Equal(w.start):
+	case start.Equal(w.start.Add(l.Window)):
+		w.prev, w.curr = w.curr, 0
+	default:
+		w.prev, w.curr = 0, 0
+	}
+	w.start = start
+
+	elapsed := now.Sub(start)
+	r := slide(l, w.prev, w.curr, elapsed)
+	if r.Allowed {
+		w.curr++
+	}
+	return r
+}
+
+// weight 上一个窗口还在最近 Window 内的比例
+func weight(l Limit, elapsed time.Duration) float64 {
+	return 1 - float64(elapsed)/float64(l.Window)
+}
+
+// slide 按计数判断是否允许，不修改计数；Memory 和 Redis 共用
+func slide(l Limit, prev, curr int, elapsed time.Duration) Result {
+	count := float64(prev)*weight(l, elapsed) + float64(curr)
+	r := Result{Limit: l.Limit}
+	if count+1 <= float64(l.Limit) {
+		r.Allowed = true
+		r.Remaining = int(float64(l.Limit) - count - 1)
+		curr++
+	}
+	// 当前窗口有计数时，要到下一个窗口结束才完全不计入
+	r.Reset = l.Window - elapsed
+	if curr > 0 {
+		r.Reset += l.Window
+	}
+	switch {
+	case r.Allowed:
+	case curr >= l.Limit:
+		// 当前窗口已经满了：等到下一个窗口，这个窗口的计数变成 prev，再等它的权重降下来
+		r.RetryAfter = l.Window - elapsed + fade(l.Window, curr, l.Limit-1)
+	default:
+		r.RetryAfter = max(fade(l.Window, prev, l.Limit-curr-1)-elapsed, time.Millisecond)
+	}
+	return r
+}
+
+// fade 从窗口开始算，上一个窗口的 prev 个请求按权重降到不超过 free 个需要多久：
+// prev × (1 - t/window) <= free，解出 t；用整数算，避免浮点误差多出 1ns
+func fade(window time.Duration, prev, free int) time.Duration {
+	over := int64(prev - free)
+	return time.Duration((int64(window)*over + int64(prev) - 1) / int64(prev))
+}
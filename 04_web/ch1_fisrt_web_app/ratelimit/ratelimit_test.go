@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// stores 同样的用例对两种 Store 各跑一遍，Redis 用 miniredis
+func stores(t *testing.T) map[string]Store {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]Store{"memory": NewMemory(), "redis": NewRedis(client)}
+}
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// allow 连续请求 n 次，返回允许的次数和最后一次的结果
+func allow(t *testing.T, s Store, key string, l Limit, now time.Time, n int) (int, Result) {
+	t.Helper()
+	ok := 0
+	var r Result
+	for range n {
+		var err error
+		if r, err = s.Allow(context.Background(), key, l, now); err != nil {
+			t.Fatal(err)
+		}
+		if r.Allowed {
+			ok++
+		}
+	}
+	return ok, r
+}
+
+func TestTokenBucket(t *testing.T) {
+	// 每秒 2 个，最多攒 5 个
+	l := Limit{Algorithm: TokenBucket, Limit: 2, Window: time.Second, Burst: 5}
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ok, r := allow(t, s, "a", l, t0, 7)
+			if ok != 5 || r.Allowed || r.Limit != 5 || r.Remaining != 0 || r.RetryAfter != 500*time.Millisecond || r.Reset != 2500*time.Millisecond {
+				t.Errorf("burst: ok=%d last=%+v", ok, r)
+			}
+			// 其他 key 不受影响
+			if ok, _ := allow(t, s, "b", l, t0, 1); ok != 1 {
+				t.Error("key b limited")
+			}
+			// 500ms 补一个
+			if ok, r := allow(t, s, "a", l, t0.Add(500*time.Millisecond), 2); ok != 1 || r.RetryAfter != 500*time.Millisecond {
+				t.Errorf("after 500ms: ok=%d last=%+v", ok, r)
+			}
+			// 很久以后最多攒到 Burst
+			if ok, _ := allow(t, s, "a", l, t0.Add(time.Hour), 10); ok != 5 {
+				t.Errorf("after an hour: ok=%d", ok)
+			}
+			// 时钟回拨不会多给令牌
+			if ok, _ := allow(t, s, "a", l, t0, 1); ok != 0 {
+				t.Errorf("clock went back: ok=%d", ok)
+			}
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l := Limit{Algorithm: SlidingWindow, Limit: 10, Window: time.Minute}
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			start := t0.Add(30 * time.Second)
+			ok, r := allow(t, s, "a", l, start, 12)
+			// 要等到下一个窗口过了 6s，上一个窗口的 10 个降到 9 个
+			if ok != 10 || r.Allowed || r.Remaining != 0 || r.RetryAfter != 36*time.Second || r.Reset != 90*time.Second {
+				t.Errorf("first window: ok=%d last=%+v", ok, r)
+			}
+			if ok, _ := allow(t, s, "a", l, start.Add(35*time.Second), 1); ok != 0 {
+				t.Errorf("before RetryAfter: ok=%d", ok)
+			}
+			// 下一个窗口过了 15s：上一个窗口的 10 个还算 7.5 个
+			next := t0.Add(75 * time.Second)
+			if ok, r := allow(t, s, "a", l, next, 3); ok != 2 || r.Remaining != 0 || r.RetryAfter != 3*time.Second {
+				t.Errorf("next window: ok=%d last=%+v", ok, r)
+			}
+			// 过了 RetryAfter 确实可以再请求一次
+			if ok, _ := allow(t, s, "a", l, next.Add(3*time.Second), 1); ok != 1 {
+				t.Errorf("after RetryAfter: ok=%d", ok)
+			}
+			// 隔了两个窗口，全部清零
+			if ok, _ := allow(t, s, "a", l, t0.Add(5*time.Minute), 11); ok != 10 {
+				t.Errorf("later: ok=%d", ok)
+			}
+		})
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	for _, l := range []Limit{
+		{Algorithm: TokenBucket, Limit: 1, Window: time.Hour, Burst: 50},
+		{Algorithm: SlidingWindow, Limit: 50, Window: time.Hour},
+	} {
+		for name, s := range stores(t) {
+			var mu sync.Mutex
+			ok := 0
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 5 {
+						r, err := s.Allow(context.Background(), "c", l, t0)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if r.Allowed {
+							mu.Lock()
+							ok++
+							mu.Unlock()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if ok != 50 {
+				t.Errorf("%s %s: %d of 100 allowed, want 50", name, l.Algorithm, ok)
+			}
+		}
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	l := Limit{Algorithm: TokenBucket, Limit: 10, Window: time.Second}
+	for i := range 100 {
+		m.Allow(context.Background(), fmt.Sprint("ip", i), l, t0)
+	}
+	m.Allow(context.Background(), "late", l, t0.Add(2*sweepInterval))
+	if n := len(m.entries); n != 1 {
+		t.Errorf("%d entries after sweep, want 1", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, l := range []Limit{
+		{Algorithm: "leaky", Limit: 1, Window: time.Second},
+		{Algorithm: TokenBucket, Window: time.Second},
+		{Algorithm: SlidingWindow, Limit: 1},
+	} {
+		if _, err := NewMemory().Allow(context.Background(), "k", l, t0); err == nil {
+			t.Errorf("Allow(%+v) = nil error", l)
+		}
+	}
+}
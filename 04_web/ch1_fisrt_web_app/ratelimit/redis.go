@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 用 Redis 协议的服务（Redis、Valkey、KeyDB 等）保存计数，多个实例共享限额
+// 检查和扣减在一个 Lua 脚本里完成，保证原子性；key 用 {} 包起来，集群模式下同一个 key 的数据在同一个槽
+type Redis struct {
+	client redis.Scripter
+	Prefix string // key 前缀，默认 ratelimit:
+}
+
+// NewRedis client 可以是 *redis.Client、*redis.ClusterClient 或 *redis.Ring
+func NewRedis(client redis.Scripter) *Redis {
+	return &Redis{client: client, Prefix: "ratelimit:"}
+}
+
+// tokenBucketScript 返回 {是否允许, 剩余令牌数}，和 bucket.take 的计算一致
+//
+//	KEYS[1]  令牌桶 hash：tokens、last（毫秒）
+//	ARGV     burst、每毫秒补充的令牌数、当前时间（毫秒）
+var tokenBucketScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  last = now
+elseif now > last then
+  tokens = math.min(burst, tokens + (now - last) * rate)
+  last = now
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil((burst - tokens) / rate)))
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript 返回 {是否允许, 上一个窗口计数, 当前窗口计数（不含这次）}
+//
+//	KEYS[1]  当前窗口计数，KEYS[2] 上一个窗口计数
+//	ARGV     limit、上一个窗口的权重、计数的过期时间（毫秒）
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * weight + curr + 1 <= limit then
+  redis.call('INCR', KEYS[1])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return {1, prev, curr}
+end
+return {0, prev, curr}
+`)
+
+func (s *Redis) Allow(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	if err := l.Validate(); err != nil {
+		return Result{}, err
+	}
+	key = s.Prefix + "{" + key + "}"
+	if l.Algorithm == TokenBucket {
+		return s.tokenBucket(ctx, key, l, now)
+	}
+	return s.slidingWindow(ctx, key, l, now)
+}
+
+func (s *Redis) tokenBucket(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	perMs := rate(l) * float64(time.Millisecond)
+	v, err := tokenBucketScript.Run(ctx, s.client, []string{key}, l.burst(), perMs, now.UnixMilli()).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: redis: %w", err)
+	}
+	if len(v) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: redis: unexpected reply %v", v)
+	}
+	allowed, _ := v[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(v[1]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: redis: unexpected reply %v", v)
+	}
+	return fill(l, tokens, allowed == 1), nil
+}
+
+func (s *Redis) slidingWindow(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	start := now.Truncate(l.Window)
+	elapsed := now.Sub(start)
+	keys := []string{
+		key + ":" + strconv.FormatInt(start.UnixMilli(), 10),
+		key + ":" + strconv.FormatInt(start.Add(-l.Window).UnixMilli(), 10),
+	}
+	ttl := (2*l.Window - elapsed).Milliseconds() + 1
+	v, err := slidingWindowScript.Run(ctx, s.client, keys, l.Limit, weight(l, elapsed), ttl).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: redis: %w", err)
+	}
+	if len(v) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: redis: unexpected reply %v", v)
+	}
+	return slide(l, int(v[1]), int(v[2]), elapsed), nil
+}
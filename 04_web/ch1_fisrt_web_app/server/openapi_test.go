@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"first/auth"
+	"first/handler"
+	"first/openapi"
+
+	"mysql-demo/fixtures/fixturestest"
+)
+
+var update = flag.Bool("update", false, "用当前的路由重新生成 openapi.json")
+
+// golden 模块根目录的 openapi.json，提交到仓库，接口的改动在 diff 里一目了然
+const golden = "../openapi.json"
+
+// 文档和路由、处理器保持一致：
+//   - /openapi.json 和 golden 文件相同，改了请求、响应结构体之后用 go test ./server -update 重新生成
+//   - /auth 和 /api 下的每个路由都写进了文档
+//   - 处理器真实的响应（成功和失败）符合文档
+func TestOpenAPI(t *testing.T) {
+	db, _ := fixturestest.Open(t, "edge_ages")
+	tokens, _ := auth.NewHS256([]byte(strings.Repeat("k", auth.MinSecretLen)))
+	hash, _ := auth.HashPassword("s3cret")
+	users := auth.NewStaticUsers([]auth.StaticUser{{Name: "admin", PasswordHash: hash, Roles: []string{"admin"}}, {Name: "alice", PasswordHash: hash}})
+	r, spec := newRouter(Handlers{
+		Health: handler.NewHealth(),
+		System: &handler.System{Version: "1.0.0"},
+		Users:  handler.NewUsers(db),
+		Auth:   handler.NewAuth(tokens, users),
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc bytes.Buffer
+	if err := json.Indent(&doc, w.Body.Bytes(), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	doc.WriteByte('\n')
+	if *update {
+		if err := os.WriteFile(golden, doc.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, err := os.ReadFile(golden); err != nil || !bytes.Equal(doc.Bytes(), want) {
+		t.Errorf("/openapi.json differs from %s (err %v), run go test ./server -update and review the diff", golden, err)
+	}
+
+	documented := spec.Routes()
+	for _, route := range r.Routes() {
+		if strings.HasPrefix(route.Path, "/api/") || strings.HasPrefix(route.Path, "/auth/") {
+			if key := route.Method + " " + route.Path; !slices.Contains(documented, key) {
+				t.Errorf("%s is not documented", key)
+			}
+		}
+	}
+
+	ctx := context.Background()
+	admin, _ := tokens.Issue(ctx, auth.Principal{Subject: "admin", Roles: []string{"admin"}})
+	alice, _ := tokens.Issue(ctx, auth.Principal{Subject: "alice"})
+	for _, tc := range []struct {
+		route, path, body, token string
+		want                     int
+	}{
+		{"POST /auth/login", "", `{"username": "admin", "password": "s3cret"}`, "", http.StatusOK},
+		{"POST /auth/login", "", `{"username": "admin", "password": "wrong"}`, "", http.StatusUnauthorized},
+		{"POST /auth/login", "", `{"username": "admin"}`, "", http.StatusBadRequest},
+		{"POST /auth/refresh", "", `{"refresh_token": "` + admin.RefreshToken + `"}`, "", http.StatusOK},
+		{"POST /auth/logout", "", `{"refresh_token": "` + alice.RefreshToken + `"}`, "", http.StatusNoContent},
+		{"GET /auth/me", "", "", alice.AccessToken, http.StatusOK},
+		{"GET /auth/me", "", "", "", http.StatusUnauthorized},
+		{"GET /api/v1/ping", "", "", "", http.StatusOK},
+		{"GET /api/v1/version", "", "", "", http.StatusOK},
+		{"GET /api/v1/users", "/api/v1/users?deleted=with&sort=-age", "", alice.AccessToken, http.StatusOK},
+		{"GET /api/v1/users", "/api/v1/users?sort=password", "", alice.AccessToken, http.StatusBadRequest},
+		{"GET /api/v1/users", "/api/v1/users?size=1000", "", alice.AccessToken, http.StatusBadRequest},
+		{"GET /api/v1/users/:id", "/api/v1/users/1", "", alice.AccessToken, http.StatusOK},
+		{"GET /api/v1/users/:id", "/api/v1/users/99", "", alice.AccessToken, http.StatusNotFound},
+		{"POST /api/v1/users", "", `{"name": "张三", "age": 20, "birthday": "2004-05-06T00:00:00Z"}`, admin.AccessToken, http.StatusCreated},
+		{"POST /api/v1/users", "", `{"name": "张三", "age": 20}`, alice.AccessToken, http.StatusForbidden},
+		{"POST /api/v1/users", "", `{"name": "` + strings.Repeat("张", openapi.MaxBodySize) + `"}`, admin.AccessToken, http.StatusRequestEntityTooLarge},
+		{"PATCH /api/v1/users/:id", "/api/v1/users/1", `{"birthday": null, "age": 19}`, admin.AccessToken, http.StatusOK},
+		{"DELETE /api/v1/users/:id", "/api/v1/users/2", "", admin.AccessToken, http.StatusNoContent},
+		{"POST /api/v1/users/:id/restore", "/api/v1/users/2/restore", "", admin.AccessToken, http.StatusOK},
+	} {
+		method, path, _ := strings.Cut(tc.route, " ")
+		if tc.path != "" {
+			path = tc.path
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(tc.body))
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d: %s", method, path, w.Code, tc.want, w.Body)
+			continue
+		}
+		if err := spec.ValidateResponse(tc.route, w.Code, w.Body.Bytes()); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"first/config"
+	"first/handler"
+	"first/ratelimit"
+)
+
+// Limits 各路由分组的限流中间件，为 nil 的分组不限流
+type Limits struct {
+	Auth gin.HandlerFunc
+	API  gin.HandlerFunc
+}
+
+// NewLimits 按配置创建限流中间件，closer 用来关闭 Redis 连接
+func NewLimits(cfg config.RateLimit) (limits Limits, closer func() error, err error) {
+	closer = func() error { return nil }
+	var store ratelimit.Store = ratelimit.NewMemory()
+	if cfg.Store == "redis" {
+		client := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+		store, closer = ratelimit.NewRedis(client), client.Close
+	}
+	limiter := handler.NewRateLimiter(store)
+	keys := keyFuncs(cfg.APIKeys)
+
+	build := func(name string, rule config.Rule) (gin.HandlerFunc, error) {
+		if rule.Limit == 0 {
+			return nil, nil
+		}
+		key, ok := keys[rule.Key]
+		if !ok {
+			return nil, fmt.Errorf("server: rate_limit.%s: unknown key %q", name, rule.Key)
+		}
+		return limiter.Limit(name, rule.Rate(), key), nil
+	}
+	if limits.Auth, err = build("auth", cfg.Auth); err != nil {
+		closer()
+		return Limits{}, nil, err
+	}
+	if limits.API, err = build("api", cfg.API); err != nil {
+		closer()
+		return Limits{}, nil, err
+	}
+	return limits, closer, nil
+}
+
+// APIKeyHeader rate_limit 的 key 为 api_key 时读取的请求头
+const APIKeyHeader = "X-API-Key"
+
+// keyFuncs rate_limit 的 key 对应的 KeyFunc；只有 apiKeys 里的 key 按 key 计数
+func keyFuncs(apiKeys []string) map[string]handler.KeyFunc {
+	known := make(map[string]bool, len(apiKeys))
+	for _, k := range apiKeys {
+		known[k] = true
+	}
+	return map[string]handler.KeyFunc{
+		"ip":      handler.ByIP,
+		"api_key": handler.ByAPIKey(APIKeyHeader, func(k string) bool { return known[k] }),
+		"user":    handler.ByUser,
+		"route":   handler.ByRoute,
+	}
+}
+
+// use 去掉为 nil 的中间件
+func use(hs ...gin.HandlerFunc) []gin.HandlerFunc {
+	var out []gin.HandlerFunc
+	for _, h := range hs {
+		if h != nil {
+			out = append(out, h)
+		}
+	}
+	return out
+}
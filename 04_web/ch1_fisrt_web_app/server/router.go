@@ -0,0 +1,81 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"first/handler"
+	"first/openapi"
+)
+
+// Handlers 路由用到的处理器，由 main 创建并注入依赖
+type Handlers struct {
+	Health *handler.Health
+	System *handler.System
+	Users  *handler.Users // 为 nil 时不注册用户接口（没有数据库）
+	Auth   *handler.Auth  // 为 nil 时业务接口不需要登录，只在测试里这样用
+	Limits Limits
+	// TrustedProxies 信任的反向代理，见 config.Server.TrustedProxies；nil 表示不信任任何代理
+	TrustedProxies []string
+}
+
+// NewRouter 组装路由
+//
+//	GET /healthz /readyz   探针，不记访问日志
+//	GET /  /ping           原来的两个接口
+//	POST /auth/...         登录、刷新、退出
+//	/api/v1/ping /version  不需要登录
+//	/api/v1/...            业务接口，需要登录，写操作需要 admin 角色；不兼容的改动放到 /api/v2
+//	GET /openapi.json      /auth 和 /api/v1 的 OpenAPI 文档，请求先按文档校验再交给处理器
+//	GET /docs/             Swagger UI
+//
+// /auth 和需要登录的 /api/v1 分别按 h.Limits 限流；找不到路由时也返回 handler 包的错误格式
+// h.TrustedProxies 不是合法的 IP 或 CIDR 时 panic，config.Validate 已经检查过
+func NewRouter(h Handlers) *gin.Engine {
+	r, _ := newRouter(h)
+	return r
+}
+
+// newRouter 同时返回文档，测试里用来检查响应和文档是否一致
+func newRouter(h Handlers) (*gin.Engine, *openapi.Spec) {
+	spec := openapi.New(openapi.Info{Title: "first", Version: h.System.Version})
+	spec.Error = handler.ErrorResponse{}
+	spec.OnError(handler.Fail)
+
+	r := gin.New()
+	// gin 默认信任所有代理，X-Forwarded-For 可以随便填，按 IP 限流形同虚设
+	if err := r.SetTrustedProxies(h.TrustedProxies); err != nil {
+		panic("server: " + err.Error())
+	}
+	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{SkipPaths: []string{"/healthz", "/readyz"}}), gin.Recovery())
+
+	h.Health.Register(r)
+	if h.Auth != nil {
+		h.Auth.Register(spec.Group(r.Group("", use(h.Limits.Auth)...)))
+	}
+	r.GET("/", h.System.Home)
+	r.GET("/ping", h.System.Ping)
+
+	v1 := r.Group("/api/v1")
+	h.System.Register(spec.Group(v1))
+
+	api := spec.Group(v1.Group(""))
+	var write []gin.HandlerFunc
+	if h.Auth != nil {
+		api.Routes().Use(h.Auth.Authenticate)
+		api.Secured = true
+		write = append(write, handler.RequireRole("admin"))
+	}
+	// 在 Authenticate 之后，按用户限流时才能拿到用户名
+	api.Routes().Use(use(h.Limits.API)...)
+	if h.Users != nil {
+		h.Users.Register(api, write...)
+	}
+
+	r.GET("/openapi.json", spec.Serve)
+	r.GET("/docs/*any", openapi.UI("/openapi.json"))
+
+	r.NoRoute(func(c *gin.Context) {
+		handler.Fail(c, handler.NotFound("no route for %s %s", c.Request.Method, c.Request.URL.Path))
+	})
+	return r, spec
+}
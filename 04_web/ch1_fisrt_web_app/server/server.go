@@ -0,0 +1,90 @@
+// Package server 路由组装和 HTTP 服务器的启动、优雅关闭
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"first/config"
+	"first/handler"
+)
+
+// ErrShutdown 排空超时后，仍在处理的请求的 ctx 以这个 cause 被取消
+// 处理器可以用 context.Cause(c.Request.Context()) 区分客户端断开和服务器关闭
+var ErrShutdown = errors.New("server: shutting down")
+
+// Run 监听 cfg.Addr 并阻塞，直到 ctx 结束或收到 SIGINT / SIGTERM，见 Serve
+func Run(ctx context.Context, cfg config.Server, h http.Handler, health *handler.Health) error {
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, ln, cfg, h, health)
+}
+
+// Serve 在 ln 上提供服务，关闭流程：
+//  1. /readyz 变成 503，等待 ReadinessDelay
+//  2. 停止接收新连接，等待在途请求完成，最多 ShutdownTimeout
+//  3. 超时后以 ErrShutdown 取消剩余请求的 ctx，强制关闭连接，返回 context.DeadlineExceeded
+//
+// 正常关闭返回 nil；health 可以为 nil
+func Serve(ctx context.Context, ln net.Listener, cfg config.Server, h http.Handler, health *handler.Health) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	base, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	setReady(health, true)
+	log.Printf("server: listening on %s", ln.Addr())
+
+	select {
+	case err := <-errc:
+		setReady(health, false)
+		return err
+	case <-ctx.Done():
+	}
+	stop() // 再按一次 Ctrl+C 直接退出
+
+	setReady(health, false)
+	drain := time.Duration(cfg.ShutdownTimeout)
+	log.Printf("server: shutting down, draining in-flight requests for up to %v", drain)
+	time.Sleep(time.Duration(cfg.ReadinessDelay))
+
+	sctx, scancel := context.WithTimeout(context.Background(), drain)
+	defer scancel()
+	err := srv.Shutdown(sctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		cancel(ErrShutdown)
+		srv.Close()
+		return fmt.Errorf("server: drain timeout after %v: %w", drain, err)
+	}
+	if err != nil {
+		return err
+	}
+	log.Print("server: stopped")
+	return nil
+}
+
+func setReady(h *handler.Health, ready bool) {
+	if h != nil {
+		h.SetReady(ready)
+	}
+}
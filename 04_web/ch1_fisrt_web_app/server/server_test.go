@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+
+	"first/auth"
+	"first/config"
+	"first/handler"
+
+	"mysql-demo/fixtures/fixturestest"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+func TestRouter(t *testing.T) {
+	r := NewRouter(Handlers{Health: handler.NewHealth(), System: &handler.System{Version: "test"}})
+	for path, want := range map[string]int{
+		"/":               http.StatusOK,
+		"/ping":           http.StatusOK,
+		"/api/v1/ping":    http.StatusOK,
+		"/api/v1/version": http.StatusOK,
+		"/healthz":        http.StatusOK,
+		"/readyz":         http.StatusServiceUnavailable, // 没有经过 Serve，还没就绪
+		"/api/v2/ping":    http.StatusNotFound,
+		"/openapi.json":   http.StatusOK,
+		"/docs/":          http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+// 业务接口需要登录，写操作需要 admin；探针和 ping 不需要
+func TestRouterAuth(t *testing.T) {
+	db, _ := fixturestest.Open(t, "edge_ages")
+	tokens, _ := auth.NewHS256([]byte(strings.Repeat("k", auth.MinSecretLen)))
+	r := NewRouter(Handlers{
+		Health: handler.NewHealth(),
+		System: &handler.System{},
+		Users:  handler.NewUsers(db),
+		Auth:   handler.NewAuth(tokens, auth.StaticUsers{}),
+	})
+	ctx := context.Background()
+	viewer, _ := tokens.Issue(ctx, auth.Principal{Subject: "alice"})
+	admin, _ := tokens.Issue(ctx, auth.Principal{Subject: "root", Roles: []string{"admin"}})
+
+	for _, tc := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/healthz", "", http.StatusOK},
+		{"GET", "/api/v1/ping", "", http.StatusOK},
+		{"GET", "/api/v1/users", "", http.StatusUnauthorized},
+		{"GET", "/api/v1/users", viewer.AccessToken, http.StatusOK},
+		{"DELETE", "/api/v1/users/1", viewer.AccessToken, http.StatusForbidden},
+		{"DELETE", "/api/v1/users/1", admin.AccessToken, http.StatusNoContent},
+		{"POST", "/auth/login", "", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d: %s", tc.method, tc.path, w.Code, tc.want, w.Body)
+		}
+	}
+}
+
+// /auth 和 /api/v1 各自限流，探针不限流；Redis 存储用 miniredis
+func TestRouterRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := config.Default().RateLimit
+	cfg.Store, cfg.Redis.Addr = "redis", mr.Addr()
+	cfg.Auth.Limit = 2
+	cfg.API = config.Rule{Algorithm: "token_bucket", Limit: 1, Window: config.Duration(time.Hour), Key: "api_key"}
+	cfg.APIKeys = []string{"k1", "k2"}
+	limits, closeLimits, err := NewLimits(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeLimits()
+
+	tokens, _ := auth.NewHS256([]byte(strings.Repeat("k", auth.MinSecretLen)))
+	db, _ := fixturestest.Open(t)
+	r := NewRouter(Handlers{
+		Health: handler.NewHealth(),
+		System: &handler.System{},
+		Users:  handler.NewUsers(db),
+		Auth:   handler.NewAuth(tokens, auth.StaticUsers{}),
+		Limits: limits,
+	})
+	token, _ := tokens.Issue(context.Background(), auth.Principal{Subject: "alice"})
+
+	do := func(method, path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for i, want := range []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests} {
+		if w := do("POST", "/auth/login", ""); w.Code != want {
+			t.Errorf("login #%d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	// 默认不信任代理，伪造 X-Forwarded-For 换不来新的额度；信任了代理才按这个头计数
+	login := func(r http.Handler) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/auth/login", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := login(r); code != http.StatusTooManyRequests {
+		t.Errorf("login with spoofed X-Forwarded-For = %d, want 429", code)
+	}
+	proxied := NewRouter(Handlers{
+		Health:         handler.NewHealth(),
+		System:         &handler.System{},
+		Auth:           handler.NewAuth(tokens, auth.StaticUsers{}),
+		Limits:         limits,
+		TrustedProxies: []string{"192.0.2.0/24"}, // httptest 请求的 RemoteAddr
+	})
+	if code := login(proxied); code != http.StatusBadRequest {
+		t.Errorf("login through a trusted proxy = %d, want 400", code)
+	}
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if w := do("GET", "/api/v1/users", "k1"); w.Code != want {
+			t.Errorf("users #%d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	if w := do("GET", "/api/v1/users", "k2"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("users with another key = %d %v", w.Code, w.Header())
+	}
+	// 没有配置的 key 按 IP 计数
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if w := do("GET", "/api/v1/users", fmt.Sprintf("guess%d", i)); w.Code != want {
+			t.Errorf("users with unknown key #%d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	for range 5 {
+		if w := do("GET", "/healthz", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("/healthz = %d %v", w.Code, w.Header())
+		}
+	}
+
+	cfg.API.Key = "nobody"
+	if _, _, err := NewLimits(cfg); err == nil {
+		t.Error("NewLimits with unknown key = nil error")
+	}
+}
+
+// serve 在随机端口启动，返回地址和 Serve 的结果
+func serve(t *testing.T, ctx context.Context, cfg config.Server, h http.Handler, health *handler.Health) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, ln, cfg, h, health) }()
+	return "http://" + ln.Addr().String(), done
+}
+
+func get(url string) (int, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b), err
+}
+
+// 关闭时在途请求能处理完，/readyz 先变成 503
+func TestGracefulShutdown(t *testing.T) {
+	health := handler.NewHealth()
+	started, release := make(chan struct{}), make(chan struct{})
+	r := NewRouter(Handlers{Health: health, System: &handler.System{}})
+	r.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := config.Default().Server
+	url, done := serve(t, ctx, cfg, r, health)
+
+	waitReady(t, url)
+	result := make(chan string, 1)
+	go func() {
+		code, body, err := get(url + "/slow")
+		result <- fmt.Sprint(code, body, err)
+	}()
+	<-started
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for code, _, _ := get(url + "/readyz"); code == http.StatusOK; code, _, _ = get(url + "/readyz") {
+		if time.Now().After(deadline) {
+			t.Fatal("/readyz still 200 after shutdown started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+
+	if got := <-result; got != "200done<nil>" {
+		t.Errorf("in-flight request = %s", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+	if _, _, err := get(url + "/ping"); err == nil {
+		t.Error("server still accepting requests after shutdown")
+	}
+}
+
+// 排空超时：请求的 ctx 以 ErrShutdown 取消
+func TestDrainTimeout(t *testing.T) {
+	started, cause := make(chan struct{}), make(chan error, 1)
+	r := gin.New()
+	r.GET("/stuck", func(c *gin.Context) {
+		close(started)
+		<-c.Request.Context().Done()
+		cause <- context.Cause(c.Request.Context())
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = config.Duration(50 * time.Millisecond)
+	url, done := serve(t, ctx, cfg, r, nil)
+
+	go get(url + "/stuck")
+	<-started
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Serve() = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case err := <-cause:
+		if !errors.Is(err, ErrShutdown) {
+			t.Errorf("request ctx cause = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck request was not canceled")
+	}
+}
+
+func waitReady(t *testing.T, url string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if code, _, _ := get(url + "/readyz"); code == http.StatusOK {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server not ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
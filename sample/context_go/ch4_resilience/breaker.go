@@ -0,0 +1,198 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen 熔断器处于打开状态，调用被直接拒绝
+var ErrOpen = errors.New("resilience: circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 关闭：正常放行，统计连续失败
+	StateOpen                  // 打开：直接拒绝，等待 OpenTimeout
+	StateHalfOpen              // 半开：放行少量探测请求，决定恢复还是继续打开
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后打开
+	OpenTimeout      time.Duration // 打开多久后进入半开
+	HalfOpenProbes   int           // 半开时允许的并发探测数，同时也是恢复所需的连续成功数
+	// IsFailure 判断错误是否计入失败，nil 表示所有非 nil 错误都算；ctx 取消的调用不经过这里，既不算失败也不算成功
+	IsFailure func(err error) bool
+	// OnStateChange 状态变化回调，可用于打日志或监控；在锁外调用，回调里可以再使用熔断器
+	OnStateChange func(from, to State)
+}
+
+// Breaker 熔断器，带半开探测
+type Breaker struct {
+	cfg BreakerConfig
+	now func() time.Time // 方便测试替换时钟
+
+	mu        sync.Mutex
+	state     State
+	failures  int       // closed 状态下的连续失败次数
+	openedAt  time.Time // 进入 open 的时间
+	probes    int       // half-open 状态下正在进行的探测数
+	successes int       // half-open 状态下的连续成功数
+	gen       uint64    // 每次切换状态加一，放行时记下，结果回来时状态已经变了就忽略
+	changes   []change  // 等待在锁外通知的状态变化
+}
+
+type change struct{ from, to State }
+
+// NewBreaker 创建熔断器，未设置的字段使用默认值
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// State 返回当前状态（open 超时后会体现为 half-open）
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	b.advance()
+	state := b.state
+	b.unlock()
+	return state
+}
+
+// Execute 通过熔断器调用 fn
+// Go 的方法不能带类型参数，所以写成泛型函数
+func Execute[T any](ctx context.Context, b *Breaker, fn Func[T]) (T, error) {
+	var zero T
+	gen, err := b.allow()
+	if err != nil {
+		return zero, err
+	}
+	v, err := fn(ctx)
+	b.record(gen, err)
+	return v, err
+}
+
+// allow 判断本次调用是否放行，返回放行时的状态代数
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return 0, ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return 0, ErrOpen
+		}
+		b.probes++
+	}
+	return b.gen, nil
+}
+
+// record 记录调用结果并驱动状态机
+// 放行之后状态已经变过（例如 closed 时放行的慢请求在 half-open 时才返回），结果不再计入
+func (b *Breaker) record(gen uint64, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+	if gen != b.gen {
+		return
+	}
+
+	// 调用方自己取消的既不算失败也不算成功：只归还探测名额，不改变连续失败、成功的计数
+	if errors.Is(err, context.Canceled) {
+		if b.state == StateHalfOpen {
+			b.probes--
+		}
+		return
+	}
+	failed := b.isFailure(err)
+	switch b.state {
+	case StateClosed:
+		if failed {
+			b.failures++
+			if b.failures >= b.cfg.FailureThreshold {
+				b.setState(StateOpen)
+			}
+		} else {
+			b.failures = 0
+		}
+	case StateHalfOpen:
+		b.probes--
+		if failed {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenProbes {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+func (b *Breaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if b.cfg.IsFailure != nil {
+		return b.cfg.IsFailure(err)
+	}
+	return true
+}
+
+// advance open 超时后切到 half-open，调用前需持有锁
+func (b *Breaker) advance() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+}
+
+// setState 切换状态并重置计数，调用前需持有锁
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.gen++
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.cfg.OnStateChange != nil {
+		b.changes = append(b.changes, change{from, to})
+	}
+}
+
+// unlock 释放锁，然后通知期间发生的状态变化，回调里再调用熔断器不会死锁
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for _, c := range changes {
+		b.cfg.OnStateChange(c.from, c.to)
+	}
+}
@@ -0,0 +1,73 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Hedge 对冲请求：先发起一次调用，如果 delay 之后还没有结果，再追加一次，
+// 最多同时 maxAttempts 个，取第一个成功的结果，其余的通过 ctx 取消
+// 某次调用失败时会立即补发下一次（不必等 delay），全部失败则返回合并后的错误
+func Hedge[T any](ctx context.Context, delay time.Duration, maxAttempts int, fn Func[T]) (T, error) {
+	var zero T
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	// 派生出可取消的 ctx，拿到结果后取消其余还在跑的调用
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	// 缓冲区等于最大并发数，被取消的调用写结果时不会阻塞，协程不会泄露
+	results := make(chan result, maxAttempts)
+	launch := func() {
+		go func() {
+			v, err := fn(ctx)
+			results <- result{v: v, err: err}
+		}()
+	}
+
+	launch()
+	started, finished := 1, 0
+	var errs []error
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return zero, errors.Join(append(errs, ctx.Err())...)
+		case <-timer.C:
+			if started < maxAttempts {
+				launch()
+				started++
+				timer.Reset(delay)
+			}
+		case r := <-results:
+			finished++
+			if r.err == nil {
+				return r.v, nil
+			}
+			errs = append(errs, r.err)
+			if started < maxAttempts {
+				launch()
+				started++
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(delay)
+			} else if finished == started {
+				return zero, errors.Join(errs...)
+			}
+		}
+	}
+}
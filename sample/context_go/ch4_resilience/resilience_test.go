@@ -0,0 +1,375 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errUnavailable = errors.New("ip service unavailable")
+
+// GetIp 和 ch2_cancel / ch3_deadline 中的一致，只是把耗时做成参数
+func GetIp(ctx context.Context, cost time.Duration) (ip string, err error) {
+	select {
+	case <-ctx.Done(): // 等待取消
+		return "", ctx.Err()
+	case <-time.After(cost): // 模拟任务耗时
+		ip = "192.168.1.1"
+		return ip, nil
+	}
+}
+
+// flakyGetIp 前 failures 次调用返回错误，之后正常
+func flakyGetIp(failures int32, cost time.Duration) (Func[string], *atomic.Int32) {
+	calls := new(atomic.Int32)
+	return func(ctx context.Context) (string, error) {
+		if calls.Add(1) <= failures {
+			return "", errUnavailable
+		}
+		return GetIp(ctx, cost)
+	}, calls
+}
+
+var fastPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   5 * time.Millisecond,
+	MaxDelay:    20 * time.Millisecond,
+	Jitter:      0.5,
+}
+
+func TestRetrySucceedsAfterFailures(t *testing.T) {
+	fn, calls := flakyGetIp(2, time.Millisecond)
+	ip, err := Retry(context.Background(), fastPolicy, fn)
+	if err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if ip != "192.168.1.1" || calls.Load() != 3 {
+		t.Errorf("got ip=%q calls=%d, want 192.168.1.1 after 3 calls", ip, calls.Load())
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	fn, calls := flakyGetIp(100, time.Millisecond)
+	_, err := Retry(context.Background(), fastPolicy, fn)
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("Retry() error = %v, want %v", err, errUnavailable)
+	}
+	if calls.Load() != 5 {
+		t.Errorf("calls = %d, want 5", calls.Load())
+	}
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	var calls int
+	_, err := Retry(context.Background(), fastPolicy, func(ctx context.Context) (string, error) {
+		calls++
+		return "", Permanent(errUnavailable)
+	})
+	if !errors.Is(err, errUnavailable) || calls != 1 {
+		t.Errorf("got err=%v calls=%d, want permanent error after 1 call", err, calls)
+	}
+}
+
+// 对应 ch3_deadline：GetIp 要 4 秒，deadline 只有 2 秒（这里按比例缩小）
+func TestRetryRespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 40*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Retry(ctx, RetryPolicy{BaseDelay: time.Second}, func(ctx context.Context) (string, error) {
+		return GetIp(ctx, 80*time.Millisecond)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Retry() error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Retry() took %v, should stop at the deadline", elapsed)
+	}
+}
+
+// 对应 ch2_cancel：手动 cancel 后重试立即停止
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	fn, _ := flakyGetIp(100, time.Millisecond)
+	_, err := Retry(ctx, RetryPolicy{BaseDelay: 5 * time.Millisecond, Multiplier: 1.5}, fn)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Retry() error = %v, want Canceled", err)
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{10, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	p.Jitter = 1
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(3); got < 0 || got > 40*time.Millisecond {
+			t.Fatalf("Backoff(3) with full jitter = %v, out of [0, 40ms]", got)
+		}
+	}
+}
+
+func TestHedgeTakesFasterAttempt(t *testing.T) {
+	var calls atomic.Int32
+	start := time.Now()
+	ip, err := Hedge(context.Background(), 20*time.Millisecond, 2, func(ctx context.Context) (string, error) {
+		// 第一次调用很慢，对冲的第二次很快
+		if calls.Add(1) == 1 {
+			return GetIp(ctx, time.Second)
+		}
+		return GetIp(ctx, 5*time.Millisecond)
+	})
+	if err != nil || ip != "192.168.1.1" {
+		t.Fatalf("Hedge() = %q, %v", ip, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Hedge() took %v, want the hedged attempt to win", elapsed)
+	}
+}
+
+func TestHedgeAllFail(t *testing.T) {
+	var calls atomic.Int32
+	_, err := Hedge(context.Background(), time.Millisecond, 3, func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "", errUnavailable
+	})
+	if !errors.Is(err, errUnavailable) || calls.Load() != 3 {
+		t.Errorf("got err=%v calls=%d, want joined error after 3 calls", err, calls.Load())
+	}
+}
+
+func TestHedgeRespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err := Hedge(ctx, 10*time.Millisecond, 3, func(ctx context.Context) (string, error) {
+		return GetIp(ctx, time.Second)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Hedge() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	var transitions []string
+	b := NewBreaker(BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   2,
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	b.now = func() time.Time { return now }
+
+	failing := func(ctx context.Context) (string, error) { return "", errUnavailable }
+	working := func(ctx context.Context) (string, error) { return GetIp(ctx, time.Millisecond) }
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		Execute(ctx, b, failing)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v, want open after 3 failures", b.State())
+	}
+	if _, err := Execute(ctx, b, working); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute() on open breaker error = %v, want ErrOpen", err)
+	}
+
+	// 超时后进入半开，一次失败的探测重新打开
+	now = now.Add(time.Minute)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %v, want half-open after timeout", b.State())
+	}
+	Execute(ctx, b, failing)
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v, want open after failed probe", b.State())
+	}
+
+	// 再次半开，连续两次成功后关闭
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := Execute(ctx, b, working); err != nil {
+			t.Fatalf("probe %d error = %v", i, err)
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state = %v, want closed after successful probes", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions[%d] = %s, want %s", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestBreakerHalfOpenLimitsProbes(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenProbes: 1})
+	b.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	Execute(ctx, b, func(ctx context.Context) (string, error) { return "", errUnavailable })
+	now = now.Add(time.Second)
+
+	// 第一个探测还没返回时，第二个请求应该被拒绝
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := Execute(ctx, b, func(ctx context.Context) (string, error) {
+			<-release
+			return "192.168.1.1", nil
+		})
+		done <- err
+	}()
+	for b.State() == StateHalfOpen {
+		b.mu.Lock()
+		probing := b.probes == 1
+		b.mu.Unlock()
+		if probing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := Execute(ctx, b, func(ctx context.Context) (string, error) { return "", nil }); !errors.Is(err, ErrOpen) {
+		t.Errorf("second probe error = %v, want ErrOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first probe error = %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Errorf("state = %v, want closed", b.State())
+	}
+}
+
+func TestBreakerWithRetry(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour})
+	fn, calls := flakyGetIp(100, time.Millisecond)
+
+	// 熔断打开后 Retry 遇到 ErrOpen 不再打到下游
+	_, err := Retry(context.Background(), RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   time.Millisecond,
+		RetryIf:     func(err error) bool { return !errors.Is(err, ErrOpen) },
+	}, func(ctx context.Context) (string, error) {
+		return Execute(ctx, b, fn)
+	})
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("error = %v, want ErrOpen", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("downstream calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenProbes: 1})
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// closed 时放行的慢请求，等它返回时熔断器已经打开又进入了半开
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Execute(ctx, b, func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "192.168.1.1", nil
+		})
+		close(done)
+	}()
+	<-started
+	Execute(ctx, b, func(ctx context.Context) (string, error) { return "", errUnavailable })
+	now = now.Add(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %v, want half-open", b.State())
+	}
+
+	close(release)
+	<-done
+	b.mu.Lock()
+	probes, successes, state := b.probes, b.successes, b.state
+	b.mu.Unlock()
+	if probes != 0 || successes != 0 || state != StateHalfOpen {
+		t.Errorf("after stale result: probes=%d successes=%d state=%v, want 0 0 half-open", probes, successes, state)
+	}
+}
+
+func TestBreakerIgnoresCanceledCalls(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Second, HalfOpenProbes: 1})
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+	failing := func(ctx context.Context) (string, error) { return "", errUnavailable }
+	canceled := func(ctx context.Context) (string, error) { return "", context.Canceled }
+
+	// closed：取消的调用不会清零连续失败
+	Execute(ctx, b, failing)
+	Execute(ctx, b, canceled)
+	Execute(ctx, b, failing)
+	if b.State() != StateOpen {
+		t.Fatalf("state = %v, want open after fail, cancel, fail", b.State())
+	}
+
+	// half-open：取消的探测归还名额，但不算成功，熔断器不会因此关闭
+	now = now.Add(time.Second)
+	Execute(ctx, b, canceled)
+	b.mu.Lock()
+	probes, successes, state := b.probes, b.successes, b.state
+	b.mu.Unlock()
+	if probes != 0 || successes != 0 || state != StateHalfOpen {
+		t.Errorf("after canceled probe: probes=%d successes=%d state=%v, want 0 0 half-open", probes, successes, state)
+	}
+	if _, err := Execute(ctx, b, failing); errors.Is(err, ErrOpen) || b.State() != StateOpen {
+		t.Errorf("next probe = %v, state = %v, want it admitted and the breaker reopened", err, b.State())
+	}
+}
+
+func TestBreakerCallbackCanReenter(t *testing.T) {
+	var b *Breaker
+	var seen []State
+	b = NewBreaker(BreakerConfig{
+		FailureThreshold: 1,
+		OnStateChange:    func(from, to State) { seen = append(seen, b.State()) },
+	})
+	done := make(chan struct{})
+	go func() {
+		Execute(context.Background(), b, func(ctx context.Context) (string, error) { return "", errUnavailable })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnStateChange calling State() deadlocked")
+	}
+	if len(seen) != 1 || seen[0] != StateOpen {
+		t.Errorf("State() in callback = %v, want [open]", seen)
+	}
+}
@@ -0,0 +1,133 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Func 是所有弹性工具包装的函数形态，和 ch2_cancel / ch3_deadline 里的 GetIp 一致：
+// 接收 ctx，返回结果和错误
+type Func[T any] func(ctx context.Context) (T, error)
+
+// RetryPolicy 重试策略：带抖动的指数退避
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（包含第一次），<=0 表示只受 ctx 限制
+	BaseDelay   time.Duration // 第一次重试前的等待时间
+	MaxDelay    time.Duration // 单次等待的上限，0 表示不设上限
+	Multiplier  float64       // 每次退避的倍数，<=1 时按 2 处理
+	Jitter      float64       // 抖动比例 [0,1]，1 表示 full jitter
+	// RetryIf 判断错误是否值得重试，nil 表示除 Permanent 和 ctx 错误外都重试
+	RetryIf func(err error) bool
+}
+
+// DefaultRetryPolicy 默认策略：最多 5 次，100ms 起步，最大 2s，全抖动
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Multiplier:  2,
+	Jitter:      1,
+}
+
+// permanentError 标记不可重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装一个错误，Retry 遇到它会立即返回，不再重试
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否被 Permanent 标记过
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Retry 按策略重试 fn，直到成功、次数用完、遇到不可重试的错误或 ctx 结束
+// 如果下一次等待会越过 ctx 的 deadline，直接放弃，返回最后一次的错误，避免白等
+func Retry[T any](ctx context.Context, p RetryPolicy, fn Func[T]) (T, error) {
+	var zero T
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, errors.Join(lastErr, err)
+		}
+
+		v, err := fn(ctx)
+		if err == nil {
+			return v, nil
+		}
+		lastErr = err
+
+		if IsPermanent(err) || !p.shouldRetry(err) {
+			return zero, err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return zero, err
+		}
+
+		delay := p.Backoff(attempt)
+		// 等待时间已经超过剩余时间，重试没有意义
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return zero, errors.Join(lastErr, context.DeadlineExceeded)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, errors.Join(lastErr, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry ctx 自身的取消/超时不重试，其余交给 RetryIf
+func (p RetryPolicy) shouldRetry(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.RetryIf != nil {
+		return p.RetryIf(err)
+	}
+	return true
+}
+
+// Backoff 计算第 attempt 次失败后的等待时间（attempt 从 1 开始）
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult <= 1 {
+		mult = 2
+	}
+	d := float64(p.BaseDelay)
+	for i := 1; i < attempt; i++ {
+		d *= mult
+		if p.MaxDelay > 0 && d >= float64(p.MaxDelay) {
+			d = float64(p.MaxDelay)
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+
+	// 抖动：在 [d*(1-jitter), d] 区间内随机，避免大量客户端同时重试
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		d = d*(1-jitter) + rand.Float64()*d*jitter
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,146 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// NodeInfo 节点的只读快照，可直接序列化成 JSON
+type NodeInfo struct {
+	ID           uint64      `json:"id"`
+	Kind         Kind        `json:"kind"`
+	Site         string      `json:"site,omitempty"`
+	Func         string      `json:"func,omitempty"`
+	Created      time.Time   `json:"created"`
+	Deadline     *time.Time  `json:"deadline,omitempty"`
+	Key          string      `json:"key,omitempty"`
+	Done         bool        `json:"done"`
+	Ended        *time.Time  `json:"ended,omitempty"`
+	Err          string      `json:"err,omitempty"`
+	Cause        string      `json:"cause,omitempty"`
+	CanceledBy   string      `json:"canceledBy,omitempty"`
+	CancelCalled bool        `json:"cancelCalled"`
+	Children     []*NodeInfo `json:"children,omitempty"`
+}
+
+// info 生成快照，调用前需持有锁
+func (n *node) info(withChildren bool) NodeInfo {
+	ni := NodeInfo{
+		ID:           n.id,
+		Kind:         n.kind,
+		Site:         n.site,
+		Func:         n.fn,
+		Created:      n.created,
+		Key:          n.key,
+		Done:         n.done,
+		CanceledBy:   n.canceledBy,
+		CancelCalled: n.cancelCalled.Load(),
+	}
+	if n.ownDL {
+		d := n.deadline
+		ni.Deadline = &d
+	}
+	if n.done {
+		e := n.ended
+		ni.Ended = &e
+	}
+	if n.err != nil {
+		ni.Err = n.err.Error()
+	}
+	// cause 和 err 一样时不重复展示
+	if n.cause != nil && n.cause != n.err {
+		ni.Cause = n.cause.Error()
+	}
+	if withChildren {
+		for _, c := range n.children {
+			ci := c.info(true)
+			ni.Children = append(ni.Children, &ci)
+		}
+	}
+	return ni
+}
+
+// Snapshot 返回当前整棵树的快照
+func (t *Tracker) Snapshot() []*NodeInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	roots := make([]*NodeInfo, 0, len(t.roots))
+	for _, r := range t.roots {
+		ri := r.info(true)
+		roots = append(roots, &ri)
+	}
+	return roots
+}
+
+// WriteJSON 以 JSON 格式输出整棵树
+func (t *Tracker) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Snapshot())
+}
+
+// WriteText 以文本树的形式输出，例如：
+//
+//	#1 Root main.go:14 main.main [live]
+//	└── #2 WithCancel main.go:15 main.main [done: context canceled, by cancel]
+//	    └── #3 WithTimeout main.go:20 main.GetIp deadline=+4s [done: context canceled, by parent]
+func (t *Tracker) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	for _, r := range t.Snapshot() {
+		writeNode(&sb, r, "", "")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// String 方便直接 fmt.Println(tracker)
+func (t *Tracker) String() string {
+	var sb strings.Builder
+	t.WriteText(&sb)
+	return sb.String()
+}
+
+func writeNode(sb *strings.Builder, n *NodeInfo, prefix, childPrefix string) {
+	sb.WriteString(prefix)
+	fmt.Fprintf(sb, "#%d %s", n.ID, n.Kind)
+	if n.Site != "" {
+		fmt.Fprintf(sb, " %s", n.Site)
+	}
+	if n.Func != "" {
+		fmt.Fprintf(sb, " %s", n.Func)
+	}
+	if n.Key != "" {
+		fmt.Fprintf(sb, " key=%s", n.Key)
+	}
+	if n.Deadline != nil {
+		fmt.Fprintf(sb, " deadline=%+v", n.Deadline.Sub(n.Created).Round(time.Millisecond))
+	}
+	switch {
+	case n.Done:
+		fmt.Fprintf(sb, " [done: %s", n.Err)
+		if n.Cause != "" {
+			fmt.Fprintf(sb, ", cause: %s", n.Cause)
+		}
+		fmt.Fprintf(sb, ", by %s", n.CanceledBy)
+		if !n.CancelCalled && n.Kind != KindRoot && n.Kind != KindWithValue {
+			sb.WriteString(", cancel() not called")
+		}
+		sb.WriteString("]")
+	case n.Kind == KindWithValue:
+		// WithValue 没有自己的生命周期，不显示状态
+	default:
+		sb.WriteString(" [live]")
+	}
+	sb.WriteString("\n")
+
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			writeNode(sb, c, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			writeNode(sb, c, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
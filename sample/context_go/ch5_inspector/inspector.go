@@ -0,0 +1,286 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind 派生 context 的方式
+type Kind string
+
+const (
+	KindRoot         Kind = "Root"
+	KindWithCancel   Kind = "WithCancel"
+	KindWithTimeout  Kind = "WithTimeout"
+	KindWithDeadline Kind = "WithDeadline"
+	KindWithValue    Kind = "WithValue"
+)
+
+// 取消来源
+const (
+	ByCancel   = "cancel"   // 自己的 cancel() 被调用
+	ByDeadline = "deadline" // 自己的 deadline 到了
+	ByParent   = "parent"   // 父节点取消，传播下来的
+)
+
+// nodeKey 私有 key，用来从 context 里找到对应的节点
+type nodeKey struct{}
+
+// node 一个被记录的 context
+type node struct {
+	id       uint64
+	kind     Kind
+	parent   *node
+	site     string // 创建位置 file:line
+	fn       string // 创建所在的函数
+	created  time.Time
+	deadline time.Time
+	ownDL    bool   // deadline 是这个节点自己设置的，而不是从父节点继承的
+	key      string // WithValue 的 key
+
+	cancelCalled atomic.Bool // cancel() 是否被调用过（WithValue/Root 没有 cancel）
+	canceledSelf atomic.Bool // cancel() 调用时 context 还没结束，结束的原因就是它
+
+	// 以下字段在 context 结束时写入，受 Tracker.mu 保护
+	done       bool
+	ended      time.Time
+	err        error
+	cause      error
+	canceledBy string
+	children   []*node
+}
+
+// Tracker 记录通过它派生出来的所有 context，组成一颗取消树
+type Tracker struct {
+	mu    sync.Mutex
+	next  uint64
+	roots []*node
+	all   map[uint64]*node
+	now   func() time.Time
+}
+
+// New 创建 Tracker
+func New() *Tracker {
+	return &Tracker{all: make(map[uint64]*node), now: time.Now}
+}
+
+// Root 把一个已有的 context（通常是 context.Background() 或 req.Context()）登记为根节点
+func (t *Tracker) Root(ctx context.Context) context.Context {
+	n := t.newNode(ctx, KindRoot, 2)
+	t.register(ctx, n)
+	return context.WithValue(ctx, nodeKey{}, n)
+}
+
+// WithCancel 等价于 context.WithCancel，并记录节点
+func (t *Tracker) WithCancel(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	return t.track(ctx, cancel, KindWithCancel)
+}
+
+// WithTimeout 等价于 context.WithTimeout，并记录节点
+func (t *Tracker) WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	return t.track(ctx, cancel, KindWithTimeout)
+}
+
+// WithDeadline 等价于 context.WithDeadline，并记录节点
+func (t *Tracker) WithDeadline(parent context.Context, d time.Time) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithDeadline(parent, d)
+	return t.track(ctx, cancel, KindWithDeadline)
+}
+
+// WithCancelCause 等价于 context.WithCancelCause，cancel(cause) 的 cause 会被记录下来
+func (t *Tracker) WithCancelCause(parent context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	n := t.newNode(ctx, KindWithCancel, 2)
+	t.register(ctx, n)
+	return context.WithValue(ctx, nodeKey{}, n), func(cause error) {
+		n.called(ctx)
+		cancel(cause)
+	}
+}
+
+// WithTimeoutCause 等价于 context.WithTimeoutCause，超时后 context.Cause 返回 cause
+func (t *Tracker) WithTimeoutCause(parent context.Context, timeout time.Duration, cause error) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeoutCause(parent, timeout, cause)
+	return t.track(ctx, cancel, KindWithTimeout)
+}
+
+// WithDeadlineCause 等价于 context.WithDeadlineCause
+func (t *Tracker) WithDeadlineCause(parent context.Context, d time.Time, cause error) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithDeadlineCause(parent, d, cause)
+	return t.track(ctx, cancel, KindWithDeadline)
+}
+
+// WithValue 等价于 context.WithValue，并记录节点
+func (t *Tracker) WithValue(parent context.Context, key, val any) context.Context {
+	ctx := context.WithValue(parent, key, val)
+	n := t.newNode(ctx, KindWithValue, 2)
+	n.key = fmt.Sprintf("%v (%T)", key, key)
+	t.register(ctx, n)
+	return context.WithValue(ctx, nodeKey{}, n)
+}
+
+// track 登记可取消的 context，包装 cancel 以记录调用
+func (t *Tracker) track(ctx context.Context, cancel context.CancelFunc, kind Kind) (context.Context, context.CancelFunc) {
+	// skip=3: newNode <- track <- WithXxx <- 调用方
+	n := t.newNode(ctx, kind, 3)
+	t.register(ctx, n)
+	wrapped := func() {
+		n.called(ctx)
+		cancel()
+	}
+	return context.WithValue(ctx, nodeKey{}, n), wrapped
+}
+
+// called 在真正调用 cancel 之前记录；常见的 defer cancel() 往往在 deadline 或父节点取消之后才执行，
+// 这时 context 已经结束，不能算作被 cancel() 取消
+func (n *node) called(ctx context.Context) {
+	if ctx.Err() == nil {
+		n.canceledSelf.Store(true)
+	}
+	n.cancelCalled.Store(true)
+}
+
+// newNode 创建节点，skip 用于定位调用方的创建位置
+func (t *Tracker) newNode(ctx context.Context, kind Kind, skip int) *node {
+	n := &node{kind: kind, created: t.now()}
+	if pc, file, line, ok := runtime.Caller(skip); ok {
+		n.site = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		if f := runtime.FuncForPC(pc); f != nil {
+			n.fn = f.Name()
+		}
+	}
+	if d, ok := ctx.Deadline(); ok {
+		n.deadline = d
+		if kind == KindWithTimeout || kind == KindWithDeadline {
+			n.ownDL = true
+		}
+	}
+	// 父节点必须是同一个 Tracker 记录的，否则当作根节点
+	if p, ok := ctx.Value(nodeKey{}).(*node); ok {
+		n.parent = p
+	}
+	return n
+}
+
+// register 把节点挂到父节点下，并在 ctx 结束时记录原因
+func (t *Tracker) register(ctx context.Context, n *node) {
+	t.mu.Lock()
+	t.next++
+	n.id = t.next
+	t.all[n.id] = n
+	if n.parent != nil && t.all[n.parent.id] == n.parent {
+		n.parent.children = append(n.parent.children, n)
+	} else {
+		n.parent = nil
+		t.roots = append(t.roots, n)
+	}
+	t.mu.Unlock()
+
+	if ctx.Done() != nil {
+		context.AfterFunc(ctx, func() { t.finish(n, ctx) })
+	}
+}
+
+// finish ctx 结束时调用，记录 err、cause 和取消来源
+func (t *Tracker) finish(n *node, ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n.done = true
+	n.ended = t.now()
+	n.err = ctx.Err()
+	n.cause = context.Cause(ctx)
+
+	// 父子节点的 AfterFunc 执行顺序不确定，不能依赖父节点的 done，只能用自身信息推断
+	switch {
+	case n.canceledSelf.Load():
+		n.canceledBy = ByCancel
+	case n.ownDL && errors.Is(n.err, context.DeadlineExceeded) && !n.ended.Before(n.deadline):
+		n.canceledBy = ByDeadline
+	default:
+		n.canceledBy = ByParent
+	}
+}
+
+// Prune 删除已经结束、且子树全部结束的节点，避免长时间运行时 Tracker 无限增长
+func (t *Tracker) Prune() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	removed := 0
+	var prune func(list []*node) []*node
+	prune = func(list []*node) []*node {
+		kept := list[:0]
+		for _, n := range list {
+			n.children = prune(n.children)
+			if n.isFinished() && len(n.children) == 0 {
+				delete(t.all, n.id)
+				removed++
+				continue
+			}
+			kept = append(kept, n)
+		}
+		return kept
+	}
+	t.roots = prune(t.roots)
+	return removed
+}
+
+// isFinished 节点已经没有继续观察的价值：已结束，或者是 WithValue 且祖先已经结束
+// WithValue/Root 自己不会结束，祖先还活着时以后可能在它下面创建子节点，删掉会让子节点变成根节点
+func (n *node) isFinished() bool {
+	for ; n != nil; n = n.parent {
+		if n.done {
+			return true
+		}
+		if n.kind != KindWithValue {
+			return false
+		}
+	}
+	return false
+}
+
+// Leak 一个可疑的 context
+type Leak struct {
+	Node   NodeInfo
+	Reason string
+}
+
+// Leaks 找出可能泄露的 context：
+//   - 已经结束但 cancel() 从未被调用：资源要等父节点或 deadline 才释放，go vet 的 lostcancel 说的就是这种
+//   - 仍然存活且超过 olderThan：可能忘了 cancel，也可能是长生命周期的 context，需要人工判断
+func (t *Tracker) Leaks(olderThan time.Duration) []Leak {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	var leaks []Leak
+	for _, n := range t.sorted() {
+		if n.kind == KindWithValue || n.kind == KindRoot {
+			continue
+		}
+		switch {
+		case n.done && !n.cancelCalled.Load():
+			leaks = append(leaks, Leak{Node: n.info(false), Reason: "cancel() never called, released by " + n.canceledBy})
+		case !n.done && olderThan > 0 && now.Sub(n.created) > olderThan:
+			leaks = append(leaks, Leak{Node: n.info(false), Reason: fmt.Sprintf("still live after %v", now.Sub(n.created).Round(time.Millisecond))})
+		}
+	}
+	return leaks
+}
+
+// sorted 按 id 排序的全部节点，调用前需持有锁
+func (t *Tracker) sorted() []*node {
+	list := make([]*node, 0, len(t.all))
+	for _, n := range t.all {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
@@ -0,0 +1,220 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// GetIp 和 ch2_cancel 中的一致，耗时缩短
+func GetIp(ctx context.Context) (ip string, err error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(200 * time.Millisecond):
+		return "192.168.1.1", nil
+	}
+}
+
+// waitDone 等待 AfterFunc 把节点状态写入
+func waitDone(t *testing.T, tr *Tracker, id uint64) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		tr.mu.Lock()
+		done := tr.all[id] != nil && tr.all[id].done
+		tr.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("node #%d never finished", id)
+}
+
+func TestCancelTree(t *testing.T) {
+	tr := New()
+	root := tr.Root(context.Background())
+
+	// 对应 ch2_cancel：WithCancel 后手动 cancel，子节点被动取消
+	ctx, cancel := tr.WithCancel(root)
+	child, childCancel := tr.WithTimeout(ctx, time.Hour)
+	defer childCancel()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := GetIp(child); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetIp() error = %v, want Canceled", err)
+	}
+	waitDone(t, tr, 2)
+	waitDone(t, tr, 3)
+
+	roots := tr.Snapshot()
+	if len(roots) != 1 || len(roots[0].Children) != 1 || len(roots[0].Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree shape:\n%s", tr)
+	}
+	c := roots[0].Children[0]
+	if c.Kind != KindWithCancel || c.CanceledBy != ByCancel || !c.CancelCalled {
+		t.Errorf("WithCancel node = %+v", c)
+	}
+	if !strings.HasPrefix(c.Site, "inspector_test.go:") || !strings.HasSuffix(c.Func, "TestCancelTree") {
+		t.Errorf("creation site = %s %s, want this test", c.Site, c.Func)
+	}
+	gc := c.Children[0]
+	if gc.Kind != KindWithTimeout || gc.CanceledBy != ByParent || gc.Deadline == nil {
+		t.Errorf("WithTimeout node = %+v", gc)
+	}
+}
+
+func TestDeadlineAndCause(t *testing.T) {
+	tr := New()
+	errSlow := errors.New("ip service too slow")
+
+	// 对应 ch3_deadline：deadline 比 GetIp 的耗时短
+	ctx, cancel := tr.WithTimeoutCause(context.Background(), 10*time.Millisecond, errSlow)
+	defer cancel()
+	if _, err := GetIp(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetIp() error = %v, want DeadlineExceeded", err)
+	}
+	waitDone(t, tr, 1)
+
+	n := tr.Snapshot()[0]
+	if n.CanceledBy != ByDeadline || n.Cause != errSlow.Error() || n.Err != context.DeadlineExceeded.Error() {
+		t.Errorf("node = %+v, want deadline with cause %q", n, errSlow)
+	}
+}
+
+// defer cancel() 常常在 deadline 或父节点取消之后、AfterFunc 执行之前就调用了，不能算作 cancel() 取消的
+func TestLateCancel(t *testing.T) {
+	tr := New()
+	ctx, cancel := tr.WithTimeout(context.Background(), time.Millisecond)
+	<-ctx.Done()
+	cancel()
+
+	parent, cancelParent := tr.WithCancel(context.Background())
+	child, cancelChild := tr.WithCancel(parent)
+	cancelParent()
+	<-child.Done()
+	cancelChild()
+
+	for _, id := range []uint64{1, 2, 3} {
+		waitDone(t, tr, id)
+	}
+	roots := tr.Snapshot()
+	if n := roots[0]; n.CanceledBy != ByDeadline || !n.CancelCalled {
+		t.Errorf("timeout canceled after deadline = %+v, want deadline", n)
+	}
+	if n := roots[1].Children[0]; n.CanceledBy != ByParent || !n.CancelCalled {
+		t.Errorf("child canceled after parent = %+v, want parent", n)
+	}
+	if leaks := tr.Leaks(0); len(leaks) != 0 {
+		t.Errorf("Leaks() = %+v, want none", leaks)
+	}
+}
+
+func TestCancelCause(t *testing.T) {
+	tr := New()
+	errShutdown := errors.New("server shutting down")
+	ctx, cancel := tr.WithCancelCause(context.Background())
+	cancel(errShutdown)
+	if !errors.Is(context.Cause(ctx), errShutdown) {
+		t.Fatalf("Cause() = %v", context.Cause(ctx))
+	}
+	waitDone(t, tr, 1)
+	if n := tr.Snapshot()[0]; n.Cause != errShutdown.Error() || n.CanceledBy != ByCancel {
+		t.Errorf("node = %+v", n)
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	tr := New()
+	parent, cancel := tr.WithCancel(context.Background())
+
+	// 忘记调用 cancel：父节点取消后才被释放
+	_, _ = tr.WithTimeout(parent, time.Hour)
+	// 正确调用 cancel
+	_, ok := tr.WithCancel(parent)
+	ok()
+	// 仍然存活的 context
+	live, liveCancel := tr.WithCancel(context.Background())
+	defer liveCancel()
+	_ = live
+
+	cancel()
+	for id := uint64(1); id <= 3; id++ {
+		waitDone(t, tr, id)
+	}
+
+	leaks := tr.Leaks(0)
+	if len(leaks) != 1 || leaks[0].Node.ID != 2 || !strings.Contains(leaks[0].Reason, "cancel() never called") {
+		t.Fatalf("Leaks(0) = %+v, want only #2", leaks)
+	}
+
+	tr.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if leaks := tr.Leaks(time.Second); len(leaks) != 2 || leaks[1].Node.ID != 4 {
+		t.Fatalf("Leaks(1s) = %+v, want #2 and the live #4", leaks)
+	}
+}
+
+func TestValueAndDump(t *testing.T) {
+	type userKey struct{}
+	tr := New()
+	root := tr.Root(context.Background())
+	ctx := tr.WithValue(root, userKey{}, "Alice")
+	_, cancel := tr.WithCancel(ctx)
+	cancel()
+	waitDone(t, tr, 3)
+
+	var text bytes.Buffer
+	if err := tr.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"#1 Root", "└── #2 WithValue", "key={} (inspector.userKey)", "    └── #3 WithCancel", "by cancel"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text dump missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tr.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*NodeInfo
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded[0].Children[0].Children[0].Kind != KindWithCancel {
+		t.Errorf("JSON tree = %s", buf.String())
+	}
+
+	// root 和 value 节点还活着，只清理结束的 #3；以后创建的子节点仍然挂在 value 节点下
+	if removed := tr.Prune(); removed != 1 {
+		t.Errorf("Prune() = %d, want 1:\n%s", removed, tr)
+	}
+	_, cancel = tr.WithCancel(ctx)
+	defer cancel()
+	roots := tr.Snapshot()
+	if len(roots) != 1 || len(roots[0].Children) != 1 || len(roots[0].Children[0].Children) != 1 || roots[0].Children[0].Children[0].ID != 4 {
+		t.Errorf("child created after Prune is not under the value node:\n%s", tr)
+	}
+}
+
+func TestPruneEndedValues(t *testing.T) {
+	type userKey struct{}
+	tr := New()
+	ctx, cancel := tr.WithCancel(context.Background())
+	value := tr.WithValue(ctx, userKey{}, "Alice")
+	tr.WithValue(value, userKey{}, "Bob")
+	cancel()
+	waitDone(t, tr, 1)
+
+	// 祖先已经结束，value 节点下面不会再有活着的子节点
+	if removed := tr.Prune(); removed != 3 || len(tr.Snapshot()) != 0 {
+		t.Errorf("Prune() = %d, remaining:\n%s", removed, tr)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"server"
+)
+
+// hello 来自 gobyexample/77_context.go
+// 关闭时如果 10 秒还没到，排空超时后 ctx 会被取消，cause 是 server.ErrShutdown
+func hello(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	fmt.Println("server: hello handler started")
+	defer fmt.Println("server: hello handler ended")
+
+	select {
+	case <-time.After(10 * time.Second):
+		fmt.Fprintf(w, "hello\n")
+	case <-ctx.Done():
+		// 客户端断开是 context.Canceled，服务器关闭是 server.ErrShutdown
+		fmt.Println("server:", ctx.Err(), "cause:", context.Cause(ctx))
+		http.Error(w, context.Cause(ctx).Error(), http.StatusServiceUnavailable)
+	}
+}
+
+// headers 来自 gobyexample/76_http_server.go
+func headers(w http.ResponseWriter, req *http.Request) {
+	for name, headers := range req.Header {
+		for _, h := range headers {
+			fmt.Fprintf(w, "%v: %v\n", name, h)
+		}
+	}
+}
+
+// go run ./cmd/hello
+// curl localhost:8090/hello 之后按 Ctrl+C：/readyz 先变成 503，/healthz 仍然 200，排空 5 秒超时后 hello 被取消
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", hello)
+	mux.HandleFunc("/headers", headers)
+
+	hooks := &server.Hooks{}
+	// 模拟关闭数据库连接池，真实项目里是 hooks.RegisterCloser("mysql", sqlDB)
+	hooks.Register("db pool", func(ctx context.Context) error {
+		log.Println("closing db pool")
+		return nil
+	})
+
+	err := server.Run(context.Background(), server.Config{
+		Addr:           ":8090",
+		Handler:        mux,
+		DrainTimeout:   5 * time.Second,
+		ReadinessDelay: time.Second,
+		Hooks:          hooks,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
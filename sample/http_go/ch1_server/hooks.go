@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Hook 关闭时执行的清理函数，ctx 带有关闭的截止时间
+type Hook func(ctx context.Context) error
+
+// Hooks 关闭钩子注册表，用来关闭 DB 连接池、刷新缓冲区等
+// 和 defer 一样按注册的逆序执行：后打开的资源先关闭
+type Hooks struct {
+	mu    sync.Mutex
+	names []string
+	hooks []Hook
+}
+
+// Register 注册一个钩子，name 用于日志和错误信息
+func (h *Hooks) Register(name string, fn Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.names = append(h.names, name)
+	h.hooks = append(h.hooks, fn)
+}
+
+// RegisterCloser 注册一个 io.Closer，例如 *sql.DB
+func (h *Hooks) RegisterCloser(name string, c io.Closer) {
+	h.Register(name, func(context.Context) error { return c.Close() })
+}
+
+// Run 逆序执行所有钩子，某个钩子失败不影响后续钩子，错误合并返回
+// 执行过的钩子会被清空，重复调用不会重复关闭
+func (h *Hooks) Run(ctx context.Context) error {
+	h.mu.Lock()
+	names, hooks := h.names, h.hooks
+	h.names, h.hooks = nil, nil
+	h.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		// 即使 ctx 已经超时也继续执行：Close 这类操作通常不看 ctx，跳过反而会泄露资源
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("hook %s: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
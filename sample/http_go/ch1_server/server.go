@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrShutdown 排空超时后，仍在处理的请求的 ctx 会以这个 cause 被取消
+// 处理器可以通过 context.Cause(req.Context()) 区分是客户端断开还是服务器关闭
+var ErrShutdown = errors.New("server: shutting down")
+
+// Config 服务器配置，零值字段使用默认值
+type Config struct {
+	Addr    string       // 监听地址，默认 :8090（和 gobyexample 一致）
+	Handler http.Handler // 业务处理器，nil 时使用 http.DefaultServeMux
+
+	ReadHeaderTimeout time.Duration // 读取请求头超时，默认 5s，防止慢速攻击
+	ReadTimeout       time.Duration // 读取整个请求超时，默认 15s
+	WriteTimeout      time.Duration // 写响应超时，默认 30s
+	IdleTimeout       time.Duration // keep-alive 空闲连接超时，默认 60s
+
+	// DrainTimeout 收到信号后等待在途请求完成的最长时间，默认 10s
+	// 超时后会取消剩余请求的 ctx 并强制关闭连接
+	DrainTimeout time.Duration
+	// ReadinessDelay /readyz 返回 503 后，等待负载均衡摘除流量的时间，默认 0
+	ReadinessDelay time.Duration
+
+	HealthPath    string      // 存活探针路径，默认 /healthz
+	ReadyPath     string      // 就绪探针路径，默认 /readyz
+	Signals       []os.Signal // 触发关闭的信号，默认 SIGINT、SIGTERM
+	Hooks         *Hooks      // 服务器停止后执行的清理钩子
+	Listener      net.Listener
+	Logf          func(format string, args ...any) // 默认 log.Printf
+	OnStateChange func(State)                      // 状态变化回调，主要用于测试和监控
+}
+
+// State 服务器生命周期状态
+type State int32
+
+const (
+	StateStarting State = iota // 启动中：未就绪
+	StateReady                 // 就绪：/readyz 200
+	StateDraining              // 排空中：/readyz 503，/healthz 仍然 200，在途请求继续处理
+	StateStopped               // 已停止：监听已经关闭，探针不再可达，正在执行清理钩子
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+func (c *Config) setDefaults() {
+	if c.Addr == "" {
+		c.Addr = ":8090"
+	}
+	if c.Handler == nil {
+		c.Handler = http.DefaultServeMux
+	}
+	if c.ReadHeaderTimeout == 0 {
+		c.ReadHeaderTimeout = 5 * time.Second
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 15 * time.Second
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 30 * time.Second
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = 60 * time.Second
+	}
+	if c.DrainTimeout == 0 {
+		c.DrainTimeout = 10 * time.Second
+	}
+	if c.HealthPath == "" {
+		c.HealthPath = "/healthz"
+	}
+	if c.ReadyPath == "" {
+		c.ReadyPath = "/readyz"
+	}
+	if len(c.Signals) == 0 {
+		c.Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	if c.Hooks == nil {
+		c.Hooks = &Hooks{}
+	}
+	if c.Logf == nil {
+		c.Logf = log.Printf
+	}
+}
+
+// Run 启动 HTTP 服务器并阻塞，直到 ctx 被取消、收到信号或服务器出错
+// 关闭流程：
+//  1. /readyz 切换为 503，等待 ReadinessDelay
+//  2. Shutdown：停止接收新连接，等待在途请求，最多 DrainTimeout；这期间请求的 ctx 不会被取消
+//  3. 超时则以 ErrShutdown 取消剩余请求的 ctx，并强制关闭连接
+//  4. 逆序执行 Hooks
+//
+// /healthz 在整个关闭过程中都返回 200：排空时进程仍然健康，存活探针失败会让编排系统直接重启进程，
+// 打断排空；监听关闭之后探针自然连不上
+//
+// 正常关闭返回 nil
+func Run(ctx context.Context, cfg Config) error {
+	cfg.setDefaults()
+
+	var state atomic.Int32
+	setState := func(s State) {
+		state.Store(int32(s))
+		if cfg.OnStateChange != nil {
+			cfg.OnStateChange(s)
+		}
+	}
+	setState(StateStarting)
+
+	// 所有请求的 ctx 都派生自 baseCtx，排空超时后统一取消
+	baseCtx, cancelBase := context.WithCancelCause(context.Background())
+	defer cancelBase(ErrShutdown)
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           probes(cfg, &state),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+
+	ln := cfg.Listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", cfg.Addr); err != nil {
+			return errors.Join(fmt.Errorf("server: listen %s: %w", cfg.Addr, err), runHooks(cfg))
+		}
+	}
+
+	// 信号和外部 ctx 都可以触发关闭
+	ctx, stop := signal.NotifyContext(ctx, cfg.Signals...)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+	cfg.Logf("server: listening on %s", ln.Addr())
+	setState(StateReady)
+
+	select {
+	case err := <-serveErr:
+		// Serve 在没有调用 Shutdown 的情况下返回，一定是出错了
+		setState(StateStopped)
+		return errors.Join(fmt.Errorf("server: serve: %w", err), runHooks(cfg))
+	case <-ctx.Done():
+	}
+	// 恢复默认信号处理：再按一次 Ctrl+C 可以直接退出
+	stop()
+
+	cfg.Logf("server: shutdown started (%v), draining for up to %v", context.Cause(ctx), cfg.DrainTimeout)
+	setState(StateDraining)
+	if cfg.ReadinessDelay > 0 {
+		time.Sleep(cfg.ReadinessDelay)
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancelDrain()
+
+	var errs []error
+	if err := srv.Shutdown(drainCtx); err != nil {
+		// 排空超时：通知还在跑的处理器，再强制断开连接
+		cfg.Logf("server: drain timeout, cancelling in-flight requests")
+		cancelBase(ErrShutdown)
+		if cerr := srv.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		errs = append(errs, fmt.Errorf("server: shutdown: %w", err))
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, fmt.Errorf("server: serve: %w", err))
+	}
+
+	setState(StateStopped)
+	errs = append(errs, runHooks(cfg))
+	cfg.Logf("server: stopped")
+	return errors.Join(errs...)
+}
+
+// runHooks 执行清理钩子，给钩子单独的超时，不受排空是否超时的影响
+func runHooks(cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	return cfg.Hooks.Run(ctx)
+}
+
+// probes 在业务处理器前面挂上存活和就绪探针
+func probes(cfg Config, state *atomic.Int32) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.HealthPath, func(w http.ResponseWriter, req *http.Request) {
+		probe(w, State(state.Load()), true)
+	})
+	mux.HandleFunc(cfg.ReadyPath, func(w http.ResponseWriter, req *http.Request) {
+		s := State(state.Load())
+		probe(w, s, s == StateReady)
+	})
+	mux.Handle("/", cfg.Handler)
+	return mux
+}
+
+func probe(w http.ResponseWriter, s State, ok bool) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, s)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startServer 在随机端口启动 Run，返回地址、状态通道和 Run 的结果
+func startServer(t *testing.T, ctx context.Context, cfg Config) (string, <-chan State, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make(chan State, 8)
+	cfg.Listener = ln
+	cfg.Logf = t.Logf
+	cfg.OnStateChange = func(s State) { states <- s }
+
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, cfg) }()
+	for s := range states {
+		if s == StateReady {
+			break
+		}
+	}
+	return "http://" + ln.Addr().String(), states, done
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRunServesAndShutsDown(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "hello\n")
+	})
+
+	var closed []string
+	hooks := &Hooks{}
+	hooks.Register("db", func(context.Context) error { closed = append(closed, "db"); return nil })
+	hooks.Register("cache", func(context.Context) error { closed = append(closed, "cache"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	base, states, done := startServer(t, ctx, Config{Handler: mux, Hooks: hooks, ReadinessDelay: 200 * time.Millisecond})
+
+	if code, body := get(t, base+"/hello"); code != http.StatusOK || body != "hello\n" {
+		t.Fatalf("/hello = %d %q", code, body)
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, body := get(t, base+path); code != http.StatusOK || strings.TrimSpace(body) != "ready" {
+			t.Errorf("%s = %d %q, want 200 ready", path, code, body)
+		}
+	}
+
+	cancel()
+	if s := <-states; s != StateDraining {
+		t.Fatalf("state = %v, want draining", s)
+	}
+	// 排空期间 readyz 翻转，healthz 仍然存活
+	if code, _ := get(t, base+"/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz during drain = %d, want 503", code)
+	}
+	if code, _ := get(t, base+"/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz during drain = %d, want 200", code)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if strings.Join(closed, ",") != "cache,db" {
+		t.Errorf("hooks ran as %v, want reverse order", closed)
+	}
+}
+
+// hello 和 gobyexample/77_context.go 一样，10 秒后才返回
+func TestDrainTimeoutCancelsHello(t *testing.T) {
+	started := make(chan struct{})
+	observed := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		close(started)
+		select {
+		case <-time.After(10 * time.Second):
+			fmt.Fprintf(w, "hello\n")
+		case <-ctx.Done():
+			observed <- context.Cause(ctx)
+			http.Error(w, ctx.Err().Error(), http.StatusInternalServerError)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	base, _, done := startServer(t, ctx, Config{Handler: mux, DrainTimeout: 50 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := http.Get(base + "/hello")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	start := time.Now()
+	err := <-done
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want drain timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %v", elapsed)
+	}
+	select {
+	case cause := <-observed:
+		if !errors.Is(cause, ErrShutdown) {
+			t.Errorf("handler cause = %v, want ErrShutdown", cause)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hello handler never observed cancellation")
+	}
+	wg.Wait()
+}
+
+func TestHooksCollectErrors(t *testing.T) {
+	errClose := errors.New("close failed")
+	h := &Hooks{}
+	var ran int
+	h.Register("a", func(context.Context) error { ran++; return nil })
+	h.RegisterCloser("b", closerFunc(func() error { ran++; return errClose }))
+
+	err := h.Run(context.Background())
+	if !errors.Is(err, errClose) || !strings.Contains(err.Error(), "hook b") || ran != 2 {
+		t.Errorf("Run() = %v, ran = %d", err, ran)
+	}
+	// 已执行的钩子不会重复执行
+	if err := h.Run(context.Background()); err != nil || ran != 2 {
+		t.Errorf("second Run() = %v, ran = %d", err, ran)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
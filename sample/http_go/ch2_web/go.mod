@@ -0,0 +1,3 @@
+module web
+
+go 1.23.4
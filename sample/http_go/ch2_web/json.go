@@ -0,0 +1,199 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MaxBodyBytes Decode 默认允许的最大请求体
+const MaxBodyBytes = 1 << 20
+
+// Error 带 HTTP 状态码的错误，会被 WriteError 编码成统一的 JSON 格式：
+//
+//	{"error": {"code": "invalid_request", "message": "...", "fields": {"name": "required"}}}
+type Error struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Err     error             `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errorf 快速构造一个 Error
+func Errorf(status int, code, format string, args ...any) *Error {
+	return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidationErrors 字段级别的校验错误，key 是 JSON 字段名
+type ValidationErrors map[string]string
+
+func (v ValidationErrors) Error() string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+v[k])
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Add 添加一个字段错误，同一字段只保留第一个
+func (v ValidationErrors) Add(field, msg string) {
+	if _, ok := v[field]; !ok {
+		v[field] = msg
+	}
+}
+
+// Err 没有错误时返回 nil，方便 Validate 里直接 return v.Err()
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// Validator 请求结构体实现这个接口，Decode 之后会自动校验
+type Validator interface {
+	Validate() error
+}
+
+// Decode 解析 JSON 请求体到 T：
+//   - Content-Type 必须是 application/json（为空时放行）
+//   - 请求体最大 MaxBodyBytes，拒绝未知字段和多余内容
+//   - T 实现了 Validator 时调用 Validate
+//
+// 所有错误都是 *Error，状态码 400/413/415
+func Decode[T any](w http.ResponseWriter, req *http.Request) (T, error) {
+	var v T
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || mt != "application/json" {
+			return v, Errorf(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return v, decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return v, Errorf(http.StatusBadRequest, "invalid_json", "request body must contain a single JSON value")
+	}
+
+	if val, ok := any(&v).(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return v, validationError(err)
+		}
+	} else if val, ok := any(v).(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return v, validationError(err)
+		}
+	}
+	return v, nil
+}
+
+// decodeError 把 encoding/json 的错误翻译成对客户端友好的信息
+func decodeError(err error) *Error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset), Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "malformed JSON", Err: err}
+	case errors.As(err, &typeErr):
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_request",
+			Message: "invalid field type",
+			Fields:  map[string]string{typeErr.Field: "must be " + typeErr.Type.String()},
+			Err:     err,
+		}
+	case errors.Is(err, io.EOF):
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "request body is empty", Err: err}
+	case errors.As(err, &maxErr):
+		return &Error{Status: http.StatusRequestEntityTooLarge, Code: "body_too_large", Message: fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit), Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_request", Message: "unknown field", Fields: map[string]string{field: "unknown field"}, Err: err}
+	}
+	return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "invalid request body", Err: err}
+}
+
+func validationError(err error) *Error {
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		return &Error{Status: http.StatusBadRequest, Code: "invalid_request", Message: "validation failed", Fields: ve, Err: err}
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Status: http.StatusBadRequest, Code: "invalid_request", Message: err.Error(), Err: err}
+}
+
+// Encode 以 JSON 写响应
+func Encode(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if v == nil || status == http.StatusNoContent {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// WriteError 把错误写成统一的 JSON 格式
+// *Error 使用自带的状态码，其余错误一律 500，且不向客户端暴露内部信息
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		slog.ErrorContext(req.Context(), "internal error", "err", err, "request_id", RequestID(req.Context()))
+		e = &Error{Status: http.StatusInternalServerError, Code: "internal", Message: http.StatusText(http.StatusInternalServerError)}
+	}
+	Encode(w, e.Status, map[string]*Error{"error": e})
+}
+
+// JSON 把一个强类型的函数适配成 http.HandlerFunc：
+// 解码并校验 Req，调用 fn，把 Resp 编码成 200，错误交给 WriteError
+// Req 为 struct{} 时不读取请求体，适合 GET
+func JSON[Req, Resp any](fn func(req *http.Request, in Req) (Resp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var in Req
+		if _, empty := any(in).(struct{}); !empty {
+			var err error
+			if in, err = Decode[Req](w, req); err != nil {
+				WriteError(w, req, err)
+				return
+			}
+		}
+		out, err := fn(req, in)
+		if err != nil {
+			WriteError(w, req, err)
+			return
+		}
+		Encode(w, http.StatusOK, out)
+	}
+}
@@ -0,0 +1,331 @@
+package web
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader 请求 ID 的请求头/响应头
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID 从 ctx 中取出请求 ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestID 给请求分配 ID：优先使用上游传来的 X-Request-ID，否则随机生成
+// ID 写入 ctx 和响应头，方便串起日志
+func WithRequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > 128 {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder 记录状态码和响应字节数
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap 让 http.ResponseController 能找到底层的 Flush/Hijack
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// AccessLog 用 slog 记录访问日志，logger 为 nil 时使用 slog.Default()
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, req)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			if rec.status >= 500 {
+				level = slog.LevelError
+			}
+			l.LogAttrs(req.Context(), level, "http request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("pattern", req.Pattern),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", req.RemoteAddr),
+				slog.String("request_id", RequestID(req.Context())),
+			)
+		})
+	}
+}
+
+// Recover 捕获 handler 的 panic，记录堆栈并返回 500，避免整个连接被断开
+// http.ErrAbortHandler 是标准库约定的主动中断，原样抛出
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				l := logger
+				if l == nil {
+					l = slog.Default()
+				}
+				l.ErrorContext(req.Context(), "panic recovered",
+					"panic", fmt.Sprint(v),
+					"request_id", RequestID(req.Context()),
+					"stack", string(debug.Stack()),
+				)
+				WriteError(w, req, Errorf(http.StatusInternalServerError, "internal", "internal server error"))
+			}()
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// gzipWriter 压缩响应体
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	minSize     int
+	buf         []byte
+	wroteHeader bool
+	status      int
+	compress    bool
+}
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+// Write 先缓冲到 minSize 再决定是否压缩：太小的响应压缩反而更大
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.wroteHeader {
+		if g.compress {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= g.minSize {
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start 确定是否压缩，写出响应头和缓冲区
+func (g *gzipWriter) start(compress bool) error {
+	g.wroteHeader = true
+	h := g.Header()
+	// 已经编码过的响应、或者没有响应体的状态码不压缩
+	if h.Get("Content-Encoding") != "" || g.status == http.StatusNoContent || g.status == http.StatusNotModified {
+		compress = false
+	}
+	g.compress = compress
+	// 压缩后标准库会按 gzip 数据嗅探类型，需要先按原始内容设置好
+	if h.Get("Content-Type") == "" && len(g.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzipPool.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) == 0 {
+		return nil
+	}
+	var err error
+	if compress {
+		_, err = g.gz.Write(g.buf)
+	} else {
+		_, err = g.ResponseWriter.Write(g.buf)
+	}
+	g.buf = nil
+	return err
+}
+
+func (g *gzipWriter) close() {
+	if !g.wroteHeader {
+		if g.status == 0 {
+			g.status = http.StatusOK
+		}
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		gzipPool.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// Flush 流式响应时先把缓冲区发出去
+func (g *gzipWriter) Flush() {
+	if !g.wroteHeader {
+		g.start(len(g.buf) >= g.minSize)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+func (g *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(g.ResponseWriter).Hijack()
+}
+
+// Gzip 客户端支持时压缩响应，小于 minSize 字节的响应不压缩
+func Gzip(minSize int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(req) || req.Method == http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize}
+			defer gw.close()
+			next.ServeHTTP(gw, req)
+		})
+	}
+}
+
+func acceptsGzip(req *http.Request) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(enc) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowedOrigins   []string // 允许的来源，"*" 表示所有
+	AllowedMethods   []string // 默认 GET、POST、PUT、PATCH、DELETE
+	AllowedHeaders   []string // 默认 Content-Type、Authorization、X-Request-ID
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // 预检结果缓存时间
+}
+
+// CORS 处理跨域请求，预检请求（OPTIONS + Access-Control-Request-Method）直接返回 204
+// 需要用根路由的 Use 挂载：预检请求的方法是 OPTIONS，匹配不到分组内按方法注册的路由
+func CORS(cfg CORSConfig) Middleware {
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = []string{"Content-Type", "Authorization", RequestIDHeader}
+	}
+	allowAll := slices.Contains(cfg.AllowedOrigins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if origin == "" || !(allowAll || slices.Contains(cfg.AllowedOrigins, origin)) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h := w.Header()
+			// 带凭证时规范不允许返回 *，只能回显具体来源
+			if allowAll && !cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if exposed != "" {
+				h.Set("Access-Control-Expose-Headers", exposed)
+			}
+
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware 中间件：包装一个 handler，返回新的 handler
+type Middleware func(http.Handler) http.Handler
+
+// Router 基于 Go 1.22 的 http.ServeMux 路由模式，支持方法匹配和路径参数：
+//
+//	r.GET("/users/{id}", h)  // req.PathValue("id")
+//	r.POST("/users", h)
+//
+// 只做了分组和中间件的封装，匹配规则完全交给标准库
+type Router struct {
+	mux        *http.ServeMux
+	prefix     string
+	middleware []Middleware
+	root       *Router // 分组共享根路由的全局中间件
+}
+
+// New 创建路由
+func New() *Router {
+	r := &Router{mux: http.NewServeMux()}
+	r.root = r
+	return r
+}
+
+// Use 添加中间件
+// 根路由上的中间件包在整个 mux 外面，404/405 也会经过（例如访问日志、recover）；
+// 分组上的中间件只作用于该分组内注册的路由
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Group 创建一个带路径前缀的子路由，可以单独挂中间件
+func (r *Router) Group(prefix string, mw ...Middleware) *Router {
+	g := &Router{
+		mux:    r.mux,
+		prefix: r.prefix + strings.TrimSuffix(prefix, "/"),
+		root:   r.root,
+	}
+	// 子分组继承父分组（非根）的中间件
+	if r != r.root {
+		g.middleware = append(g.middleware, r.middleware...)
+	}
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+// Handle 注册路由，method 为空表示匹配所有方法
+func (r *Router) Handle(method, path string, h http.Handler) {
+	pattern := r.prefix + path
+	if method != "" {
+		pattern = method + " " + pattern
+	}
+	if r != r.root {
+		h = chain(h, r.middleware)
+	}
+	r.mux.Handle(pattern, h)
+}
+
+// HandleFunc 同 Handle，接收函数
+func (r *Router) HandleFunc(method, path string, h http.HandlerFunc) {
+	r.Handle(method, path, h)
+}
+
+func (r *Router) GET(path string, h http.HandlerFunc)    { r.Handle(http.MethodGet, path, h) }
+func (r *Router) POST(path string, h http.HandlerFunc)   { r.Handle(http.MethodPost, path, h) }
+func (r *Router) PUT(path string, h http.HandlerFunc)    { r.Handle(http.MethodPut, path, h) }
+func (r *Router) PATCH(path string, h http.HandlerFunc)  { r.Handle(http.MethodPatch, path, h) }
+func (r *Router) DELETE(path string, h http.HandlerFunc) { r.Handle(http.MethodDelete, path, h) }
+
+// ServeHTTP 实现 http.Handler，全局中间件在这里套上
+// 每次请求都重新组装中间件链，Use 可以在注册路由之后调用
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	chain(r.mux, r.root.middleware).ServeHTTP(w, req)
+}
+
+// Param 读取路径参数，等价于 req.PathValue
+func Param(req *http.Request, name string) string {
+	return req.PathValue(name)
+}
+
+// chain 按注册顺序组装中间件：第一个注册的在最外层
+func chain(h http.Handler, mw []Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// hello 和 headers 来自 gobyexample/76_http_server.go
+func hello(w http.ResponseWriter, req *http.Request) {
+	fmt.Fprintf(w, "hello\n")
+}
+
+func headers(w http.ResponseWriter, req *http.Request) {
+	for name, headers := range req.Header {
+		for _, h := range headers {
+			fmt.Fprintf(w, "%v: %v\n", name, h)
+		}
+	}
+}
+
+type greetRequest struct {
+	Name  string `json:"name"`
+	Times int    `json:"times"`
+}
+
+func (g greetRequest) Validate() error {
+	v := ValidationErrors{}
+	if strings.TrimSpace(g.Name) == "" {
+		v.Add("name", "required")
+	}
+	if g.Times < 1 || g.Times > 5 {
+		v.Add("times", "must be between 1 and 5")
+	}
+	return v.Err()
+}
+
+type greetResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+func newTestRouter(logs io.Writer) *Router {
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+	r := New()
+	r.Use(WithRequestID(), AccessLog(logger), Recover(logger), CORS(CORSConfig{AllowedOrigins: []string{"https://example.com"}}))
+	r.GET("/hello", hello)
+	r.GET("/headers", headers)
+
+	api := r.Group("/api", Gzip(64))
+	api.POST("/greet/{id}", JSON(func(req *http.Request, in greetRequest) (greetResponse, error) {
+		return greetResponse{ID: Param(req, "id"), Message: strings.Repeat("hello "+in.Name+" ", in.Times)}, nil
+	}))
+	api.GET("/big", func(w http.ResponseWriter, req *http.Request) {
+		Encode(w, http.StatusOK, map[string]string{"data": strings.Repeat("hello", 100)})
+	})
+	api.GET("/panic", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+	return r
+}
+
+func do(h http.Handler, method, target, body string, hdr map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHelloAndHeaders(t *testing.T) {
+	var logs bytes.Buffer
+	r := newTestRouter(&logs)
+
+	rec := do(r, http.MethodGet, "/hello", "", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello\n" {
+		t.Fatalf("GET /hello = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Error("missing X-Request-ID")
+	}
+
+	rec = do(r, http.MethodGet, "/headers", "", map[string]string{"X-Demo": "go", RequestIDHeader: "abc"})
+	if !strings.Contains(rec.Body.String(), "X-Demo: go") || rec.Header().Get(RequestIDHeader) != "abc" {
+		t.Errorf("GET /headers = %q, request id %q", rec.Body.String(), rec.Header().Get(RequestIDHeader))
+	}
+
+	// 方法不匹配由 ServeMux 返回 405
+	if rec := do(r, http.MethodPost, "/hello", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /hello = %d, want 405", rec.Code)
+	}
+
+	var entry map[string]any
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("bad log line %q: %v", lines[1], err)
+	}
+	if entry["path"] != "/headers" || entry["request_id"] != "abc" || entry["pattern"] != "GET /headers" {
+		t.Errorf("access log = %v", entry)
+	}
+}
+
+func TestJSONDecodeAndValidate(t *testing.T) {
+	r := newTestRouter(io.Discard)
+	jsonHdr := map[string]string{"Content-Type": "application/json"}
+
+	rec := do(r, http.MethodPost, "/api/greet/42", `{"name":"Alice","times":2}`, jsonHdr)
+	var resp greetResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+		t.Fatalf("POST /api/greet/42 = %d %s", rec.Code, rec.Body.String())
+	}
+	if resp.ID != "42" || resp.Message != "hello Alice hello Alice " {
+		t.Errorf("response = %+v", resp)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		hdr    map[string]string
+		status int
+		fields map[string]string
+	}{
+		{"validation", `{"name":" ","times":9}`, jsonHdr, 400, map[string]string{"name": "required", "times": "must be between 1 and 5"}},
+		{"unknown field", `{"name":"a","times":1,"age":3}`, jsonHdr, 400, map[string]string{"age": "unknown field"}},
+		{"wrong type", `{"name":"a","times":"x"}`, jsonHdr, 400, map[string]string{"times": "must be int"}},
+		{"malformed", `{"name":`, jsonHdr, 400, nil},
+		{"empty", ``, jsonHdr, 400, nil},
+		{"trailing data", `{"name":"a","times":1}{}`, jsonHdr, 400, nil},
+		{"content type", `{"name":"a","times":1}`, map[string]string{"Content-Type": "text/plain"}, 415, nil},
+		{"too large", `{"name":"` + strings.Repeat("a", MaxBodyBytes) + `"}`, jsonHdr, 413, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(r, http.MethodPost, "/api/greet/1", tt.body, tt.hdr)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			var body struct{ Error Error }
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code == "" {
+				t.Fatalf("error envelope = %s", rec.Body.String())
+			}
+			for k, v := range tt.fields {
+				if body.Error.Fields[k] != v {
+					t.Errorf("fields[%s] = %q, want %q", k, body.Error.Fields[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	r := newTestRouter(&logs)
+	rec := do(r, http.MethodGet, "/api/panic", "", nil)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"code":"internal"`) {
+		t.Fatalf("GET /api/panic = %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "panic recovered") || !strings.Contains(logs.String(), `"status":500`) {
+		t.Errorf("logs = %s", logs.String())
+	}
+}
+
+func TestGzip(t *testing.T) {
+	r := newTestRouter(io.Discard)
+
+	rec := do(r, http.MethodGet, "/api/big", "", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", rec.Header().Get("Content-Encoding"))
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if !bytes.Contains(plain, []byte(strings.Repeat("hello", 100))) {
+		t.Errorf("decompressed body = %s", plain)
+	}
+
+	// 小响应不压缩
+	rec = do(r, http.MethodPost, "/api/greet/1", `{"name":"a","times":1}`, map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "hello a") {
+		t.Errorf("small response = %q %q", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+	// 不在分组内的路由不压缩
+	rec = do(r, http.MethodGet, "/hello", "", map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("/hello should not be gzipped")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	r := newTestRouter(io.Discard)
+
+	rec := do(r, http.MethodOptions, "/api/greet/1", "", map[string]string{
+		"Origin":                        "https://example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://example.com" ||
+		!strings.Contains(rec.Header().Get("Access-Control-Allow-Methods"), "POST") {
+		t.Fatalf("preflight = %d %v", rec.Code, rec.Header())
+	}
+
+	rec = do(r, http.MethodGet, "/api/big", "", map[string]string{"Origin": "https://evil.com"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed origin got CORS headers: %v", rec.Header())
+	}
+}
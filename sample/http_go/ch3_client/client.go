@@ -0,0 +1,283 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrResponseTooLarge 响应体超过 MaxResponseBytes
+var ErrResponseTooLarge = errors.New("client: response body too large")
+
+// Config 客户端配置，零值字段使用默认值
+type Config struct {
+	Timeout        time.Duration // 整个请求（包括所有重试）的超时，默认 30s
+	AttemptTimeout time.Duration // 单次尝试的超时，0 表示只受 Timeout 限制
+
+	MaxAttempts   int           // 最多尝试次数（包含第一次），默认 3，1 表示不重试
+	BaseDelay     time.Duration // 第一次重试前的等待，默认 100ms，之后指数增长并加抖动
+	MaxDelay      time.Duration // 单次等待上限，默认 5s
+	MaxRetryAfter time.Duration // 服务端 Retry-After 超过这个值就不重试，默认 30s
+
+	MaxResponseBytes int64 // 响应体大小上限，默认 10MB，<0 不限制
+
+	Pool       PoolConfig        // 连接池，Transport 为 nil 时生效
+	Transport  http.RoundTripper // 底层 Transport，测试时可替换
+	Middleware []Middleware      // 日志、监控等中间件，每次重试都会经过
+}
+
+// Client 带超时、重试和响应大小限制的 HTTP 客户端
+type Client struct {
+	cfg Config
+	hc  *http.Client
+}
+
+// New 创建客户端
+func New(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseDelay == 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = 5 * time.Second
+	}
+	if cfg.MaxRetryAfter == 0 {
+		cfg.MaxRetryAfter = 30 * time.Second
+	}
+	if cfg.MaxResponseBytes == 0 {
+		cfg.MaxResponseBytes = 10 << 20
+	}
+	rt := cfg.Transport
+	if rt == nil {
+		rt = NewTransport(cfg.Pool)
+	}
+	// 超时全部交给 ctx 控制，http.Client.Timeout 保持 0
+	return &Client{cfg: cfg, hc: &http.Client{Transport: Chain(rt, cfg.Middleware...)}}
+}
+
+// Get 发送 GET 请求
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do 发送请求，需要重试的情况：
+//   - 幂等方法（GET/HEAD/OPTIONS/TRACE/PUT/DELETE），或者带 Idempotency-Key 请求头
+//   - 网络错误、429、500、502、503、504（501 等其他状态码重试也不会变好）
+//   - 有请求体时必须能重放（req.GetBody 不为 nil，http.NewRequest 对常见类型会自动设置）
+//
+// 返回的响应体读完后必须 Close，Close 时才会释放 ctx
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.cfg.Timeout)
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &body{rc: resp.Body, limit: c.cfg.MaxResponseBytes, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, req, attempt)
+		last := !retryable || attempt >= c.cfg.MaxAttempts
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			// 调用方取消或者整体超时，不再重试
+			if ctx.Err() != nil || last {
+				return nil, fmt.Errorf("client: %s %s: %w", req.Method, req.URL.Redacted(), err)
+			}
+			wait = c.backoff(attempt)
+		case retryableStatus(resp.StatusCode) && !last:
+			wait = c.backoff(attempt)
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				// 服务端要求等太久，直接把响应交给调用方
+				if ra > c.cfg.MaxRetryAfter {
+					return resp, nil
+				}
+				wait = ra
+			}
+			// 要重试就得把这次的响应体读完关掉，连接才能复用
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			if err == nil {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil, fmt.Errorf("client: %s %s: retry would exceed deadline: %w", req.Method, req.URL.Redacted(), errors.Join(err, context.DeadlineExceeded))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("client: %s %s: %w", req.Method, req.URL.Redacted(), context.Cause(ctx))
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt 发出一次请求，必要时重放请求体
+func (c *Client) attempt(ctx context.Context, req *http.Request, attempt int) (*http.Response, error) {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	var cancel context.CancelFunc = func() {}
+	if c.cfg.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.AttemptTimeout)
+	}
+
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		b, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = b
+	}
+
+	resp, err := c.hc.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// 单次超时的 ctx 要等响应体关闭后再释放
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff 指数退避 + 抖动，结果在 [d/2, d] 之间
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseDelay << (attempt - 1)
+	if d > c.cfg.MaxDelay || d <= 0 {
+		d = c.cfg.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+type attemptKey struct{}
+
+// attemptFrom 中间件里读取当前是第几次尝试
+func attemptFrom(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+// Attempt 返回当前请求是第几次尝试，供自定义中间件使用
+func Attempt(req *http.Request) int {
+	return attemptFrom(req.Context())
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter 解析 Retry-After，支持秒数和 HTTP 日期两种格式
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// cancelBody 关闭时释放单次尝试的 ctx
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// body 限制响应体大小，关闭时释放整体超时的 ctx
+type body struct {
+	rc     io.ReadCloser
+	limit  int64
+	read   int64
+	cancel context.CancelFunc
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.limit < 0 {
+		return b.rc.Read(p)
+	}
+	if b.read >= b.limit {
+		// 多读一个字节判断是否真的超限，正好读完的不算
+		var one [1]byte
+		n, err := b.rc.Read(one[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.limit-b.read {
+		p = p[:b.limit-b.read]
+	}
+	n, err := b.rc.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *body) Close() error {
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
+
+// ReadAll 读取整个响应体并关闭，超过 MaxResponseBytes 返回 ErrResponseTooLarge
+// 服务端声明的 Content-Length 已经超限时不会读取
+func (c *Client) ReadAll(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if c.cfg.MaxResponseBytes >= 0 && resp.ContentLength > c.cfg.MaxResponseBytes {
+		return nil, ErrResponseTooLarge
+	}
+	return io.ReadAll(resp.Body)
+}
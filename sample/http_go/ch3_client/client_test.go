@@ -0,0 +1,276 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(cfg Config) *Client {
+	if cfg.BaseDelay == 0 {
+		cfg.BaseDelay = time.Millisecond
+	}
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = 5 * time.Millisecond
+	}
+	return New(cfg)
+}
+
+// flaky 前 failures 次返回 status，之后返回 200
+func flaky(failures int32, status int, hdr map[string]string) (*httptest.Server, *atomic.Int32) {
+	calls := new(atomic.Int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range hdr {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		fmt.Fprintf(w, "ok %s", body)
+	}))
+	return srv, calls
+}
+
+func TestRetryOn5xx(t *testing.T) {
+	for _, code := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		srv, calls := flaky(2, code, nil)
+		c := newTestClient(Config{})
+		resp, err := c.Get(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := c.ReadAll(resp)
+		if err != nil || string(body) != "ok " || calls.Load() != 3 {
+			t.Errorf("%d: body=%q err=%v calls=%d", code, body, err, calls.Load())
+		}
+		srv.Close()
+	}
+
+	// 501 重试也不会变好
+	srv, calls := flaky(1, http.StatusNotImplemented, nil)
+	defer srv.Close()
+	resp, err := newTestClient(Config{}).Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented || calls.Load() != 1 {
+		t.Errorf("501: status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestNoRetryForPost(t *testing.T) {
+	srv, calls := flaky(5, http.StatusBadGateway, nil)
+	defer srv.Close()
+	c := newTestClient(Config{})
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("data"))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || calls.Load() != 1 {
+		t.Errorf("status=%d calls=%d, POST should not be retried", resp.StatusCode, calls.Load())
+	}
+
+	// 带 Idempotency-Key 的 POST 可以重试，并且每次都重放请求体
+	calls.Store(4)
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("data"))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := c.ReadAll(resp)
+	if string(body) != "ok data" || calls.Load() != 6 {
+		t.Errorf("body=%q calls=%d", body, calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, calls := flaky(1, http.StatusTooManyRequests, map[string]string{"Retry-After": "1"})
+	defer srv.Close()
+
+	start := time.Now()
+	c := newTestClient(Config{})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < time.Second || calls.Load() != 2 {
+		t.Errorf("elapsed=%v calls=%d, want to wait Retry-After", elapsed, calls.Load())
+	}
+
+	// Retry-After 超过上限时不重试，直接返回 429
+	calls.Store(0)
+	c = newTestClient(Config{MaxRetryAfter: 500 * time.Millisecond})
+	resp, err = c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"3", 3 * time.Second, true},
+		{"Wed, 01 Jan 2025 00:00:10 GMT", 10 * time.Second, true},
+		{"Tue, 31 Dec 2024 00:00:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.in, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	// 单次超时后重试，整体超时后放弃
+	c := newTestClient(Config{Timeout: 150 * time.Millisecond, AttemptTimeout: 40 * time.Millisecond, MaxAttempts: 10})
+	start := time.Now()
+	_, err := c.Get(context.Background(), srv.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("elapsed = %v", elapsed)
+	}
+
+	// 调用方的 ctx 取消后立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := newTestClient(Config{}).Get(ctx, srv.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want Canceled", err)
+	}
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("stream") != "" {
+			// 分块传输，没有 Content-Length
+			w.Write([]byte(strings.Repeat("a", 60)))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("a", 60)))
+			return
+		}
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(Config{MaxResponseBytes: 100})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := c.ReadAll(resp); err != nil || len(body) != 100 {
+		t.Errorf("exact limit: len=%d err=%v", len(body), err)
+	}
+
+	resp, err = c.Get(context.Background(), srv.URL+"?stream=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadAll(resp); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("streamed: err = %v, want ErrResponseTooLarge", err)
+	}
+
+	c = newTestClient(Config{MaxResponseBytes: 50})
+	resp, err = c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadAll(resp); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Content-Length: err = %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	srv, _ := flaky(1, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	metrics := &Metrics{}
+	var attempts []int
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts = append(attempts, Attempt(req))
+			req.Header.Set("X-Client", "go_begin")
+			return next.RoundTrip(req)
+		})
+	}
+
+	c := newTestClient(Config{
+		Middleware: []Middleware{
+			Logging(slog.New(slog.NewTextHandler(&logs, nil))),
+			metrics.Middleware(),
+			record,
+		},
+		Pool: PoolConfig{MaxIdleConnsPerHost: 4},
+	})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if fmt.Sprint(attempts) != "[1 2]" {
+		t.Errorf("attempts = %v", attempts)
+	}
+	s := metrics.Host(strings.TrimPrefix(srv.URL, "http://"))
+	if s.Requests.Load() != 2 || s.Status5xx.Load() != 1 || s.Status2xx.Load() != 1 {
+		t.Errorf("metrics = req %d 5xx %d 2xx %d", s.Requests.Load(), s.Status5xx.Load(), s.Status2xx.Load())
+	}
+	if !strings.Contains(logs.String(), "status=503") || !strings.Contains(logs.String(), "attempt=2") {
+		t.Errorf("logs = %s", logs.String())
+	}
+}
+
+func TestNetworkErrorRetry(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(Config{Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) < 3 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+	})})
+	resp, err := c.Get(context.Background(), "http://example.invalid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d", calls.Load())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"client"
+)
+
+// gobyexample/75_http_client.go 的改写：带超时、重试和请求日志
+// go run ./cmd/get https://gobyexample.com
+func main() {
+	url := "https://gobyexample.com"
+	if len(os.Args) > 1 {
+		url = os.Args[1]
+	}
+
+	c := client.New(client.Config{
+		Timeout:    10 * time.Second,
+		Middleware: []client.Middleware{client.Logging(slog.Default())},
+	})
+
+	resp, err := c.Get(context.Background(), url)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+
+	fmt.Println("Response status:", resp.Status)
+
+	// 读取前 5 行
+	scanner := bufio.NewScanner(resp.Body)
+	for i := 0; scanner.Scan() && i < 5; i++ {
+		fmt.Println(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolConfig 连接池配置，对应 http.Transport 的同名字段，零值使用默认值
+type PoolConfig struct {
+	MaxIdleConns          int           // 所有 host 的最大空闲连接数，默认 100
+	MaxIdleConnsPerHost   int           // 每个 host 的最大空闲连接数，默认 10（标准库默认只有 2）
+	MaxConnsPerHost       int           // 每个 host 的最大连接数，0 不限制
+	IdleConnTimeout       time.Duration // 空闲连接保留时间，默认 90s
+	DialTimeout           time.Duration // 建立 TCP 连接超时，默认 5s
+	KeepAlive             time.Duration // TCP keep-alive 间隔，默认 30s
+	TLSHandshakeTimeout   time.Duration // TLS 握手超时，默认 5s
+	ResponseHeaderTimeout time.Duration // 等待响应头超时，0 不限制（由 ctx 控制）
+	DisableHTTP2          bool
+}
+
+// NewTransport 按配置创建 http.Transport
+func NewTransport(p PoolConfig) *http.Transport {
+	if p.MaxIdleConns == 0 {
+		p.MaxIdleConns = 100
+	}
+	if p.MaxIdleConnsPerHost == 0 {
+		p.MaxIdleConnsPerHost = 10
+	}
+	if p.IdleConnTimeout == 0 {
+		p.IdleConnTimeout = 90 * time.Second
+	}
+	if p.DialTimeout == 0 {
+		p.DialTimeout = 5 * time.Second
+	}
+	if p.KeepAlive == 0 {
+		p.KeepAlive = 30 * time.Second
+	}
+	if p.TLSHandshakeTimeout == 0 {
+		p.TLSHandshakeTimeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: p.DialTimeout, KeepAlive: p.KeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !p.DisableHTTP2,
+		MaxIdleConns:          p.MaxIdleConns,
+		MaxIdleConnsPerHost:   p.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       p.MaxConnsPerHost,
+		IdleConnTimeout:       p.IdleConnTimeout,
+		TLSHandshakeTimeout:   p.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: p.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// RoundTripperFunc 让普通函数实现 http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Middleware 包装 RoundTripper，作用于每一次实际发出的请求（包括重试）
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// Chain 组装中间件，第一个在最外层
+func Chain(rt http.RoundTripper, mw ...Middleware) http.RoundTripper {
+	for i := len(mw) - 1; i >= 0; i-- {
+		rt = mw[i](rt)
+	}
+	return rt
+}
+
+// Logging 用 slog 记录每次请求，logger 为 nil 时使用 slog.Default()
+func Logging(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Int("attempt", attemptFrom(req.Context())),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				l.LogAttrs(req.Context(), slog.LevelWarn, "http client request failed", append(attrs, slog.String("err", err.Error()))...)
+				return nil, err
+			}
+			level := slog.LevelInfo
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				level = slog.LevelWarn
+			}
+			l.LogAttrs(req.Context(), level, "http client request", append(attrs, slog.Int("status", resp.StatusCode))...)
+			return resp, nil
+		})
+	}
+}
+
+// Metrics 简单的请求计数和耗时统计，按 host 汇总
+type Metrics struct {
+	mu    sync.Mutex
+	hosts map[string]*HostStats
+}
+
+// HostStats 单个 host 的统计
+type HostStats struct {
+	Requests                                   atomic.Int64
+	Errors                                     atomic.Int64 // 网络错误
+	Status2xx, Status3xx, Status4xx, Status5xx atomic.Int64
+	TotalNanos                                 atomic.Int64
+}
+
+// Host 返回某个 host 的统计，不存在时创建
+func (m *Metrics) Host(host string) *HostStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hosts == nil {
+		m.hosts = make(map[string]*HostStats)
+	}
+	s, ok := m.hosts[host]
+	if !ok {
+		s = &HostStats{}
+		m.hosts[host] = s
+	}
+	return s
+}
+
+// Middleware 返回记录统计的中间件
+func (m *Metrics) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			s := m.Host(req.URL.Host)
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			s.Requests.Add(1)
+			s.TotalNanos.Add(int64(time.Since(start)))
+			if err != nil {
+				s.Errors.Add(1)
+				return nil, err
+			}
+			switch {
+			case resp.StatusCode >= 500:
+				s.Status5xx.Add(1)
+			case resp.StatusCode >= 400:
+				s.Status4xx.Add(1)
+			case resp.StatusCode >= 300:
+				s.Status3xx.Add(1)
+			default:
+				s.Status2xx.Add(1)
+			}
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem 一个失效的引用
+type Problem struct {
+	File   string // 相对仓库根目录
+	Line   int
+	Target string
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Target, p.Reason)
+}
+
+// Checker 检查仓库里所有 markdown 的相对链接和图片
+type Checker struct {
+	root string
+	docs map[string]*Doc // 绝对路径 -> 解析结果
+}
+
+func NewChecker(root string) *Checker {
+	return &Checker{root: root, docs: map[string]*Doc{}}
+}
+
+// CheckAll 检查 root 下所有 .md 文件
+func (c *Checker) CheckAll() ([]Problem, error) {
+	var problems []Problem
+	err := filepath.WalkDir(c.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != c.root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		ps, err := c.Check(p)
+		problems = append(problems, ps...)
+		return err
+	})
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, err
+}
+
+// Check 检查一个文件
+func (c *Checker) Check(file string) ([]Problem, error) {
+	doc, err := c.doc(file)
+	if err != nil {
+		return nil, err
+	}
+	rel, _ := filepath.Rel(c.root, file)
+	var problems []Problem
+	for _, l := range doc.Links {
+		if reason := c.resolve(file, l.Target); reason != "" {
+			problems = append(problems, Problem{File: filepath.ToSlash(rel), Line: l.Line, Target: l.Target, Reason: reason})
+		}
+	}
+	return problems, nil
+}
+
+// resolve 返回链接失效的原因，正常时返回空字符串
+func (c *Checker) resolve(from, target string) string {
+	p, fragment, _ := strings.Cut(target, "#")
+	p, _, _ = strings.Cut(p, "?")
+	if u, err := url.PathUnescape(p); err == nil {
+		p = u
+	}
+
+	dest := from
+	if p != "" {
+		dest = filepath.Join(filepath.Dir(from), filepath.FromSlash(p))
+		if !strings.HasPrefix(dest+string(filepath.Separator), c.root+string(filepath.Separator)) {
+			return "points outside the repository"
+		}
+		info, err := os.Stat(dest)
+		if err != nil {
+			return "file not found"
+		}
+		if info.IsDir() {
+			return ""
+		}
+	}
+	if fragment == "" || !strings.HasSuffix(dest, ".md") {
+		return ""
+	}
+	doc, err := c.doc(dest)
+	if err != nil {
+		return err.Error()
+	}
+	if !doc.HasAnchor(fragment) {
+		return "anchor not found"
+	}
+	return ""
+}
+
+func (c *Checker) doc(file string) (*Doc, error) {
+	if d, ok := c.docs[file]; ok {
+		return d, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	d := Parse(data)
+	c.docs[file] = d
+	return d, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const usage = `用法: go run . [flags]
+
+生成整个课程的目录，并检查所有 markdown 里的相对链接、图片和锚点
+默认把目录打印到标准输出；有失效的引用时打印到标准错误并以状态码 1 退出
+
+README 里用下面的标记圈出目录的位置，-write 会就地更新标记之间的内容：
+
+  <!-- toc -->           完整目录
+  <!-- toc depth=1 -->   只列一层
+  <!-- /toc -->
+
+flags:
+`
+
+// 例：cd tools/toc && go run . -write
+func main() {
+	root := flag.String("root", "", "仓库根目录，默认从当前目录向上找 .git")
+	write := flag.Bool("write", false, "更新各个 README 里 <!-- toc --> 标记之间的目录")
+	check := flag.Bool("check", false, "只检查链接，不打印目录")
+	depth := flag.Int("depth", 0, "打印的层数，0 表示不限")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir, err := findRoot(*root)
+	if err != nil {
+		fail(err)
+	}
+	tree, err := Build(dir)
+	if err != nil {
+		fail(err)
+	}
+
+	switch {
+	case *write:
+		updated, err := WriteAll(dir, tree)
+		if err != nil {
+			fail(err)
+		}
+		for _, f := range updated {
+			fmt.Println("updated", f)
+		}
+	case !*check:
+		fmt.Printf("# 目录\n\n%s", tree.Render(".", *depth))
+	}
+
+	problems, err := NewChecker(dir).CheckAll()
+	if err != nil {
+		fail(err)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d broken references\n", len(problems))
+		os.Exit(1)
+	}
+}
+
+// tocRe README 里的目录标记
+var tocRe = regexp.MustCompile(`(?s)(<!--\s*toc(?:\s+depth=(\d+))?\s*-->).*?(<!--\s*/toc\s*-->)`)
+
+// WriteAll 更新目录树里所有带标记的 README，返回内容有变化的文件（相对仓库根目录）
+func WriteAll(root string, tree *Node) ([]string, error) {
+	var updated []string
+	var walk func(n *Node) error
+	walk = func(n *Node) error {
+		if n.Dir && n.Path != "" {
+			changed, err := writeReadme(root, n)
+			if err != nil {
+				return err
+			}
+			if changed {
+				updated = append(updated, n.Path)
+			}
+		}
+		for _, c := range n.Children {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return updated, walk(tree)
+}
+
+func writeReadme(root string, n *Node) (bool, error) {
+	file := filepath.Join(root, filepath.FromSlash(n.Path))
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	out := UpdateTOC(data, n, path.Dir(n.Path))
+	if bytes.Equal(out, data) {
+		return false, nil
+	}
+	return true, os.WriteFile(file, out, 0o644)
+}
+
+// UpdateTOC 把 data 里每一对标记之间的内容换成 n 的目录
+func UpdateTOC(data []byte, n *Node, base string) []byte {
+	return tocRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := tocRe.FindSubmatch(m)
+		depth, _ := strconv.Atoi(string(sub[2]))
+		var b bytes.Buffer
+		b.Write(sub[1])
+		b.WriteString("\n\n")
+		b.WriteString(n.Render(base, depth))
+		b.WriteString("\n")
+		b.Write(sub[3])
+		return b.Bytes()
+	})
+}
+
+// findRoot 没有指定时从当前目录向上找包含 .git 的目录
+func findRoot(root string) (string, error) {
+	if root != "" {
+		return filepath.Abs(root)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if exists(filepath.Join(d, ".git")) {
+			return d, nil
+		}
+		if filepath.Dir(d) == d {
+			return dir, nil
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "toc:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Heading markdown 标题
+type Heading struct {
+	Level int
+	Text  string
+	Slug  string
+	Line  int
+}
+
+// Link 文档里的一个相对链接或图片
+type Link struct {
+	Target string // 原始写法，例如 assets/README/image.png、06_CRUD_更新.md#乐观锁
+	Line   int
+}
+
+// Doc 解析后的 markdown 文件
+type Doc struct {
+	Headings []Heading
+	Links    []Link
+	anchors  map[string]bool
+}
+
+// HasAnchor 文档里是否有这个锚点（标题 slug 或者 <a id/name>）
+func (d *Doc) HasAnchor(fragment string) bool {
+	if f, err := url.PathUnescape(fragment); err == nil {
+		fragment = f
+	}
+	return d.anchors[strings.ToLower(fragment)]
+}
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceRe   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	// [text](target "title") 和 ![alt](target)，target 可以用 <> 包起来以便包含空格
+	linkRe     = regexp.MustCompile(`!?\[(?:[^\[\]]|\[[^\]]*\])*\]\(\s*(<[^>]*>|[^)\s]+)(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	refRe      = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*(<[^>]*>|\S+)`)
+	htmlRe     = regexp.MustCompile(`(?i)<(?:img|a|source)\b[^>]*?\s(?:src|href)\s*=\s*["']([^"']+)["']`)
+	anchorRe   = regexp.MustCompile(`(?i)<a\b[^>]*?\s(?:id|name)\s*=\s*["']([^"']+)["']`)
+	codeSpanRe = regexp.MustCompile("`+[^`]*`+")
+	commentRe  = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// Parse 解析标题和链接，跳过代码块、行内代码和 HTML 注释
+func Parse(data []byte) *Doc {
+	d := &Doc{anchors: map[string]bool{}}
+	// 注释可能跨行，替换成同样行数的空行，保持行号不变
+	data = commentRe.ReplaceAllFunc(data, func(b []byte) []byte {
+		return bytes.Repeat([]byte("\n"), bytes.Count(b, []byte("\n")))
+	})
+
+	slugs := newSlugger()
+	var fence string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case strings.HasPrefix(m[1], fence[:1]) && len(m[1]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			text := plainText(m[2])
+			h := Heading{Level: len(m[1]), Text: text, Slug: slugs.slug(text), Line: n}
+			d.Headings = append(d.Headings, h)
+			d.anchors[h.Slug] = true
+		}
+		for _, m := range anchorRe.FindAllStringSubmatch(line, -1) {
+			d.anchors[strings.ToLower(m[1])] = true
+		}
+
+		line = codeSpanRe.ReplaceAllString(line, "")
+		var targets []string
+		for _, m := range linkRe.FindAllStringSubmatch(line, -1) {
+			targets = append(targets, m[1])
+		}
+		if m := refRe.FindStringSubmatch(line); m != nil {
+			targets = append(targets, m[1])
+		}
+		for _, m := range htmlRe.FindAllStringSubmatch(line, -1) {
+			targets = append(targets, m[1])
+		}
+		for _, t := range targets {
+			t = strings.TrimSuffix(strings.TrimPrefix(t, "<"), ">")
+			if isLocal(t) {
+				d.Links = append(d.Links, Link{Target: t, Line: n})
+			}
+		}
+	}
+	return d
+}
+
+// isLocal 是否是仓库里的相对链接（外链、邮件、绝对路径不检查）
+func isLocal(target string) bool {
+	if target == "" || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		return false
+	}
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" {
+		return false
+	}
+	return true
+}
+
+var (
+	inlineLinkRe = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	emphasisRe   = regexp.MustCompile("[*_`~]+")
+	htmlTagRe    = regexp.MustCompile(`<[^>]+>`)
+)
+
+// plainText 去掉标题里的链接、强调、行内代码和 HTML 标签，只留下显示的文字
+func plainText(s string) string {
+	s = inlineLinkRe.ReplaceAllString(s, "$1")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = emphasisRe.ReplaceAllStringFunc(s, func(m string) string {
+		// 单词中间的下划线（snake_case）保留
+		if strings.Trim(m, "_") == "" {
+			return m
+		}
+		return ""
+	})
+	return strings.TrimSpace(s)
+}
+
+// slugger 按 GitHub 的规则生成锚点，同名标题依次加 -1、-2
+type slugger map[string]int
+
+func newSlugger() slugger { return slugger{} }
+
+func (s slugger) slug(text string) string {
+	base := Slug(text)
+	n, seen := s[base]
+	s[base] = n + 1
+	if !seen {
+		return base
+	}
+	for {
+		candidate := fmt.Sprintf("%s-%d", base, n)
+		if _, taken := s[candidate]; !taken {
+			s[candidate] = 1
+			return candidate
+		}
+		n++
+	}
+}
+
+// Slug GitHub 的锚点规则：转小写，保留各种文字（包括中文）、数字、- 和 _，
+// 空格变成 -，其他标点和符号删除；连续的 - 不合并
+//
+//	Slug("CRUD & 事务")  == "crud--事务"
+//	Slug("Top-k问题")     == "top-k问题"
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
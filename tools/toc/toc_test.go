@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"索引", "索引"},
+		{"CRUD_创建", "crud_创建"},
+		{"Top-k问题", "top-k问题"},
+		{"B+Tree 与 B-Tree", "btree-与-b-tree"},
+		{"CRUD & 事务", "crud--事务"},
+		{"0-1背包问题（动态规划）", "0-1背包问题动态规划"},
+		{"QueryFields 模式的作用", "queryfields-模式的作用"},
+	} {
+		if got := Slug(tc.in); got != tc.want {
+			t.Errorf("Slug(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc := Parse([]byte(strings.Join([]string{
+		"## 锁",
+		"见 [乐观锁](06_CRUD_更新.md#乐观锁) 和 ![图](assets/a.png \"标题\")",
+		"```go",
+		"## 不是标题 [也不是链接](missing.md)",
+		"```",
+		"`[行内代码](missing.md)` [外链](https://gorm.io) <img src=\"assets/b.png\">",
+		"<!-- ![注释掉的图](assets/c.png)",
+		"-->",
+		"## 锁",
+		"### **加粗** 与 `代码` [链接](x.md)",
+		"[ref]: <assets/d e.png>",
+	}, "\n")))
+
+	var slugs []string
+	for _, h := range doc.Headings {
+		slugs = append(slugs, h.Slug)
+	}
+	if got := strings.Join(slugs, ","); got != "锁,锁-1,加粗-与-代码-链接" {
+		t.Errorf("slugs = %s", got)
+	}
+	var links []string
+	for _, l := range doc.Links {
+		links = append(links, l.Target)
+	}
+	want := "06_CRUD_更新.md#乐观锁,assets/a.png,assets/b.png,x.md,assets/d e.png"
+	if got := strings.Join(links, ","); got != want {
+		t.Errorf("links = %s, want %s", got, want)
+	}
+	if doc.Links[2].Line != 6 {
+		t.Errorf("line of assets/b.png = %d, want 6", doc.Links[2].Line)
+	}
+	if !doc.HasAnchor("%E9%94%81-1") {
+		t.Error("url-encoded anchor not found")
+	}
+}
+
+// course 在临时目录里搭一个小课程
+func course(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"README.md":                     "# 课程\n\n<!-- toc depth=1 -->\n<!-- /toc -->\n",
+		"01_mysql/README.md":            "![图](assets/README/ok.png)\n\n<!-- toc -->\nold\n<!-- /toc -->\n",
+		"01_mysql/assets/README/ok.png": "",
+		"01_mysql/01_模型定义.md":           "## 模型\n",
+		"01_mysql/02_连接.md":             "[模型](01_模型定义.md#模型) [坏锚点](01_模型定义.md#没有) [坏图](assets/missing.png)\n",
+		"01_mysql/02_连接.go":             "package main\n",
+		"01_mysql/audit/audit.go":       "package audit\n",
+		"09_algo/02_树/L02_遍历.md":        "[上一节](L01_二叉树.md)\n",
+		"09_algo/02_树/L01_二叉树.md":       "",
+		"09_algo/02_树/小结.md":            "",
+		"09_algo/01_初识/L01_初识.md":       "[外面](../../../x.md)\n",
+		"gobyexample/10_map.go":         "package main\n",
+		"gobyexample/2_values.go":       "package main\n",
+		"gobyexample/folder/x.txt":      "",
+		"tools/toc/main.go":             "package main\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBuildAndRender(t *testing.T) {
+	root := course(t)
+	tree, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- [01_mysql](01_mysql/README.md)
+  - [模型定义](01_mysql/01_模型定义.md)
+  - [连接](01_mysql/02_连接.md)（[代码](01_mysql/02_连接.go)）
+- 09_algo
+  - 初识
+    - [初识](09_algo/01_初识/L01_初识.md)
+  - 树
+    - [二叉树](09_algo/02_树/L01_二叉树.md)
+    - [遍历](09_algo/02_树/L02_遍历.md)
+    - [小结](09_algo/02_树/小结.md)
+- gobyexample
+  - [values](gobyexample/2_values.go)
+  - [map](gobyexample/10_map.go)
+`
+	if got := tree.Render(".", 0); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+	if got := tree.Render(".", 1); strings.Count(got, "\n") != 3 {
+		t.Errorf("Render(depth 1) =\n%s", got)
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	root := course(t)
+	tree, _ := Build(root)
+	updated, err := WriteAll(root, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(updated, ",") != "README.md,01_mysql/README.md" {
+		t.Errorf("updated = %v", updated)
+	}
+	readme, _ := os.ReadFile(filepath.Join(root, "01_mysql/README.md"))
+	want := "![图](assets/README/ok.png)\n\n<!-- toc -->\n\n- [模型定义](01_模型定义.md)\n- [连接](02_连接.md)（[代码](02_连接.go)）\n\n<!-- /toc -->\n"
+	if string(readme) != want {
+		t.Errorf("01_mysql/README.md =\n%s", readme)
+	}
+	// 再写一次没有变化
+	if updated, _ := WriteAll(root, tree); len(updated) != 0 {
+		t.Errorf("second WriteAll() updated %v", updated)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	root := course(t)
+	problems, err := NewChecker(root).CheckAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"01_mysql/02_连接.md:1: 01_模型定义.md#没有: anchor not found",
+		"01_mysql/02_连接.md:1: assets/missing.png: file not found",
+		"09_algo/01_初识/L01_初识.md:1: ../../../x.md: points outside the repository",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
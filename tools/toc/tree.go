@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node 目录树里的一个章节（目录）或一节（文件）
+type Node struct {
+	Name     string // 文件名或目录名
+	Title    string // 去掉编号后的标题
+	Path     string // 相对仓库根目录的链接目标，目录是它的 README.md，没有 README 时为空
+	Code     string // 和 .md 同名的 .go 文件
+	Dir      bool
+	Children []*Node
+
+	number   int
+	numbered bool
+}
+
+// numberRe 01_模型定义、L01_二叉树、08.defer、12.xml 序列化
+var numberRe = regexp.MustCompile(`^L?(\d+)[_.]\s*(.*)$`)
+
+// skipDirs 不属于课程内容的目录
+var skipDirs = map[string]bool{"assets": true, "res": true, "testdata": true, "vendor": true, "node_modules": true}
+
+// Build 扫描仓库，根目录下的每个目录是一章，章里面按编号嵌套：
+//
+//	09_hello_algo/07_树/L01_二叉树.md -> 09_hello_algo > 树 > 二叉树
+//
+// 章里面只收录带编号的目录和 .go 文件（不带编号的是代码包，例如 01_mysql/audit），.md 都收录
+func Build(root string) (*Node, error) {
+	n := &Node{Name: filepath.Base(root), Title: "目录", Dir: true}
+	if exists(filepath.Join(root, "README.md")) {
+		n.Path = "README.md"
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || hidden(e.Name()) || skipDirs[e.Name()] {
+			continue
+		}
+		child, err := scanDir(root, e.Name(), true)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			n.Children = append(n.Children, child)
+		}
+	}
+	sortNodes(n.Children)
+	return n, nil
+}
+
+// scanDir 扫描 rel 目录，没有 README 也没有任何内容时返回 nil
+func scanDir(root, rel string, chapter bool) (*Node, error) {
+	n := newNode(path.Base(rel), true)
+	if chapter {
+		n.Title = n.Name // 章用完整的目录名，和仓库里的叫法一致
+	}
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+
+	lessons := map[string]*Node{}
+	var code []string
+	for _, e := range entries {
+		name := e.Name()
+		if hidden(name) {
+			continue
+		}
+		childRel := path.Join(rel, name)
+		if e.IsDir() {
+			if skipDirs[name] || !numberRe.MatchString(name) {
+				continue
+			}
+			child, err := scanDir(root, childRel, false)
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				n.Children = append(n.Children, child)
+			}
+			continue
+		}
+		switch {
+		case strings.EqualFold(name, "README.md"):
+			n.Path = childRel
+		case strings.HasSuffix(name, ".md"):
+			child := newNode(name, false)
+			child.Path = childRel
+			lessons[child.key()] = child
+			n.Children = append(n.Children, child)
+		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") && numberRe.MatchString(name):
+			code = append(code, childRel)
+		}
+	}
+	// 02_连接到数据库.md 和 02_连接到数据库.go、08.defer.md 和 08_defer.go 合成一项
+	for _, rel := range code {
+		child := newNode(path.Base(rel), false)
+		if md, ok := lessons[child.key()]; ok && md.Code == "" {
+			md.Code = rel
+			continue
+		}
+		child.Path = rel
+		n.Children = append(n.Children, child)
+	}
+
+	if n.Path == "" && len(n.Children) == 0 {
+		return nil, nil
+	}
+	sortNodes(n.Children)
+	return n, nil
+}
+
+func newNode(name string, dir bool) *Node {
+	stem := name
+	if !dir {
+		stem = strings.TrimSuffix(strings.TrimSuffix(name, ".md"), ".go")
+	}
+	n := &Node{Name: name, Title: stem, Dir: dir}
+	if m := numberRe.FindStringSubmatch(stem); m != nil {
+		n.number, _ = strconv.Atoi(m[1])
+		n.numbered = true
+		if m[2] != "" {
+			n.Title = m[2]
+		}
+	}
+	return n
+}
+
+// key 编号和标题相同的 .md 和 .go 是同一节
+func (n *Node) key() string {
+	if n.numbered {
+		return strconv.Itoa(n.number) + "_" + n.Title
+	}
+	return n.Title
+}
+
+// sortNodes 带编号的按编号排在前面，其余按名字
+func sortNodes(nodes []*Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.numbered != b.numbered {
+			return a.numbered
+		}
+		if a.numbered && a.number != b.number {
+			return a.number < b.number
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Render 把 n 的子节点渲染成嵌套列表，base 是目录所在位置（相对仓库根目录），链接相对于它
+// depth <= 0 表示不限层数
+func (n *Node) Render(base string, depth int) string {
+	var b strings.Builder
+	n.render(&b, base, 0, depth)
+	return b.String()
+}
+
+func (n *Node) render(b *strings.Builder, base string, level, depth int) {
+	if depth > 0 && level >= depth {
+		return
+	}
+	for _, c := range n.Children {
+		indent := strings.Repeat("  ", level)
+		if c.Path == "" {
+			fmt.Fprintf(b, "%s- %s\n", indent, c.Title)
+		} else {
+			fmt.Fprintf(b, "%s- [%s](%s)", indent, c.Title, relLink(base, c.Path))
+			if c.Code != "" {
+				fmt.Fprintf(b, "（[代码](%s)）", relLink(base, c.Code))
+			}
+			b.WriteByte('\n')
+		}
+		c.render(b, base, level+1, depth)
+	}
+}
+
+// relLink 从 base 目录指向 target 的相对链接，空格转成 %20
+func relLink(base, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(base), filepath.FromSlash(target))
+	if err != nil {
+		rel = target
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), " ", "%20")
+}
+
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}